@@ -17,24 +17,223 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+const (
+	// ReconcileAtAnnotation is set to the current time to force an immediate
+	// reconciliation of a GithubIssue, e.g. by kubectl githubissue sync, any
+	// change of its value triggers a reconcile
+	ReconcileAtAnnotation string = "training.redhat.com/reconcile-at"
+
+	// DryRunAnnotation set to "true" makes the operator only plan the changes to the issue
+	// of a GithubIssue, they are listed in its Planned condition and in events
+	DryRunAnnotation string = "training.redhat.com/dry-run"
+
+	// PausedAnnotation set to "true" stops the operator from changing the issue of a
+	// GithubIssue, e.g. during an incident, while the status still reports the issue
+	PausedAnnotation string = "training.redhat.com/paused"
+)
+
+// TitleDriftPolicy describes how a title which was changed on github is handled
+// +kubebuilder:validation:Enum=Correct;Accept
+type TitleDriftPolicy string
+
+const (
+	// TitleDriftPolicyCorrect renames the issue back to the title in the spec
+	TitleDriftPolicyCorrect TitleDriftPolicy = "Correct"
+
+	// TitleDriftPolicyAccept writes the title of the issue back into the spec
+	TitleDriftPolicyAccept TitleDriftPolicy = "Accept"
+)
+
+// SyncDirection describes in which direction changes are synced between the object and github
+// +kubebuilder:validation:Enum=ToGithub;FromGithub;Bidirectional
+type SyncDirection string
+
+const (
+	// SyncDirectionToGithub applies the spec to the issue on github
+	SyncDirectionToGithub SyncDirection = "ToGithub"
+
+	// SyncDirectionFromGithub writes the issue on github back into the spec
+	SyncDirectionFromGithub SyncDirection = "FromGithub"
+
+	// SyncDirectionBidirectional syncs changes in both directions, the side
+	// which changed since the last sync wins
+	SyncDirectionBidirectional SyncDirection = "Bidirectional"
+)
+
+// LockReason is the reason given for locking the conversation of an issue
+// +kubebuilder:validation:Enum=off-topic;too heated;resolved;spam
+type LockReason string
+
+// ClosePolicy defines what happens to an issue when it is closed, either
+// by setting its state to closed or by deleting the object
+type ClosePolicy struct {
+	// Lock locks the conversation of the issue once it is closed
+	// +optional
+	Lock bool `json:"lock,omitempty"`
+
+	// LockReason is the reason given for locking the conversation
+	// +optional
+	LockReason LockReason `json:"lockReason,omitempty"`
+
+	// Comment is posted on the issue when it is closed because the object was deleted
+	// +optional
+	Comment string `json:"comment,omitempty"`
+}
+
+// ProjectFieldValue is the value of a field of a project item
+type ProjectFieldValue struct {
+	// Name is the name of the field, e.g. Status or Iteration
+	Name string `json:"name"`
+
+	// Value is the value of the field. It is the name of the option for single select fields,
+	// the title of the iteration for iteration fields and a date in YYYY-MM-DD format for date fields
+	Value string `json:"value"`
+}
+
+// DiscussionRecreation requests to recreate the issue as a discussion of its repository
+type DiscussionRecreation struct {
+	// Category is the name of the discussion category the discussion is created in
+	// +kubebuilder:validation:MinLength=1
+	Category string `json:"category"`
+}
+
+// GithubProject references a GitHub project (v2) the issue is added to
+type GithubProject struct {
+	// Owner is the login of the organization or user owning the project,
+	// it defaults to the owner of the repository
+	// +optional
+	Owner string `json:"owner,omitempty"`
+
+	// Number is the number of the project
+	// +optional
+	Number int `json:"number,omitempty"`
+
+	// NodeID is the node ID of the project, it takes precedence over the number
+	// +optional
+	NodeID string `json:"nodeID,omitempty"`
+
+	// Fields are the values of the fields of the project item of the issue
+	// +optional
+	Fields []ProjectFieldValue `json:"fields,omitempty"`
+}
+
 // GithubIssueSpec defines the desired state of GithubIssue
 type GithubIssueSpec struct {
 	// +kubebuilder:validation:Pattern=`(http(s)?)(:(//)?)([\w\.@\:/\-~]+)(/)?`
 	Repo        string `json:"repo,omitempty"`
 	Title       string `json:"title,omitempty"`
 	Description string `json:"description,omitempty"`
+
+	// IssueNumber is the number of an existing issue in the repository that
+	// should be adopted instead of creating a new one. The issue number can
+	// also be given as part of the repository URL, e.g. https://github.com/owner/repo/issues/42
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	IssueNumber int `json:"issueNumber,omitempty"`
+
+	// TitleDriftPolicy determines whether a title which was changed on github
+	// is corrected back to the title in the spec or accepted into the spec
+	// +kubebuilder:default=Correct
+	// +optional
+	TitleDriftPolicy TitleDriftPolicy `json:"titleDriftPolicy,omitempty"`
+
+	// Labels are the names of the labels set on the issue, an empty
+	// list leaves the labels of the issue untouched
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Assignees are the logins of the users assigned to the issue, an
+	// empty list leaves the assignees of the issue untouched
+	// +optional
+	Assignees []string `json:"assignees,omitempty"`
+
+	// State is the state of the issue, an empty state leaves
+	// the state of the issue untouched
+	// +kubebuilder:validation:Enum=open;closed
+	// +optional
+	State string `json:"state,omitempty"`
+
+	// SyncDirection determines whether changes are applied from the spec to github,
+	// from github to the spec or in both directions
+	// +kubebuilder:default=ToGithub
+	// +optional
+	SyncDirection SyncDirection `json:"syncDirection,omitempty"`
+
+	// Locked locks or unlocks the conversation of the issue, leaving it
+	// unset leaves the lock of the issue untouched
+	// +optional
+	Locked *bool `json:"locked,omitempty"`
+
+	// LockReason is the reason given for locking the conversation
+	// +optional
+	LockReason LockReason `json:"lockReason,omitempty"`
+
+	// ClosePolicy defines what happens to the issue when it is closed
+	// +optional
+	ClosePolicy *ClosePolicy `json:"closePolicy,omitempty"`
+
+	// Pinned pins or unpins the issue in its repository, leaving it
+	// unset leaves the issue untouched
+	// +optional
+	Pinned *bool `json:"pinned,omitempty"`
+
+	// IssueType is the name of the issue type set on the issue
+	// +optional
+	IssueType string `json:"issueType,omitempty"`
+
+	// TransferTo is the URL of a repository the issue should be transferred to. Once the
+	// transfer succeeds, the new issue number is recorded in the status, the repository
+	// in the spec is updated and the field is cleared
+	// +kubebuilder:validation:Pattern=`(http(s)?)(:(//)?)([\w\.@\:/\-~]+)(/)?`
+	// +optional
+	TransferTo string `json:"transferTo,omitempty"`
+
+	// Projects are the GitHub projects (v2) the issue is added to
+	// +optional
+	Projects []GithubProject `json:"projects,omitempty"`
+
+	// MilestoneRef references a GithubMilestone in the namespace of the object,
+	// the issue is added to the milestone once the milestone was created
+	// +optional
+	MilestoneRef *corev1.LocalObjectReference `json:"milestoneRef,omitempty"`
+
+	// RecreateAsDiscussion recreates the issue as a discussion of its repository on github, as
+	// github has no api to convert an issue. A new discussion is created with the title and
+	// description of the issue, the issue is closed with a comment linking to the discussion and
+	// it is no longer synced with the object. The comments, reactions, labels, assignees and
+	// history of the issue are not carried over to the discussion, they are only kept on the issue
+	// +optional
+	RecreateAsDiscussion *DiscussionRecreation `json:"recreateAsDiscussion,omitempty"`
+}
+
+// ProjectItemStatus records the item of the issue in a GitHub project
+type ProjectItemStatus struct {
+	ProjectID string `json:"project_id"`
+	ItemID    string `json:"item_id"`
 }
 
 // GithubIssueStatus defines the observed state of GithubIssue
 type GithubIssueStatus struct {
-	ActiveDescription string             `json:"active_description,omitempty"`
-	Conditions        []metav1.Condition `json:"conditions,omitempty"`
+	ActiveTitle       string              `json:"active_title,omitempty"`
+	ActiveDescription string              `json:"active_description,omitempty"`
+	IssueNumber       int                 `json:"issue_number,omitempty"`
+	Locked            bool                `json:"locked,omitempty"`
+	LockReason        string              `json:"lock_reason,omitempty"`
+	Pinned            bool                `json:"pinned,omitempty"`
+	IssueType         string              `json:"issue_type,omitempty"`
+	Milestone         int                 `json:"milestone,omitempty"`
+	ProjectItems      []ProjectItemStatus `json:"project_items,omitempty"`
+	LastAppliedHash   string              `json:"last_applied_hash,omitempty"`
+	GithubUpdatedAt   *metav1.Time        `json:"github_updated_at,omitempty"`
+	DiscussionURL     string              `json:"discussion_url,omitempty"`
+	Conditions        []metav1.Condition  `json:"conditions,omitempty"`
 
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
@@ -42,6 +241,7 @@ type GithubIssueStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:storageversion
 
 // GithubIssue is the Schema for the githubissues API
 type GithubIssue struct {
@@ -0,0 +1,288 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var githubissuelog = logf.Log.WithName("githubissue-resource")
+
+const (
+	// DefaultRepoAnnotation is the namespace annotation holding the repository
+	// of GithubIssue objects which don't name one
+	DefaultRepoAnnotation string = "training.redhat.com/default-repo"
+
+	// DefaultLabelsAnnotation is the namespace annotation holding a comma-separated
+	// list of labels of GithubIssue objects which don't list any
+	DefaultLabelsAnnotation string = "training.redhat.com/default-labels"
+
+	// DefaultAssigneesAnnotation is the namespace annotation holding a comma-separated
+	// list of assignees of GithubIssue objects which don't list any
+	DefaultAssigneesAnnotation string = "training.redhat.com/default-assignees"
+
+	// defaultRepoHost is the host of repositories which are given as owner/repo
+	defaultRepoHost string = "github.com"
+)
+
+// SetupWebhookWithManager registers the webhooks of GithubIssue with the manager,
+// the conversion webhook is served for all versions which implement conversion
+func (r *GithubIssue) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	githubissuelog.Info("setting up webhooks")
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&GithubIssueDefaulter{Client: mgr.GetClient()}).
+		WithValidator(&GithubIssueValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-training-redhat-com-v1alpha1-githubissue,mutating=true,failurePolicy=fail,sideEffects=None,groups=training.redhat.com,resources=githubissues,verbs=create;update,versions=v1alpha1,name=mgithubissue.kb.io,admissionReviewVersions=v1
+
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubissuedefaults,verbs=get;list;watch
+
+// GithubIssueDefaulter fills in the repository, labels and assignees of new GithubIssue
+// objects from the annotations of their namespace or the GithubIssueDefaults objects in
+// it, and normalizes the repository of every object into the https://host/owner/repo form
+// +kubebuilder:object:generate=false
+type GithubIssueDefaulter struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomDefaulter = &GithubIssueDefaulter{}
+
+// Default implements webhook.CustomDefaulter
+func (d *GithubIssueDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	githubissue, ok := obj.(*GithubIssue)
+	if !ok {
+		return fmt.Errorf("expected a GithubIssue but got %T", obj)
+	}
+	githubissuelog.Info("default", "name", githubissue.Name, "namespace", githubissue.Namespace)
+
+	// the defaults only fill in objects which are being created, the creation timestamp
+	// is not set yet, so that emptying a list of an existing object isn't undone
+	if githubissue.CreationTimestamp.IsZero() {
+		defaults, err := d.getDefaults(ctx, githubissue.Namespace)
+		if err != nil {
+			return err
+		}
+
+		if githubissue.Spec.Repo == "" {
+			githubissue.Spec.Repo = defaults.Repo
+		}
+		if len(githubissue.Spec.Labels) == 0 {
+			githubissue.Spec.Labels = defaults.Labels
+		}
+		if len(githubissue.Spec.Assignees) == 0 {
+			githubissue.Spec.Assignees = defaults.Assignees
+		}
+	}
+
+	if githubissue.Spec.Repo == "" {
+		return nil
+	}
+
+	repo, issueNumber, err := NormalizeRepo(githubissue.Spec.Repo)
+	if err != nil {
+		return err
+	}
+
+	githubissue.Spec.Repo = repo
+	if githubissue.Spec.IssueNumber == 0 {
+		githubissue.Spec.IssueNumber = issueNumber
+	}
+
+	return nil
+}
+
+// this function returns the defaults of a namespace, the GithubIssueDefaults objects are
+// merged in the order of their names and the annotations of the namespace override them
+func (d *GithubIssueDefaulter) getDefaults(ctx context.Context, namespace string) (GithubIssueDefaultsSpec, error) {
+	defaults := GithubIssueDefaultsSpec{}
+
+	var defaultsList GithubIssueDefaultsList
+	if err := d.Client.List(ctx, &defaultsList, client.InNamespace(namespace)); err != nil {
+		return defaults, err
+	}
+
+	sort.Slice(defaultsList.Items, func(i, j int) bool {
+		return defaultsList.Items[i].Name < defaultsList.Items[j].Name
+	})
+
+	for _, githubIssueDefaults := range defaultsList.Items {
+		if defaults.Repo == "" {
+			defaults.Repo = githubIssueDefaults.Spec.Repo
+		}
+		if len(defaults.Labels) == 0 {
+			defaults.Labels = githubIssueDefaults.Spec.Labels
+		}
+		if len(defaults.Assignees) == 0 {
+			defaults.Assignees = githubIssueDefaults.Spec.Assignees
+		}
+	}
+
+	var ns corev1.Namespace
+	if err := d.Client.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		return defaults, client.IgnoreNotFound(err)
+	}
+
+	if repo := ns.Annotations[DefaultRepoAnnotation]; repo != "" {
+		defaults.Repo = repo
+	}
+	if labels := splitList(ns.Annotations[DefaultLabelsAnnotation]); len(labels) > 0 {
+		defaults.Labels = labels
+	}
+	if assignees := splitList(ns.Annotations[DefaultAssigneesAnnotation]); len(assignees) > 0 {
+		defaults.Assignees = assignees
+	}
+
+	return defaults, nil
+}
+
+// NormalizeRepo returns the canonical https://host/owner/repo form of a repository reference
+// and the number of the issue it points to, if any. The reference may be given as owner/repo,
+// host/owner/repo, an ssh remote such as git@host:owner/repo.git, or an http(s) URL of the
+// repository or one of its issues. Outside of github.com the owner may be nested, such as
+// a gitlab group with subgroups in host/group/subgroup/repo
+func NormalizeRepo(repo string) (string, int, error) {
+	reference := strings.TrimSpace(repo)
+
+	// ssh remotes separate the host from the path with a colon
+	if match := regexp.MustCompile(`^(?:ssh://)?[^@/]+@([^:/]+)[:/](.+)$`).FindStringSubmatch(reference); match != nil {
+		reference = match[1] + "/" + match[2]
+	}
+
+	reference = regexp.MustCompile(`^(?i)https?://`).ReplaceAllString(reference, "")
+	reference = strings.TrimSuffix(reference, "/")
+
+	issueNumber := 0
+	if match := regexp.MustCompile(`(?:/-)?/issues/(\d+)$`).FindStringSubmatch(reference); match != nil {
+		issueNumber, _ = strconv.Atoi(match[1])
+		reference = strings.TrimSuffix(reference, match[0])
+	}
+
+	reference = strings.TrimSuffix(strings.TrimSuffix(reference, "/"), ".git")
+
+	segments := strings.Split(reference, "/")
+	if len(segments) == 2 {
+		segments = append([]string{defaultRepoHost}, segments...)
+	}
+	if len(segments) < 3 || !strings.Contains(segments[0], ".") {
+		return "", 0, fmt.Errorf("repository %q is not of the form [host/]owner/repo", repo)
+	}
+	if len(segments) > 3 && strings.EqualFold(segments[0], defaultRepoHost) {
+		return "", 0, fmt.Errorf("repository %q is not of the form [host/]owner/repo, only repositories outside of %s may be nested in groups", repo, defaultRepoHost)
+	}
+
+	for _, segment := range segments {
+		if segment == "" {
+			return "", 0, fmt.Errorf("repository %q is not of the form [host/]owner/repo", repo)
+		}
+	}
+
+	return fmt.Sprintf("https://%s/%s", strings.ToLower(segments[0]), strings.Join(segments[1:], "/")), issueNumber, nil
+}
+
+//+kubebuilder:webhook:path=/validate-training-redhat-com-v1alpha1-githubissue,mutating=false,failurePolicy=fail,sideEffects=None,groups=training.redhat.com,resources=githubissues,verbs=create;update,versions=v1alpha1,name=vgithubissue.kb.io,admissionReviewVersions=v1
+
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubrepopolicies,verbs=get;list;watch
+
+// GithubIssueValidator rejects GithubIssue objects which violate
+// the GithubRepoPolicy objects applying to their namespace
+// +kubebuilder:object:generate=false
+type GithubIssueValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &GithubIssueValidator{}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *GithubIssueValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	githubissue, ok := obj.(*GithubIssue)
+	if !ok {
+		return fmt.Errorf("expected a GithubIssue but got %T", obj)
+	}
+	githubissuelog.Info("validate create", "name", githubissue.Name, "namespace", githubissue.Namespace)
+
+	return v.validatePolicies(ctx, githubissue)
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *GithubIssueValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	oldGithubIssue, ok := oldObj.(*GithubIssue)
+	if !ok {
+		return fmt.Errorf("expected a GithubIssue but got %T", oldObj)
+	}
+	githubissue, ok := newObj.(*GithubIssue)
+	if !ok {
+		return fmt.Errorf("expected a GithubIssue but got %T", newObj)
+	}
+	githubissuelog.Info("validate update", "name", githubissue.Name, "namespace", githubissue.Namespace)
+
+	// updates which don't change the spec, such as removing the finalizer, are always
+	// allowed so that objects which violate a policy can still be deleted
+	if equality.Semantic.DeepEqual(oldGithubIssue.Spec, githubissue.Spec) {
+		return nil
+	}
+
+	return v.validatePolicies(ctx, githubissue)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *GithubIssueValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// this function returns an error listing the violations of the policies of an object
+func (v *GithubIssueValidator) validatePolicies(ctx context.Context, githubissue *GithubIssue) error {
+	violations, err := CheckRepoPolicies(ctx, v.Client, githubissue)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("githubissue violates repository policies: %s", strings.Join(violations, "; "))
+	}
+
+	return nil
+}
+
+// this function splits a comma-separated list, ignoring empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
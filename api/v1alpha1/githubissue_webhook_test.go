@@ -0,0 +1,138 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// this function returns a defaulter reading the given objects
+func newFakeDefaulter(g *WithT, objs ...client.Object) *GithubIssueDefaulter {
+	s := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(s)).To(Succeed())
+	g.Expect(AddToScheme(s)).To(Succeed())
+
+	return &GithubIssueDefaulter{Client: fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()}
+}
+
+// this function returns a new object in the default namespace
+func newDefaultedGithubIssue(spec GithubIssueSpec) *GithubIssue {
+	return &GithubIssue{
+		ObjectMeta: metav1.ObjectMeta{Name: "issue", Namespace: "default"},
+		Spec:       spec,
+	}
+}
+
+func TestDefaultFromNamespaceAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "default",
+			Annotations: map[string]string{
+				DefaultRepoAnnotation:      "mzeevi/githubissues-operator",
+				DefaultLabelsAnnotation:    "bug, triage,",
+				DefaultAssigneesAnnotation: "mzeevi",
+			},
+		},
+	}
+	githubIssueDefaults := &GithubIssueDefaults{
+		ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "default"},
+		Spec: GithubIssueDefaultsSpec{
+			Repo:      "https://github.com/other/repo",
+			Labels:    []string{"ignored"},
+			Assignees: []string{"ignored"},
+		},
+	}
+	d := newFakeDefaulter(g, ns, githubIssueDefaults)
+
+	// the annotations of the namespace override the GithubIssueDefaults objects
+	githubissue := newDefaultedGithubIssue(GithubIssueSpec{Title: "title"})
+	g.Expect(d.Default(context.Background(), githubissue)).To(Succeed())
+	g.Expect(githubissue.Spec.Repo).To(Equal("https://github.com/mzeevi/githubissues-operator"))
+	g.Expect(githubissue.Spec.Labels).To(Equal([]string{"bug", "triage"}))
+	g.Expect(githubissue.Spec.Assignees).To(Equal([]string{"mzeevi"}))
+}
+
+func TestDefaultFromGithubIssueDefaults(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	githubIssueDefaults := &GithubIssueDefaults{
+		ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "default"},
+		Spec: GithubIssueDefaultsSpec{
+			Repo:      "git@github.com:mzeevi/githubissues-operator.git",
+			Labels:    []string{"bug"},
+			Assignees: []string{"mzeevi"},
+		},
+	}
+	d := newFakeDefaulter(g, githubIssueDefaults)
+
+	// the fields of the spec take precedence over the defaults
+	githubissue := newDefaultedGithubIssue(GithubIssueSpec{Title: "title", Labels: []string{"feature"}})
+	g.Expect(d.Default(context.Background(), githubissue)).To(Succeed())
+	g.Expect(githubissue.Spec.Repo).To(Equal("https://github.com/mzeevi/githubissues-operator"))
+	g.Expect(githubissue.Spec.Labels).To(Equal([]string{"feature"}))
+	g.Expect(githubissue.Spec.Assignees).To(Equal([]string{"mzeevi"}))
+
+	// existing objects are only normalized
+	existing := newDefaultedGithubIssue(GithubIssueSpec{Title: "title", Repo: "mzeevi/githubissues-operator/issues/3"})
+	existing.CreationTimestamp = metav1.Now()
+	g.Expect(d.Default(context.Background(), existing)).To(Succeed())
+	g.Expect(existing.Spec.Repo).To(Equal("https://github.com/mzeevi/githubissues-operator"))
+	g.Expect(existing.Spec.IssueNumber).To(Equal(3))
+	g.Expect(existing.Spec.Labels).To(BeEmpty())
+}
+
+func TestNormalizeRepo(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		repo        string
+		expected    string
+		issueNumber int
+	}{
+		{"mzeevi/githubissues-operator", "https://github.com/mzeevi/githubissues-operator", 0},
+		{"github.com/mzeevi/githubissues-operator/", "https://github.com/mzeevi/githubissues-operator", 0},
+		{"http://GitHub.com/mzeevi/githubissues-operator.git", "https://github.com/mzeevi/githubissues-operator", 0},
+		{"git@gitlab.example.com:team/project.git", "https://gitlab.example.com/team/project", 0},
+		{"ssh://git@github.com/mzeevi/githubissues-operator.git", "https://github.com/mzeevi/githubissues-operator", 0},
+		{"https://github.com/mzeevi/githubissues-operator/issues/42/", "https://github.com/mzeevi/githubissues-operator", 42},
+		{"gitlab.example.com/group/sub/project", "https://gitlab.example.com/group/sub/project", 0},
+		{"git@gitlab.example.com:group/sub/project.git", "https://gitlab.example.com/group/sub/project", 0},
+		{"https://gitlab.example.com/group/sub/project/-/issues/7", "https://gitlab.example.com/group/sub/project", 7},
+	}
+
+	for _, test := range tests {
+		repo, issueNumber, err := NormalizeRepo(test.repo)
+		g.Expect(err).ToNot(HaveOccurred(), test.repo)
+		g.Expect(repo).To(Equal(test.expected), test.repo)
+		g.Expect(issueNumber).To(Equal(test.issueNumber), test.repo)
+	}
+
+	for _, repo := range []string{"githubissues-operator", "a/b/c/d", "https://github.com//repo", "github.com/group/sub/project", "gitlab.example.com/group//project"} {
+		_, _, err := NormalizeRepo(repo)
+		g.Expect(err).To(HaveOccurred(), repo)
+	}
+}
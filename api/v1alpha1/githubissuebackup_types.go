@@ -0,0 +1,114 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BackupFormat is the format the issues of a backup are written in
+// +kubebuilder:validation:Enum=JSON;Markdown
+type BackupFormat string
+
+const (
+	// BackupFormatJSON writes the issues as json, which can be restored into another repository
+	BackupFormatJSON BackupFormat = "JSON"
+
+	// BackupFormatMarkdown writes the issues as a markdown document meant to be read
+	BackupFormatMarkdown BackupFormat = "Markdown"
+)
+
+// BackupDestination is where the backups are written, exactly one destination has to be set
+type BackupDestination struct {
+	// ConfigMap is the name of a ConfigMap in the namespace of the backup which
+	// holds the latest backup, it is created if it doesn't exist
+	// +optional
+	ConfigMap string `json:"configMap,omitempty"`
+
+	// File is a directory, relative to the --backup-dir of the operator, to which a file is
+	// written for every backup. a persistent volume is mounted at --backup-dir to keep the files
+	// +optional
+	File string `json:"file,omitempty"`
+
+	// Retain is the number of the latest files of the backup which are kept in the File
+	// directory, older files of the backup are deleted. all the files are kept if it is not set
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Retain int `json:"retain,omitempty"`
+}
+
+// GithubIssueBackupSpec defines the desired state of GithubIssueBackup
+type GithubIssueBackupSpec struct {
+	// Selector selects the GithubIssue objects in the namespace of the backup
+	// whose issues are backed up, the issues of all the objects are backed up if it is not set
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// ClusterIssues also backs up the issues of the ClusterGithubIssue objects selected by
+	// the selector, they can be read by anyone who can read the destination of the backup
+	// +optional
+	ClusterIssues bool `json:"clusterIssues,omitempty"`
+
+	// Interval is the time between two backups, e.g. 24h. a single backup is taken if it is not set
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Format is the format of the backups
+	// +kubebuilder:default=JSON
+	// +optional
+	Format BackupFormat `json:"format,omitempty"`
+
+	// Destination is where the backups are written
+	Destination BackupDestination `json:"destination"`
+}
+
+// GithubIssueBackupStatus defines the observed state of GithubIssueBackup
+type GithubIssueBackupStatus struct {
+	LastBackupTime     *metav1.Time       `json:"last_backup_time,omitempty"`
+	LastBackupLocation string             `json:"last_backup_location,omitempty"`
+	BackedUpIssues     int                `json:"backed_up_issues,omitempty"`
+	FailedIssues       int                `json:"failed_issues,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Issues",type=integer,JSONPath=`.status.backed_up_issues`
+//+kubebuilder:printcolumn:name="Last Backup",type=date,JSONPath=`.status.last_backup_time`
+//+kubebuilder:printcolumn:name="Location",type=string,JSONPath=`.status.last_backup_location`
+
+// GithubIssueBackup is the Schema for the githubissuebackups API
+type GithubIssueBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubIssueBackupSpec   `json:"spec,omitempty"`
+	Status GithubIssueBackupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubIssueBackupList contains a list of GithubIssueBackup
+type GithubIssueBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubIssueBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubIssueBackup{}, &GithubIssueBackupList{})
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GithubIssueDefaultsSpec defines the defaults of the GithubIssue objects in a namespace
+type GithubIssueDefaultsSpec struct {
+	// Repo is the repository of objects which don't name one
+	// +optional
+	Repo string `json:"repo,omitempty"`
+
+	// Labels are the labels of objects which don't list any
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Assignees are the assignees of objects which don't list any
+	// +optional
+	Assignees []string `json:"assignees,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=githubissuedefaults
+
+// GithubIssueDefaults is the Schema for the githubissuedefaults API, it holds the
+// values the defaulting webhook fills into the GithubIssue objects of its namespace
+type GithubIssueDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GithubIssueDefaultsSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubIssueDefaultsList contains a list of GithubIssueDefaults
+type GithubIssueDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubIssueDefaults `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubIssueDefaults{}, &GithubIssueDefaultsList{})
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RepoSelector selects the repositories of an organization or user
+type RepoSelector struct {
+	// Owner is the login of the organization or user owning the repositories
+	// +kubebuilder:validation:MinLength=1
+	Owner string `json:"owner"`
+
+	// NamePattern is a regular expression the name of a repository has to match
+	// +optional
+	NamePattern string `json:"namePattern,omitempty"`
+
+	// Topics are topics a repository has to be tagged with, all of them have to match
+	// +optional
+	Topics []string `json:"topics,omitempty"`
+}
+
+// GithubIssueTemplateSpec defines the desired state of GithubIssueTemplate
+type GithubIssueTemplateSpec struct {
+	// Title is a go template of the title of the issues, it is rendered
+	// for every repository with the fields .Repo, .Owner and .Name
+	// +kubebuilder:validation:MinLength=1
+	Title string `json:"title"`
+
+	// Description is a go template of the description of the issues, it is
+	// rendered for every repository with the fields .Repo, .Owner and .Name
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Labels are the names of the labels set on the issues
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Repos are the URLs of the repositories an issue is filed in
+	// +optional
+	Repos []string `json:"repos,omitempty"`
+
+	// RepoSelector selects further repositories an issue is filed in
+	// +optional
+	RepoSelector *RepoSelector `json:"repoSelector,omitempty"`
+
+	// MaxInFlight is the maximum number of issues which are created
+	// or updated at the same time when the template changes
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=5
+	// +optional
+	MaxInFlight int `json:"maxInFlight,omitempty"`
+}
+
+// GithubIssueTemplateStatus defines the observed state of GithubIssueTemplate
+type GithubIssueTemplateStatus struct {
+	Repos        int                `json:"repos"`
+	UpdatedRepos int                `json:"updated_repos"`
+	OpenIssues   int                `json:"open_issues"`
+	ClosedIssues int                `json:"closed_issues"`
+	FailedIssues int                `json:"failed_issues"`
+	Conditions   []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Repos",type=integer,JSONPath=`.status.repos`
+//+kubebuilder:printcolumn:name="Updated",type=integer,JSONPath=`.status.updated_repos`
+//+kubebuilder:printcolumn:name="Open",type=integer,JSONPath=`.status.open_issues`
+//+kubebuilder:printcolumn:name="Closed",type=integer,JSONPath=`.status.closed_issues`
+//+kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed_issues`
+
+// GithubIssueTemplate is the Schema for the githubissuetemplates API
+type GithubIssueTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubIssueTemplateSpec   `json:"spec,omitempty"`
+	Status GithubIssueTemplateStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubIssueTemplateList contains a list of GithubIssueTemplate
+type GithubIssueTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubIssueTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubIssueTemplate{}, &GithubIssueTemplateList{})
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GithubLabelSpec defines the desired state of GithubLabel
+type GithubLabelSpec struct {
+	// +kubebuilder:validation:Pattern=`(http(s)?)(:(//)?)([\w\.@\:/\-~]+)(/)?`
+	Repo string `json:"repo,omitempty"`
+
+	// Name is the name of the label in the repository
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Color is the hexadecimal color code of the label without the leading #
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F]{6}$`
+	// +optional
+	Color string `json:"color,omitempty"`
+
+	// Description is a short description of the label
+	// +kubebuilder:validation:MaxLength=100
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// GithubLabelStatus defines the observed state of GithubLabel
+type GithubLabelStatus struct {
+	ActiveName        string             `json:"active_name,omitempty"`
+	ActiveColor       string             `json:"active_color,omitempty"`
+	ActiveDescription string             `json:"active_description,omitempty"`
+	Conditions        []metav1.Condition `json:"conditions,omitempty"`
+
+	// Created is whether the label was created by the operator, only labels which were
+	// created by the operator are deleted from the repository with the object
+	Created bool `json:"created,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// GithubLabel is the Schema for the githublabels API
+type GithubLabel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubLabelSpec   `json:"spec,omitempty"`
+	Status GithubLabelStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubLabelList contains a list of GithubLabel
+type GithubLabelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubLabel `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubLabel{}, &GithubLabelList{})
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GithubMilestoneSpec defines the desired state of GithubMilestone
+type GithubMilestoneSpec struct {
+	// +kubebuilder:validation:Pattern=`(http(s)?)(:(//)?)([\w\.@\:/\-~]+)(/)?`
+	Repo string `json:"repo,omitempty"`
+
+	// Title is the title of the milestone in the repository
+	// +kubebuilder:validation:MinLength=1
+	Title string `json:"title"`
+
+	// Description is the description of the milestone
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// DueOn is the date the milestone is due
+	// +optional
+	DueOn *metav1.Time `json:"dueOn,omitempty"`
+
+	// State is the state of the milestone
+	// +kubebuilder:validation:Enum=open;closed
+	// +kubebuilder:default=open
+	// +optional
+	State string `json:"state,omitempty"`
+}
+
+// GithubMilestoneStatus defines the observed state of GithubMilestone
+type GithubMilestoneStatus struct {
+	MilestoneNumber int                `json:"milestone_number,omitempty"`
+	ActiveTitle     string             `json:"active_title,omitempty"`
+	State           string             `json:"state,omitempty"`
+	OpenIssues      int                `json:"open_issues"`
+	ClosedIssues    int                `json:"closed_issues"`
+	Conditions      []metav1.Condition `json:"conditions,omitempty"`
+	// Created is whether the milestone was created by the operator, only milestones
+	// which were created by the operator are deleted from the repository with the object
+	Created bool `json:"created,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Number",type=integer,JSONPath=`.status.milestone_number`
+//+kubebuilder:printcolumn:name="Open",type=integer,JSONPath=`.status.open_issues`
+//+kubebuilder:printcolumn:name="Closed",type=integer,JSONPath=`.status.closed_issues`
+//+kubebuilder:printcolumn:name="Due",type=date,JSONPath=`.spec.dueOn`
+
+// GithubMilestone is the Schema for the githubmilestones API
+type GithubMilestone struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubMilestoneSpec   `json:"spec,omitempty"`
+	Status GithubMilestoneStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubMilestoneList contains a list of GithubMilestone
+type GithubMilestoneList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubMilestone `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubMilestone{}, &GithubMilestoneList{})
+}
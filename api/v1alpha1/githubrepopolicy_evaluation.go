@@ -0,0 +1,180 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CheckRepoPolicies returns the violations of the policies which apply to the namespace of an
+// object. It is shared by the validating webhook and the reconciler, so that objects created
+// before a policy, or while the webhook wasn't served, are held back as well
+func CheckRepoPolicies(ctx context.Context, c client.Reader, githubissue *GithubIssue) ([]string, error) {
+	// cluster-scoped objects belong to no namespace and are not restricted by policies
+	if githubissue.Namespace == "" {
+		return nil, nil
+	}
+
+	var policies GithubRepoPolicyList
+	if err := c.List(ctx, &policies); err != nil {
+		return nil, err
+	}
+	if len(policies.Items) == 0 {
+		return nil, nil
+	}
+
+	var ns corev1.Namespace
+	if err := c.Get(ctx, types.NamespacedName{Name: githubissue.Namespace}, &ns); err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+
+		selected, err := policy.Selects(&ns)
+		if err != nil {
+			return nil, err
+		}
+		if !selected {
+			continue
+		}
+
+		violations = append(violations, policy.Violations(&githubissue.Spec)...)
+
+		if policy.Spec.MaxIssues > 0 {
+			count, err := countOlderGithubIssues(ctx, c, githubissue)
+			if err != nil {
+				return nil, err
+			}
+			if count >= policy.Spec.MaxIssues {
+				violations = append(violations, fmt.Sprintf("policy %s allows at most %d issues in namespace %s", policy.Name, policy.Spec.MaxIssues, githubissue.Namespace))
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// Selects returns whether the policy applies to a namespace
+func (p *GithubRepoPolicy) Selects(ns *corev1.Namespace) (bool, error) {
+	if p.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector)
+	if err != nil {
+		return false, err
+	}
+
+	return selector.Matches(labels.Set(ns.Labels)), nil
+}
+
+// Violations returns the reasons why the repositories, labels and assignees of a spec
+// are not allowed by the policy, the limit of the number of objects is checked separately
+func (p *GithubRepoPolicy) Violations(spec *GithubIssueSpec) []string {
+	var violations []string
+
+	for _, repo := range []string{spec.Repo, spec.TransferTo} {
+		if repo != "" && !p.allowsRepo(repo) {
+			violations = append(violations, fmt.Sprintf("policy %s does not allow repository %s", p.Name, repo))
+		}
+	}
+
+	if p.Spec.MaxLabels > 0 && len(spec.Labels) > p.Spec.MaxLabels {
+		violations = append(violations, fmt.Sprintf("policy %s allows at most %d labels", p.Name, p.Spec.MaxLabels))
+	}
+	for _, label := range spec.Labels {
+		if len(p.Spec.AllowedLabels) > 0 && !matchesAnyPattern(p.Spec.AllowedLabels, label) {
+			violations = append(violations, fmt.Sprintf("policy %s does not allow label %s", p.Name, label))
+		}
+	}
+
+	if p.Spec.MaxAssignees > 0 && len(spec.Assignees) > p.Spec.MaxAssignees {
+		violations = append(violations, fmt.Sprintf("policy %s allows at most %d assignees", p.Name, p.Spec.MaxAssignees))
+	}
+	for _, assignee := range spec.Assignees {
+		if len(p.Spec.AllowedAssignees) > 0 && !matchesAnyPattern(p.Spec.AllowedAssignees, assignee) {
+			violations = append(violations, fmt.Sprintf("policy %s does not allow assignee %s", p.Name, assignee))
+		}
+	}
+
+	return violations
+}
+
+// this function checks whether a repository matches one of the allowed patterns
+// of the policy, both are compared in the host/owner/repo form
+func (p *GithubRepoPolicy) allowsRepo(repo string) bool {
+	normalized, _, err := NormalizeRepo(repo)
+	if err != nil {
+		return false
+	}
+	normalized = strings.TrimPrefix(normalized, "https://")
+
+	for _, pattern := range p.Spec.AllowedRepos {
+		if strings.Count(pattern, "/") == 1 {
+			pattern = defaultRepoHost + "/" + pattern
+		}
+		if matchesAnyPattern([]string{pattern}, normalized) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// this function checks whether a value matches one of the patterns, case-insensitively
+func matchesAnyPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(value)); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// this function counts the objects in the namespace of an object which were created before
+// it and are not being deleted, all other objects are counted for an object being created
+func countOlderGithubIssues(ctx context.Context, c client.Reader, githubissue *GithubIssue) (int, error) {
+	var githubissues GithubIssueList
+	if err := c.List(ctx, &githubissues, client.InNamespace(githubissue.Namespace)); err != nil {
+		return 0, err
+	}
+
+	created := githubissue.CreationTimestamp
+	count := 0
+	for _, other := range githubissues.Items {
+		if other.Name == githubissue.Name || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if created.IsZero() || other.CreationTimestamp.Before(&created) ||
+			(other.CreationTimestamp.Equal(&created) && other.Name < githubissue.Name) {
+			count++
+		}
+	}
+
+	return count, nil
+}
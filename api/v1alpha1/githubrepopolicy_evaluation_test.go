@@ -0,0 +1,133 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// this function returns a policy allowing the repositories of the mzeevi user
+func newGithubRepoPolicy() *GithubRepoPolicy {
+	return &GithubRepoPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy"},
+		Spec: GithubRepoPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}},
+			AllowedRepos:      []string{"mzeevi/*", "gitlab.com/team/project"},
+			AllowedLabels:     []string{"bug", "team/*"},
+			MaxAssignees:      1,
+			MaxIssues:         2,
+		},
+	}
+}
+
+// this function returns a client reading the given objects
+func newFakePolicyClient(g *WithT, objs ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(s)).To(Succeed())
+	g.Expect(AddToScheme(s)).To(Succeed())
+
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+}
+
+func TestRepoPolicyViolations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	policy := newGithubRepoPolicy()
+
+	g.Expect(policy.Violations(&GithubIssueSpec{
+		Repo:      "https://github.com/mzeevi/githubissues-operator",
+		Labels:    []string{"bug", "team/platform"},
+		Assignees: []string{"mzeevi"},
+	})).To(BeEmpty())
+
+	g.Expect(policy.Violations(&GithubIssueSpec{Repo: "git@gitlab.com:team/project.git"})).To(BeEmpty())
+
+	violations := policy.Violations(&GithubIssueSpec{
+		Repo:       "https://github.com/other/repo",
+		TransferTo: "https://github.com/mzeevi/elsewhere",
+		Labels:     []string{"feature"},
+		Assignees:  []string{"mzeevi", "other"},
+	})
+	g.Expect(violations).To(ConsistOf(
+		"policy policy does not allow repository https://github.com/other/repo",
+		"policy policy does not allow label feature",
+		"policy policy allows at most 1 assignees",
+	))
+}
+
+func TestCheckRepoPolicies(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	created := metav1.NewTime(time.Date(2022, time.October, 1, 12, 0, 0, 0, time.UTC))
+	newObject := func(name, namespace string, age time.Duration) *GithubIssue {
+		return &GithubIssue{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, CreationTimestamp: metav1.NewTime(created.Add(age))},
+			Spec:       GithubIssueSpec{Repo: "https://github.com/other/repo"},
+		}
+	}
+
+	platform := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "platform", Labels: map[string]string{"team": "platform"}}}
+	other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+	first := newObject("first", "platform", 0)
+	second := newObject("second", "platform", time.Minute)
+	third := newObject("third", "platform", 2*time.Minute)
+	for _, githubissue := range []*GithubIssue{first, second, third} {
+		githubissue.Spec.Repo = "https://github.com/mzeevi/githubissues-operator"
+	}
+
+	c := newFakePolicyClient(g, newGithubRepoPolicy(), platform, other, first, second, third)
+
+	// namespaces which are not selected by the policy are not restricted
+	violations, err := CheckRepoPolicies(ctx, c, newObject("unrestricted", "other", 0))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(violations).To(BeEmpty())
+
+	violations, err = CheckRepoPolicies(ctx, c, newObject("restricted", "platform", 0))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(violations).ToNot(BeEmpty())
+
+	// only the oldest objects are within the limit of the number of objects
+	violations, err = CheckRepoPolicies(ctx, c, second)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(violations).To(BeEmpty())
+
+	violations, err = CheckRepoPolicies(ctx, c, third)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(violations).To(ConsistOf("policy policy allows at most 2 issues in namespace platform"))
+
+	// an object being created is counted after all existing objects
+	validator := &GithubIssueValidator{Client: c}
+	fourth := first.DeepCopy()
+	fourth.Name = "fourth"
+	fourth.CreationTimestamp = metav1.Time{}
+	g.Expect(validator.ValidateCreate(ctx, fourth)).ToNot(Succeed())
+
+	// updates which don't change the spec are allowed
+	updated := third.DeepCopy()
+	updated.Finalizers = nil
+	g.Expect(validator.ValidateUpdate(ctx, third, updated)).To(Succeed())
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GithubRepoPolicySpec defines which repositories the GithubIssue objects of a set of
+// namespaces may target and how many labels, assignees and objects they may have
+type GithubRepoPolicySpec struct {
+	// NamespaceSelector selects the namespaces the policy applies to,
+	// the policy applies to all namespaces if it is not set
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// AllowedRepos are the patterns of the repositories which may be targeted, in the
+	// owner/repo or host/owner/repo form, e.g. mzeevi/* or gitlab.com/team/*. Patterns
+	// without a host match repositories on github.com
+	// +kubebuilder:validation:MinItems=1
+	AllowedRepos []string `json:"allowedRepos"`
+
+	// AllowedLabels are the patterns of the labels which may be set, any label may be set if it is empty
+	// +optional
+	AllowedLabels []string `json:"allowedLabels,omitempty"`
+
+	// AllowedAssignees are the patterns of the users which may be assigned, any user may be assigned if it is empty
+	// +optional
+	AllowedAssignees []string `json:"allowedAssignees,omitempty"`
+
+	// MaxLabels is the maximum number of labels of an object, 0 means no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxLabels int `json:"maxLabels,omitempty"`
+
+	// MaxAssignees is the maximum number of assignees of an object, 0 means no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxAssignees int `json:"maxAssignees,omitempty"`
+
+	// MaxIssues is the maximum number of GithubIssue objects in a namespace, 0 means no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxIssues int `json:"maxIssues,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// GithubRepoPolicy is the Schema for the githubrepopolicies API, it restricts the
+// GithubIssue objects of the namespaces it selects. An object has to satisfy
+// every policy which applies to its namespace
+type GithubRepoPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GithubRepoPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubRepoPolicyList contains a list of GithubRepoPolicy
+type GithubRepoPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubRepoPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubRepoPolicy{}, &GithubRepoPolicyList{})
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+const (
+	// repoAnnotation keeps the repository URL of a v1alpha1 object
+	// which can't be rebuilt from the structured repository
+	repoAnnotation string = "training.redhat.com/v1alpha1-repo"
+
+	// transferToAnnotation keeps the URL of the repository the issue of a v1alpha1
+	// object is transferred to if it can't be rebuilt from the structured repository
+	transferToAnnotation string = "training.redhat.com/v1alpha1-transfer-to"
+
+	defaultHost string = "github.com"
+)
+
+var issueURLRegexp = regexp.MustCompile(`(?:/-)?/issues/(\d+)$`)
+
+// ConvertTo converts this GithubIssue to the Hub version (v1alpha1)
+func (src *GithubIssue) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.GithubIssue)
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	repo, issueNumber := restoreRepositoryURL(src.Annotations, repoAnnotation, src.Spec.Repository, src.Spec.IssueNumber)
+	transferTo := ""
+	if src.Spec.TransferTo != nil {
+		transferTo, _ = restoreRepositoryURL(src.Annotations, transferToAnnotation, *src.Spec.TransferTo, 0)
+	}
+	removeAnnotation(dst, repoAnnotation)
+	removeAnnotation(dst, transferToAnnotation)
+
+	dst.Spec = v1alpha1.GithubIssueSpec{
+		Repo:             repo,
+		Title:            src.Spec.Title,
+		Description:      src.Spec.Description,
+		IssueNumber:      issueNumber,
+		TitleDriftPolicy: v1alpha1.TitleDriftPolicy(src.Spec.TitleDriftPolicy),
+		Labels:           src.Spec.Labels,
+		Assignees:        src.Spec.Assignees,
+		State:            src.Spec.State,
+		SyncDirection:    v1alpha1.SyncDirection(src.Spec.SyncDirection),
+		Locked:           src.Spec.Locked,
+		LockReason:       v1alpha1.LockReason(src.Spec.LockReason),
+		Pinned:           src.Spec.Pinned,
+		IssueType:        src.Spec.IssueType,
+		TransferTo:       transferTo,
+		MilestoneRef:     src.Spec.MilestoneRef,
+	}
+	if closePolicy := src.Spec.ClosePolicy; closePolicy != nil {
+		dst.Spec.ClosePolicy = &v1alpha1.ClosePolicy{
+			Lock:       closePolicy.Lock,
+			LockReason: v1alpha1.LockReason(closePolicy.LockReason),
+			Comment:    closePolicy.Comment,
+		}
+	}
+	if conversion := src.Spec.RecreateAsDiscussion; conversion != nil {
+		dst.Spec.RecreateAsDiscussion = &v1alpha1.DiscussionRecreation{Category: conversion.Category}
+	}
+	for _, project := range src.Spec.Projects {
+		dstProject := v1alpha1.GithubProject{Owner: project.Owner, Number: project.Number, NodeID: project.NodeID}
+		for _, field := range project.Fields {
+			dstProject.Fields = append(dstProject.Fields, v1alpha1.ProjectFieldValue{Name: field.Name, Value: field.Value})
+		}
+		dst.Spec.Projects = append(dst.Spec.Projects, dstProject)
+	}
+
+	dst.Status = v1alpha1.GithubIssueStatus{
+		ActiveTitle:       src.Status.ActiveTitle,
+		ActiveDescription: src.Status.ActiveDescription,
+		IssueNumber:       src.Status.IssueNumber,
+		Locked:            src.Status.Locked,
+		LockReason:        src.Status.LockReason,
+		Pinned:            src.Status.Pinned,
+		IssueType:         src.Status.IssueType,
+		Milestone:         src.Status.Milestone,
+		LastAppliedHash:   src.Status.LastAppliedHash,
+		GithubUpdatedAt:   src.Status.GithubUpdatedAt,
+		DiscussionURL:     src.Status.DiscussionURL,
+		Conditions:        src.Status.Conditions,
+	}
+	for _, item := range src.Status.ProjectItems {
+		dst.Status.ProjectItems = append(dst.Status.ProjectItems, v1alpha1.ProjectItemStatus{ProjectID: item.ProjectID, ItemID: item.ItemID})
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1alpha1) to this version
+func (dst *GithubIssue) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.GithubIssue)
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	repository, issueNumber, err := ParseRepositoryURL(src.Spec.Repo)
+	if err != nil {
+		return err
+	}
+	keepRepositoryURL(dst, repoAnnotation, src.Spec.Repo, repository)
+
+	// the issue number may also be given as part of the repository URL
+	if src.Spec.IssueNumber != 0 {
+		issueNumber = src.Spec.IssueNumber
+	}
+
+	var transferTo *Repository
+	if src.Spec.TransferTo != "" {
+		transferRepository, _, err := ParseRepositoryURL(src.Spec.TransferTo)
+		if err != nil {
+			return err
+		}
+		keepRepositoryURL(dst, transferToAnnotation, src.Spec.TransferTo, transferRepository)
+		transferTo = &transferRepository
+	}
+
+	dst.Spec = GithubIssueSpec{
+		Repository:       repository,
+		Title:            src.Spec.Title,
+		Description:      src.Spec.Description,
+		IssueNumber:      issueNumber,
+		TitleDriftPolicy: TitleDriftPolicy(src.Spec.TitleDriftPolicy),
+		Labels:           src.Spec.Labels,
+		Assignees:        src.Spec.Assignees,
+		State:            src.Spec.State,
+		SyncDirection:    SyncDirection(src.Spec.SyncDirection),
+		Locked:           src.Spec.Locked,
+		LockReason:       LockReason(src.Spec.LockReason),
+		Pinned:           src.Spec.Pinned,
+		IssueType:        src.Spec.IssueType,
+		TransferTo:       transferTo,
+		MilestoneRef:     src.Spec.MilestoneRef,
+	}
+	if closePolicy := src.Spec.ClosePolicy; closePolicy != nil {
+		dst.Spec.ClosePolicy = &ClosePolicy{
+			Lock:       closePolicy.Lock,
+			LockReason: LockReason(closePolicy.LockReason),
+			Comment:    closePolicy.Comment,
+		}
+	}
+	if conversion := src.Spec.RecreateAsDiscussion; conversion != nil {
+		dst.Spec.RecreateAsDiscussion = &DiscussionRecreation{Category: conversion.Category}
+	}
+	for _, project := range src.Spec.Projects {
+		dstProject := GithubProject{Owner: project.Owner, Number: project.Number, NodeID: project.NodeID}
+		for _, field := range project.Fields {
+			dstProject.Fields = append(dstProject.Fields, ProjectFieldValue{Name: field.Name, Value: field.Value})
+		}
+		dst.Spec.Projects = append(dst.Spec.Projects, dstProject)
+	}
+
+	dst.Status = GithubIssueStatus{
+		ActiveTitle:       src.Status.ActiveTitle,
+		ActiveDescription: src.Status.ActiveDescription,
+		IssueNumber:       src.Status.IssueNumber,
+		Locked:            src.Status.Locked,
+		LockReason:        src.Status.LockReason,
+		Pinned:            src.Status.Pinned,
+		IssueType:         src.Status.IssueType,
+		Milestone:         src.Status.Milestone,
+		LastAppliedHash:   src.Status.LastAppliedHash,
+		GithubUpdatedAt:   src.Status.GithubUpdatedAt,
+		DiscussionURL:     src.Status.DiscussionURL,
+		Conditions:        src.Status.Conditions,
+	}
+	for _, item := range src.Status.ProjectItems {
+		dst.Status.ProjectItems = append(dst.Status.ProjectItems, ProjectItemStatus{ProjectID: item.ProjectID, ItemID: item.ItemID})
+	}
+
+	return nil
+}
+
+// URL returns the URL of the repository
+func (r Repository) URL() string {
+	if r.Owner == "" && r.Name == "" {
+		return ""
+	}
+
+	host := r.Host
+	if host == "" {
+		host = defaultHost
+	}
+	return "https://" + host + "/" + r.Owner + "/" + r.Name
+}
+
+// ParseRepositoryURL parses the URL of a repository, which may also be the URL of an issue in
+// the repository, and returns the repository and the number of the issue in the URL. an empty
+// URL is an empty repository
+func ParseRepositoryURL(repositoryURL string) (Repository, int, error) {
+	if repositoryURL == "" {
+		return Repository{}, 0, nil
+	}
+
+	path := strings.TrimSuffix(repositoryURL, "/")
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "https://"), "http://")
+
+	issueNumber := 0
+	if match := issueURLRegexp.FindStringSubmatch(path); match != nil {
+		issueNumber, _ = strconv.Atoi(match[1])
+		path = strings.TrimSuffix(path, match[0])
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 || parts[len(parts)-2] == "" || parts[len(parts)-1] == "" {
+		return Repository{}, 0, fmt.Errorf("unable to parse repository URL %q", repositoryURL)
+	}
+
+	// the owner of a repository outside of github.com may be a group with subgroups
+	owner := parts[len(parts)-2]
+	host := strings.Join(parts[:len(parts)-2], "/")
+	if !strings.EqualFold(parts[0], defaultHost) && strings.Contains(parts[0], ".") {
+		owner = strings.Join(parts[1:len(parts)-1], "/")
+		host = parts[0]
+	}
+
+	return Repository{
+		Host:  host,
+		Owner: owner,
+		Name:  parts[len(parts)-1],
+	}, issueNumber, nil
+}
+
+// this function records the URL of a repository in an annotation
+// if the URL can't be rebuilt from the structured repository
+func keepRepositoryURL(obj metav1.Object, key, repositoryURL string, repository Repository) {
+	removeAnnotation(obj, key)
+	if repositoryURL == repository.URL() {
+		return
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = repositoryURL
+	obj.SetAnnotations(annotations)
+}
+
+// this function returns the URL of a repository, the URL recorded in the annotation
+// is used if it still points to the repository. an issue number which is part
+// of the recorded URL is not repeated in the returned issue number
+func restoreRepositoryURL(annotations map[string]string, key string, repository Repository, issueNumber int) (string, int) {
+	if repositoryURL, ok := annotations[key]; ok {
+		recorded, recordedIssueNumber, err := ParseRepositoryURL(repositoryURL)
+		if err == nil && recorded == repository {
+			if recordedIssueNumber != 0 && recordedIssueNumber == issueNumber {
+				issueNumber = 0
+			}
+			return repositoryURL, issueNumber
+		}
+	}
+
+	return repository.URL(), issueNumber
+}
+
+// this function removes an annotation from an object
+func removeAnnotation(obj metav1.Object, key string) {
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[key]; !ok {
+		return
+	}
+
+	delete(annotations, key)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+}
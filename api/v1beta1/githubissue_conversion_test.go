@@ -0,0 +1,155 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// this function returns a v1alpha1 object with every field set
+func generateHubGithubIssue(repo string) *v1alpha1.GithubIssue {
+	updatedAt := metav1.NewTime(time.Date(2022, time.October, 1, 12, 0, 0, 0, time.UTC))
+
+	return &v1alpha1.GithubIssue{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "issue",
+			Namespace:   "default",
+			Annotations: map[string]string{"team": "platform"},
+		},
+		Spec: v1alpha1.GithubIssueSpec{
+			Repo:             repo,
+			Title:            "title",
+			Description:      "description",
+			TitleDriftPolicy: v1alpha1.TitleDriftPolicyAccept,
+			Labels:           []string{"bug", "help wanted"},
+			Assignees:        []string{"octocat"},
+			State:            "open",
+			SyncDirection:    v1alpha1.SyncDirectionBidirectional,
+			Locked:           func(b bool) *bool { return &b }(true),
+			LockReason:       "resolved",
+			ClosePolicy:      &v1alpha1.ClosePolicy{Lock: true, LockReason: "spam", Comment: "Closing as spam"},
+			Pinned:           func(b bool) *bool { return &b }(false),
+			IssueType:        "Bug",
+			TransferTo:       "https://github.com/testOrg/otherRepo",
+			Projects: []v1alpha1.GithubProject{
+				{
+					Owner:  "testOrg",
+					Number: 3,
+					Fields: []v1alpha1.ProjectFieldValue{{Name: "Status", Value: "Todo"}},
+				},
+			},
+			MilestoneRef:        &corev1.LocalObjectReference{Name: "v1"},
+			RecreateAsDiscussion: &v1alpha1.DiscussionRecreation{Category: "Ideas"},
+		},
+		Status: v1alpha1.GithubIssueStatus{
+			ActiveTitle:       "title",
+			ActiveDescription: "description",
+			IssueNumber:       7,
+			Locked:            true,
+			LockReason:        "resolved",
+			IssueType:         "Bug",
+			Milestone:         2,
+			ProjectItems:      []v1alpha1.ProjectItemStatus{{ProjectID: "PVT_1", ItemID: "PVTI_1"}},
+			LastAppliedHash:   "abc",
+			GithubUpdatedAt:   &updatedAt,
+			DiscussionURL:     "https://github.com/testOrg/testRepo/discussions/3",
+			Conditions: []metav1.Condition{
+				{Type: "IssueOpen", Status: metav1.ConditionTrue, Reason: "IssueInOpenState", LastTransitionTime: updatedAt},
+			},
+		},
+	}
+}
+
+func TestGithubIssueHubRoundTrip(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// every v1alpha1 object is converted to v1beta1 and back without losing information
+	for _, repo := range []string{
+		"https://github.com/testOrg/testRepo",
+		"https://github.com/testOrg/testRepo/",
+		"http://github.com/testOrg/testRepo",
+		"https://github.com/testOrg/testRepo/issues/42",
+		"https://ghe.example.com/testOrg/testRepo",
+		"https://gitlab.example.com/group/subgroup/project",
+		"",
+	} {
+		hub := generateHubGithubIssue(repo)
+
+		spoke := &GithubIssue{}
+		g.Expect(spoke.ConvertFrom(hub)).To(Succeed(), repo)
+
+		converted := &v1alpha1.GithubIssue{}
+		g.Expect(spoke.ConvertTo(converted)).To(Succeed(), repo)
+		g.Expect(converted).To(Equal(hub), repo)
+	}
+}
+
+func TestParseRepositoryURLWithSubgroups(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// the owner of a repository outside of github.com is the whole path of its group
+	repository, issueNumber, err := ParseRepositoryURL("https://gitlab.example.com/group/subgroup/project/-/issues/7")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(repository).To(Equal(Repository{Host: "gitlab.example.com", Owner: "group/subgroup", Name: "project"}))
+	g.Expect(issueNumber).To(Equal(7))
+	g.Expect(repository.URL()).To(Equal("https://gitlab.example.com/group/subgroup/project"))
+}
+
+func TestGithubIssueSpokeRoundTrip(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spoke := &GithubIssue{}
+	g.Expect(spoke.ConvertFrom(generateHubGithubIssue("https://github.com/testOrg/testRepo"))).To(Succeed())
+
+	// the v1beta1 object is converted to v1alpha1 and back without losing information
+	hub := &v1alpha1.GithubIssue{}
+	g.Expect(spoke.ConvertTo(hub)).To(Succeed())
+
+	converted := &GithubIssue{}
+	g.Expect(converted.ConvertFrom(hub)).To(Succeed())
+	g.Expect(converted).To(Equal(spoke))
+}
+
+func TestGithubIssueConvertFromRepositoryURL(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// the repository URL is split into its parts and
+	// the number of an issue URL is moved to the spec
+	spoke := &GithubIssue{}
+	g.Expect(spoke.ConvertFrom(generateHubGithubIssue("https://github.com/testOrg/testRepo/issues/42"))).To(Succeed())
+	g.Expect(spoke.Spec.Repository).To(Equal(Repository{Host: "github.com", Owner: "testOrg", Name: "testRepo"}))
+	g.Expect(spoke.Spec.IssueNumber).To(Equal(42))
+	g.Expect(spoke.Spec.TransferTo).To(Equal(&Repository{Host: "github.com", Owner: "testOrg", Name: "otherRepo"}))
+
+	// a repository which was changed in v1beta1 is not overridden by the recorded URL
+	spoke.Spec.Repository.Name = "newRepo"
+	hub := &v1alpha1.GithubIssue{}
+	g.Expect(spoke.ConvertTo(hub)).To(Succeed())
+	g.Expect(hub.Spec.Repo).To(Equal("https://github.com/testOrg/newRepo"))
+	g.Expect(hub.Spec.IssueNumber).To(Equal(42))
+	g.Expect(hub.Annotations).ToNot(HaveKey(repoAnnotation))
+
+	// a URL which is not a repository can't be converted
+	g.Expect(spoke.ConvertFrom(generateHubGithubIssue("https://github.com"))).ToNot(Succeed())
+}
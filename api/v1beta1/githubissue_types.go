@@ -0,0 +1,269 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TitleDriftPolicy describes how a title which was changed on github is handled
+// +kubebuilder:validation:Enum=Correct;Accept
+type TitleDriftPolicy string
+
+const (
+	// TitleDriftPolicyCorrect renames the issue back to the title in the spec
+	TitleDriftPolicyCorrect TitleDriftPolicy = "Correct"
+
+	// TitleDriftPolicyAccept writes the title of the issue back into the spec
+	TitleDriftPolicyAccept TitleDriftPolicy = "Accept"
+)
+
+// SyncDirection describes in which direction changes are synced between the object and github
+// +kubebuilder:validation:Enum=ToGithub;FromGithub;Bidirectional
+type SyncDirection string
+
+const (
+	// SyncDirectionToGithub applies the spec to the issue on github
+	SyncDirectionToGithub SyncDirection = "ToGithub"
+
+	// SyncDirectionFromGithub writes the issue on github back into the spec
+	SyncDirectionFromGithub SyncDirection = "FromGithub"
+
+	// SyncDirectionBidirectional syncs changes in both directions, the side
+	// which changed since the last sync wins
+	SyncDirectionBidirectional SyncDirection = "Bidirectional"
+)
+
+// LockReason is the reason given for locking the conversation of an issue
+// +kubebuilder:validation:Enum=off-topic;too heated;resolved;spam
+type LockReason string
+
+// Repository identifies a repository on a github host
+type Repository struct {
+	// Host is the host of the repository
+	// +kubebuilder:default=github.com
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// Owner is the login of the organization or user owning the repository, or
+	// the path of its group outside of github.com, e.g. group/subgroup on gitlab
+	// +kubebuilder:validation:MinLength=1
+	Owner string `json:"owner"`
+
+	// Name is the name of the repository
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// ClosePolicy defines what happens to an issue when it is closed, either
+// by setting its state to closed or by deleting the object
+type ClosePolicy struct {
+	// Lock locks the conversation of the issue once it is closed
+	// +optional
+	Lock bool `json:"lock,omitempty"`
+
+	// LockReason is the reason given for locking the conversation
+	// +optional
+	LockReason LockReason `json:"lockReason,omitempty"`
+
+	// Comment is posted on the issue when it is closed because the object was deleted
+	// +optional
+	Comment string `json:"comment,omitempty"`
+}
+
+// ProjectFieldValue is the value of a field of a project item
+type ProjectFieldValue struct {
+	// Name is the name of the field, e.g. Status or Iteration
+	Name string `json:"name"`
+
+	// Value is the value of the field. It is the name of the option for single select fields,
+	// the title of the iteration for iteration fields and a date in YYYY-MM-DD format for date fields
+	Value string `json:"value"`
+}
+
+// DiscussionRecreation requests to recreate the issue as a discussion of its repository
+type DiscussionRecreation struct {
+	// Category is the name of the discussion category the discussion is created in
+	// +kubebuilder:validation:MinLength=1
+	Category string `json:"category"`
+}
+
+// GithubProject references a GitHub project (v2) the issue is added to
+type GithubProject struct {
+	// Owner is the login of the organization or user owning the project,
+	// it defaults to the owner of the repository
+	// +optional
+	Owner string `json:"owner,omitempty"`
+
+	// Number is the number of the project
+	// +optional
+	Number int `json:"number,omitempty"`
+
+	// NodeID is the node ID of the project, it takes precedence over the number
+	// +optional
+	NodeID string `json:"nodeID,omitempty"`
+
+	// Fields are the values of the fields of the project item of the issue
+	// +optional
+	Fields []ProjectFieldValue `json:"fields,omitempty"`
+}
+
+// GithubIssueSpec defines the desired state of GithubIssue
+type GithubIssueSpec struct {
+	// Repository is the repository the issue is filed in
+	Repository Repository `json:"repository"`
+
+	// Title is the title of the issue
+	// +optional
+	Title string `json:"title,omitempty"`
+
+	// Description is the body of the issue
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// IssueNumber is the number of an existing issue in the repository
+	// that should be adopted instead of creating a new one
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	IssueNumber int `json:"issueNumber,omitempty"`
+
+	// TitleDriftPolicy determines whether a title which was changed on github
+	// is corrected back to the title in the spec or accepted into the spec
+	// +kubebuilder:default=Correct
+	// +optional
+	TitleDriftPolicy TitleDriftPolicy `json:"titleDriftPolicy,omitempty"`
+
+	// Labels are the names of the labels set on the issue, an empty
+	// list leaves the labels of the issue untouched
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Assignees are the logins of the users assigned to the issue, an
+	// empty list leaves the assignees of the issue untouched
+	// +optional
+	Assignees []string `json:"assignees,omitempty"`
+
+	// State is the state of the issue, an empty state leaves
+	// the state of the issue untouched
+	// +kubebuilder:validation:Enum=open;closed
+	// +optional
+	State string `json:"state,omitempty"`
+
+	// SyncDirection determines whether changes are applied from the spec to github,
+	// from github to the spec or in both directions
+	// +kubebuilder:default=ToGithub
+	// +optional
+	SyncDirection SyncDirection `json:"syncDirection,omitempty"`
+
+	// Locked locks or unlocks the conversation of the issue, leaving it
+	// unset leaves the lock of the issue untouched
+	// +optional
+	Locked *bool `json:"locked,omitempty"`
+
+	// LockReason is the reason given for locking the conversation
+	// +optional
+	LockReason LockReason `json:"lockReason,omitempty"`
+
+	// ClosePolicy defines what happens to the issue when it is closed
+	// +optional
+	ClosePolicy *ClosePolicy `json:"closePolicy,omitempty"`
+
+	// Pinned pins or unpins the issue in its repository, leaving it
+	// unset leaves the issue untouched
+	// +optional
+	Pinned *bool `json:"pinned,omitempty"`
+
+	// IssueType is the name of the issue type set on the issue
+	// +optional
+	IssueType string `json:"issueType,omitempty"`
+
+	// TransferTo is a repository the issue should be transferred to. Once the
+	// transfer succeeds, the new issue number is recorded in the status, the
+	// repository in the spec is updated and the field is cleared
+	// +optional
+	TransferTo *Repository `json:"transferTo,omitempty"`
+
+	// Projects are the GitHub projects (v2) the issue is added to
+	// +optional
+	Projects []GithubProject `json:"projects,omitempty"`
+
+	// MilestoneRef references a GithubMilestone in the namespace of the object,
+	// the issue is added to the milestone once the milestone was created
+	// +optional
+	MilestoneRef *corev1.LocalObjectReference `json:"milestoneRef,omitempty"`
+
+	// RecreateAsDiscussion recreates the issue as a discussion of its repository on github, as
+	// github has no api to convert an issue. A new discussion is created with the title and
+	// description of the issue, the issue is closed with a comment linking to the discussion and
+	// it is no longer synced with the object. The comments, reactions, labels, assignees and
+	// history of the issue are not carried over to the discussion, they are only kept on the issue
+	// +optional
+	RecreateAsDiscussion *DiscussionRecreation `json:"recreateAsDiscussion,omitempty"`
+}
+
+// ProjectItemStatus records the item of the issue in a GitHub project
+type ProjectItemStatus struct {
+	ProjectID string `json:"projectID"`
+	ItemID    string `json:"itemID"`
+}
+
+// GithubIssueStatus defines the observed state of GithubIssue
+type GithubIssueStatus struct {
+	ActiveTitle       string              `json:"activeTitle,omitempty"`
+	ActiveDescription string              `json:"activeDescription,omitempty"`
+	IssueNumber       int                 `json:"issueNumber,omitempty"`
+	Locked            bool                `json:"locked,omitempty"`
+	LockReason        string              `json:"lockReason,omitempty"`
+	Pinned            bool                `json:"pinned,omitempty"`
+	IssueType         string              `json:"issueType,omitempty"`
+	Milestone         int                 `json:"milestone,omitempty"`
+	ProjectItems      []ProjectItemStatus `json:"projectItems,omitempty"`
+	LastAppliedHash   string              `json:"lastAppliedHash,omitempty"`
+	GithubUpdatedAt   *metav1.Time        `json:"githubUpdatedAt,omitempty"`
+	DiscussionURL     string              `json:"discussionURL,omitempty"`
+	Conditions        []metav1.Condition  `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Owner",type=string,JSONPath=`.spec.repository.owner`
+//+kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.spec.repository.name`
+//+kubebuilder:printcolumn:name="Issue",type=integer,JSONPath=`.status.issueNumber`
+//+kubebuilder:printcolumn:name="Title",type=string,JSONPath=`.status.activeTitle`
+
+// GithubIssue is the Schema for the githubissues API
+type GithubIssue struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubIssueSpec   `json:"spec,omitempty"`
+	Status GithubIssueStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GithubIssueList contains a list of GithubIssue
+type GithubIssueList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubIssue `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubIssue{}, &GithubIssueList{})
+}
@@ -0,0 +1,276 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClosePolicy) DeepCopyInto(out *ClosePolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClosePolicy.
+func (in *ClosePolicy) DeepCopy() *ClosePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClosePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscussionRecreation) DeepCopyInto(out *DiscussionRecreation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscussionRecreation.
+func (in *DiscussionRecreation) DeepCopy() *DiscussionRecreation {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscussionRecreation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubIssue) DeepCopyInto(out *GithubIssue) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubIssue.
+func (in *GithubIssue) DeepCopy() *GithubIssue {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubIssue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GithubIssue) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubIssueList) DeepCopyInto(out *GithubIssueList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GithubIssue, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubIssueList.
+func (in *GithubIssueList) DeepCopy() *GithubIssueList {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubIssueList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GithubIssueList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubIssueSpec) DeepCopyInto(out *GithubIssueSpec) {
+	*out = *in
+	out.Repository = in.Repository
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Assignees != nil {
+		in, out := &in.Assignees, &out.Assignees
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Locked != nil {
+		in, out := &in.Locked, &out.Locked
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ClosePolicy != nil {
+		in, out := &in.ClosePolicy, &out.ClosePolicy
+		*out = new(ClosePolicy)
+		**out = **in
+	}
+	if in.Pinned != nil {
+		in, out := &in.Pinned, &out.Pinned
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TransferTo != nil {
+		in, out := &in.TransferTo, &out.TransferTo
+		*out = new(Repository)
+		**out = **in
+	}
+	if in.Projects != nil {
+		in, out := &in.Projects, &out.Projects
+		*out = make([]GithubProject, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MilestoneRef != nil {
+		in, out := &in.MilestoneRef, &out.MilestoneRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.RecreateAsDiscussion != nil {
+		in, out := &in.RecreateAsDiscussion, &out.RecreateAsDiscussion
+		*out = new(DiscussionRecreation)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubIssueSpec.
+func (in *GithubIssueSpec) DeepCopy() *GithubIssueSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubIssueSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubIssueStatus) DeepCopyInto(out *GithubIssueStatus) {
+	*out = *in
+	if in.ProjectItems != nil {
+		in, out := &in.ProjectItems, &out.ProjectItems
+		*out = make([]ProjectItemStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.GithubUpdatedAt != nil {
+		in, out := &in.GithubUpdatedAt, &out.GithubUpdatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubIssueStatus.
+func (in *GithubIssueStatus) DeepCopy() *GithubIssueStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubIssueStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubProject) DeepCopyInto(out *GithubProject) {
+	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]ProjectFieldValue, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubProject.
+func (in *GithubProject) DeepCopy() *GithubProject {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubProject)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectFieldValue) DeepCopyInto(out *ProjectFieldValue) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectFieldValue.
+func (in *ProjectFieldValue) DeepCopy() *ProjectFieldValue {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectFieldValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectItemStatus) DeepCopyInto(out *ProjectItemStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectItemStatus.
+func (in *ProjectItemStatus) DeepCopy() *ProjectItemStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectItemStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Repository) DeepCopyInto(out *Repository) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Repository.
+func (in *Repository) DeepCopy() *Repository {
+	if in == nil {
+		return nil
+	}
+	out := new(Repository)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,363 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	"github.com/mzeevi/githubissues-operator/controllers"
+)
+
+// errDiffFound makes diff exit with a non-zero code when the spec differs from the issue, as kubectl diff does
+var errDiffFound = errors.New("the spec differs from the issue")
+
+func lsCommand() *command {
+	fs := flag.NewFlagSet("ls", flag.ContinueOnError)
+	allNamespaces := fs.Bool("all-namespaces", false, "List the objects of all the namespaces.")
+	fs.BoolVar(allNamespaces, "A", false, "Shorthand for --all-namespaces.")
+
+	return &command{flags: fs, run: func(ctx context.Context, p *plugin, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("ls takes no arguments")
+		}
+
+		githubissues := trainingv1alpha1.GithubIssueList{}
+		listOpts := []client.ListOption{}
+		if !*allNamespaces {
+			listOpts = append(listOpts, client.InNamespace(p.namespace))
+		}
+		if err := p.client.List(ctx, &githubissues, listOpts...); err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		if *allNamespaces {
+			fmt.Fprint(w, "NAMESPACE\t")
+		}
+		fmt.Fprintln(w, "NAME\tREPO\tISSUE\tTITLE\tGITHUB STATE\tGITHUB TITLE\tIN SYNC")
+
+		var lookupErr error
+		for i := range githubissues.Items {
+			githubissue := &githubissues.Items[i]
+
+			issueNumber, state, title, inSync := "-", "-", "-", "-"
+			if githubissue.Status.IssueNumber != 0 {
+				issueNumber = strconv.Itoa(githubissue.Status.IssueNumber)
+			}
+
+			issue, err := p.inspector.LiveIssue(ctx, githubissue)
+			switch {
+			case err != nil:
+				state = "<error>"
+				lookupErr = fmt.Errorf("unable to fetch the issue of %s/%s: %w", githubissue.Namespace, githubissue.Name, err)
+				fmt.Fprintln(os.Stderr, "error:", lookupErr)
+			case issue == nil:
+				state = "<none>"
+			default:
+				issueNumber = strconv.Itoa(issue.Number)
+				state = issue.State
+				title = issue.Title
+				inSync = strconv.FormatBool(len(controllers.DiffIssue(githubissue, issue)) == 0)
+			}
+
+			if *allNamespaces {
+				fmt.Fprintf(w, "%s\t", githubissue.Namespace)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", githubissue.Name, githubissue.Spec.Repo, issueNumber,
+				valueOrDash(githubissue.Status.ActiveTitle), state, title, inSync)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		return lookupErr
+	}}
+}
+
+func openCommand() *command {
+	fs := flag.NewFlagSet("open", flag.ContinueOnError)
+	printOnly := fs.Bool("print", false, "Print the URL of the issue instead of opening it.")
+
+	return &command{flags: fs, run: func(ctx context.Context, p *plugin, args []string) error {
+		githubissue, err := p.getGithubIssue(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		issue, err := p.inspector.LiveIssue(ctx, githubissue)
+		if err != nil {
+			return err
+		}
+		if issue == nil || issue.URL == "" {
+			return fmt.Errorf("%s has no issue yet", githubissue.Name)
+		}
+
+		fmt.Println(issue.URL)
+		if *printOnly {
+			return nil
+		}
+
+		return openBrowser(issue.URL)
+	}}
+}
+
+func adoptCommand() *command {
+	fs := flag.NewFlagSet("adopt", flag.ContinueOnError)
+	name := fs.String("name", "", "The name of the object, derived from the repository and the issue number by default.")
+
+	return &command{flags: fs, run: func(ctx context.Context, p *plugin, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("adopt takes the URL of an issue")
+		}
+
+		githubissue, err := p.inspector.AdoptIssue(ctx, args[0], p.namespace, *name)
+		if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(githubissue)
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.Write(data)
+		return err
+	}}
+}
+
+func importCommand() *command {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	state := fs.String("state", "open", "The state of the imported issues, one of open, closed or all.")
+	labels := fs.String("labels", "", "A comma-separated list of labels which the imported issues have.")
+	milestone := fs.String("milestone", "", "The number or, on github, the title of the milestone of the imported issues.")
+	outputDir := fs.String("output-dir", "", "A directory to write a manifest per issue to, instead of printing them.")
+
+	return &command{flags: fs, run: func(ctx context.Context, p *plugin, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("import takes the URL of a repository")
+		}
+		if *state != "open" && *state != "closed" && *state != "all" {
+			return fmt.Errorf("unknown state %q, expected open, closed or all", *state)
+		}
+
+		filter := controllers.IssueFilter{State: *state}
+		if *labels != "" {
+			for _, label := range strings.Split(*labels, ",") {
+				filter.Labels = append(filter.Labels, strings.TrimSpace(label))
+			}
+		}
+		if *milestone != "" {
+			number, err := p.inspector.ResolveMilestone(ctx, args[0], *milestone)
+			if err != nil {
+				return err
+			}
+			filter.Milestone = number
+		}
+
+		githubissues, err := p.inspector.ImportIssues(ctx, args[0], filter, p.namespace)
+		if err != nil {
+			return err
+		}
+
+		for i, githubissue := range githubissues {
+			data, err := yaml.Marshal(githubissue)
+			if err != nil {
+				return err
+			}
+
+			if *outputDir != "" {
+				if err := os.WriteFile(filepath.Join(*outputDir, githubissue.Name+".yaml"), data, 0o644); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if i > 0 {
+				fmt.Println("---")
+			}
+			if _, err := os.Stdout.Write(data); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "%d issues imported\n", len(githubissues))
+		return nil
+	}}
+}
+
+func restoreCommand() *command {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	file := fs.String("file", "", "The path of a backup in the JSON format.")
+	configMap := fs.String("configmap", "", "The name of a ConfigMap holding a backup in the JSON format.")
+
+	return &command{flags: fs, run: func(ctx context.Context, p *plugin, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("restore takes the URL of the repository to restore the issues into")
+		}
+		if (*file == "") == (*configMap == "") {
+			return fmt.Errorf("exactly one of --file and --configmap has to be set")
+		}
+
+		var data []byte
+		if *file != "" {
+			var err error
+			if data, err = os.ReadFile(*file); err != nil {
+				return err
+			}
+		} else {
+			cm := &corev1.ConfigMap{}
+			if err := p.client.Get(ctx, types.NamespacedName{Namespace: p.namespace, Name: *configMap}, cm); err != nil {
+				return err
+			}
+			value, ok := cm.Data[controllers.BackupJSONKey]
+			if !ok {
+				return fmt.Errorf("configmap %s holds no %s, only backups in the JSON format can be restored", *configMap, controllers.BackupJSONKey)
+			}
+			data = []byte(value)
+		}
+
+		backup, err := controllers.ParseIssueBackup(data)
+		if err != nil {
+			return err
+		}
+
+		restored, err := p.inspector.RestoreIssues(ctx, backup, args[0])
+		for _, issue := range restored {
+			if issue.Existing {
+				fmt.Printf("%s already exists as issue %d\n", issue.Source, issue.Number)
+				continue
+			}
+			fmt.Printf("%s restored as issue %d\n", issue.Source, issue.Number)
+		}
+
+		return err
+	}}
+}
+
+func syncCommand() *command {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+
+	return &command{flags: fs, run: func(ctx context.Context, p *plugin, args []string) error {
+		githubissue, err := p.getGithubIssue(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		patch := client.MergeFrom(githubissue.DeepCopy())
+		annotations := githubissue.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[trainingv1alpha1.ReconcileAtAnnotation] = time.Now().UTC().Format(time.RFC3339Nano)
+		githubissue.SetAnnotations(annotations)
+
+		if err := p.client.Patch(ctx, githubissue, patch); err != nil {
+			return err
+		}
+
+		fmt.Printf("githubissue/%s sync requested\n", githubissue.Name)
+		return nil
+	}}
+}
+
+func diffCommand() *command {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+
+	return &command{flags: fs, run: func(ctx context.Context, p *plugin, args []string) error {
+		githubissue, err := p.getGithubIssue(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		issue, err := p.inspector.LiveIssue(ctx, githubissue)
+		if err != nil {
+			return err
+		}
+		if issue == nil {
+			fmt.Printf("%s has no issue yet, an issue titled %q will be created\n", githubissue.Name, githubissue.Spec.Title)
+			return errDiffFound
+		}
+
+		diffs := controllers.DiffIssue(githubissue, issue)
+		for _, diff := range diffs {
+			fmt.Printf("%s:\n%s%s", diff.Field, prefixLines("- ", diff.Spec), prefixLines("+ ", diff.Issue))
+		}
+		if len(diffs) != 0 {
+			return errDiffFound
+		}
+
+		return nil
+	}}
+}
+
+// this function gets the object named by the single argument of a command
+func (p *plugin) getGithubIssue(ctx context.Context, args []string) (*trainingv1alpha1.GithubIssue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected the name of a githubissue")
+	}
+
+	githubissue := &trainingv1alpha1.GithubIssue{}
+	if err := p.client.Get(ctx, types.NamespacedName{Namespace: p.namespace, Name: args[0]}, githubissue); err != nil {
+		return nil, err
+	}
+
+	return githubissue, nil
+}
+
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
+
+// this function prefixes every line of a value, so multi-line descriptions read as a diff
+func prefixLines(prefix, value string) string {
+	lines := strings.Split(value, "\n")
+	for i := range lines {
+		lines[i] = prefix + lines[i]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-githubissue is a kubectl plugin for inspecting and operating on
+// GithubIssue objects, e.g. kubectl githubissue ls -n default
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	"github.com/mzeevi/githubissues-operator/controllers"
+)
+
+const usage = `kubectl githubissue inspects and operates on GithubIssue objects.
+
+Usage:
+  kubectl githubissue ls [-n namespace | -A]     list the objects next to the state of their issues
+  kubectl githubissue open NAME [--print]        open the issue of an object in the browser
+  kubectl githubissue adopt ISSUE-URL [--name]   print an object adopting an existing issue
+  kubectl githubissue import REPO-URL            print objects adopting the issues of a repository,
+      [--state] [--labels] [--milestone]         selected by their state, labels and milestone
+      [--output-dir]
+  kubectl githubissue restore REPO-URL           recreate the issues of a backup in a repository
+      (--file PATH | --configmap NAME)
+  kubectl githubissue sync NAME                  force the reconciliation of an object
+  kubectl githubissue diff NAME                  show how the spec of an object differs from its issue
+
+Every command accepts -n/--namespace, --kubeconfig, --gitlab-url and --tracker-config.
+The github and gitlab tokens are read from GH_PERSONAL_TOKEN and GL_PERSONAL_TOKEN.
+`
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(trainingv1alpha1.AddToScheme(scheme))
+}
+
+// options holds the flags shared by all the commands
+type options struct {
+	namespace     string
+	kubeconfig    string
+	gitlabURL     string
+	trackerConfig string
+}
+
+// command is a subcommand of the plugin, it gets the positional arguments
+type command struct {
+	flags *flag.FlagSet
+	run   func(ctx context.Context, p *plugin, args []string) error
+}
+
+// plugin holds the clients used by the commands
+type plugin struct {
+	client    client.Client
+	namespace string
+	inspector *controllers.IssueInspector
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	opts := &options{}
+	commands := map[string]*command{
+		"ls":      lsCommand(),
+		"open":    openCommand(),
+		"adopt":   adoptCommand(),
+		"import":  importCommand(),
+		"restore": restoreCommand(),
+		"sync":    syncCommand(),
+		"diff":    diffCommand(),
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	opts.bindFlags(cmd.flags)
+
+	args, err := parseArgs(cmd.flags, os.Args[2:])
+	if err != nil {
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	p, err := newPlugin(ctx, opts)
+	if err == nil {
+		err = cmd.run(ctx, p, args)
+	}
+	if errors.Is(err, errDiffFound) {
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (o *options) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.namespace, "namespace", "", "The namespace of the objects, the namespace of the current context by default.")
+	fs.StringVar(&o.namespace, "n", "", "Shorthand for --namespace.")
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "The path of the kubeconfig file.")
+	fs.StringVar(&o.gitlabURL, "gitlab-url", "https://gitlab.com",
+		"The URL of the gitlab instance whose repositories are managed with the GL_PERSONAL_TOKEN.")
+	fs.StringVar(&o.trackerConfig, "tracker-config", "",
+		"The path of the tracker config given to the operator, for repositories on other forges.")
+}
+
+// this function parses the flags of a command, which may
+// also follow its positional arguments as with kubectl
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// this function builds the kubernetes client and the issue trackers the same way the operator does
+func newPlugin(ctx context.Context, opts *options) (*plugin, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = opts.kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+
+	namespace := opts.namespace
+	if namespace == "" {
+		var err error
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			return nil, err
+		}
+	}
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cl, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+
+	gitlabHost, err := url.Parse(opts.gitlabURL)
+	if err != nil || gitlabHost.Host == "" {
+		return nil, fmt.Errorf("expected an absolute gitlab URL, got %q", opts.gitlabURL)
+	}
+	trackers := map[string]controllers.IssueTracker{
+		strings.ToLower(gitlabHost.Host): controllers.NewGitlabIssueTracker(opts.gitlabURL, os.Getenv("GL_PERSONAL_TOKEN")),
+	}
+	if opts.trackerConfig != "" {
+		config, err := controllers.LoadTrackerConfig(opts.trackerConfig)
+		if err != nil {
+			return nil, err
+		}
+		configTrackers, err := config.NewTrackers(ctx, cl, os.Getenv)
+		if err != nil {
+			return nil, err
+		}
+		for host, tracker := range configTrackers {
+			trackers[host] = tracker
+		}
+	}
+
+	return &plugin{
+		client:    cl,
+		namespace: namespace,
+		inspector: &controllers.IssueInspector{
+			GithubClient: controllers.GetGithubClient(ctx),
+			Trackers:     trackers,
+		},
+	}, nil
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/shurcooL/githubv4"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// ClusterGithubIssueReconciler reconciles a ClusterGithubIssue object
+type ClusterGithubIssueReconciler struct {
+	client.Client
+	Scheme         *runtime.Scheme
+	GithubClient   *github.Client
+	GithubV4Client *githubv4.Client
+
+	// APIReader reads the credentials secret without caching secrets
+	// of the whole cluster, the client is used if it is not set
+	APIReader client.Reader
+
+	// CredentialsSecret is the secret holding the personal access token used for
+	// cluster-scoped objects, the github clients are used if it is not set
+	CredentialsSecret types.NamespacedName
+
+	// GithubTransport sends the requests of the github clients built from the
+	// credentials secret, such as a CassetteRecorder, the default transport is used if it is not set
+	GithubTransport http.RoundTripper
+
+	// Scope restricts the objects handled by the reconciler
+	Scope Scope
+
+	// Throttle limits the issues created by the reconciler, issues are created without limits if it is nil
+	Throttle *Throttle
+
+	// Trackers are the issue trackers of repository hosts other than github
+	Trackers map[string]IssueTracker
+
+	// DryRun makes the reconciler only plan the changes to issues, without changing anything on github
+	DryRun bool
+
+	// Recorder emits the events of objects, no events are emitted if it is nil
+	Recorder record.EventRecorder
+}
+
+const (
+	// credentialsSecretKey is the key of the personal access token in the credentials secret
+	credentialsSecretKey string = "GH_PERSONAL_TOKEN"
+)
+
+//+kubebuilder:rbac:groups=training.redhat.com,resources=clustergithubissues,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=training.redhat.com,resources=clustergithubissues/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=training.redhat.com,resources=clustergithubissues/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile syncs a ClusterGithubIssue object with its issue, the object
+// is handled by the same logic as GithubIssue objects.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *ClusterGithubIssueReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Processing ClusterGithubIssueReconciler")
+
+	ghClient, ghV4Client, err := r.getGithubClients(ctx)
+	if err != nil {
+		log.Error(err, "unable to create github clients from credentials secret", "secret", r.CredentialsSecret)
+		return ctrl.Result{}, err
+	}
+
+	githubIssueReconciler := &GithubIssueReconciler{
+		Client:         &clusterGithubIssueClient{Client: r.Client},
+		Scheme:         r.Scheme,
+		GithubClient:   ghClient,
+		GithubV4Client: ghV4Client,
+		Throttle:       r.Throttle,
+		Trackers:       r.Trackers,
+		DryRun:         r.DryRun,
+	}
+	if r.Recorder != nil {
+		githubIssueReconciler.Recorder = &clusterGithubIssueRecorder{EventRecorder: r.Recorder}
+	}
+
+	return githubIssueReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: req.Name}})
+}
+
+// this function returns the github clients used for cluster-scoped objects, they
+// authenticate with the token in the credentials secret if one is configured
+func (r *ClusterGithubIssueReconciler) getGithubClients(ctx context.Context) (*github.Client, *githubv4.Client, error) {
+	if r.CredentialsSecret.Name == "" {
+		return r.GithubClient, r.GithubV4Client, nil
+	}
+
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	var secret corev1.Secret
+	if err := reader.Get(ctx, r.CredentialsSecret, &secret); err != nil {
+		return nil, nil, err
+	}
+
+	token, ok := secret.Data[credentialsSecretKey]
+	if !ok {
+		err := fmt.Errorf("secret %s has no %s key", r.CredentialsSecret, credentialsSecretKey)
+		return nil, nil, err
+	}
+
+	// the clients send their requests through the same transport as
+	// the clients of the other reconcilers
+	if r.GithubTransport != nil {
+		ctx = WithGithubTransport(ctx, r.GithubTransport)
+	}
+
+	return NewGithubClient(ctx, string(token)), NewGithubV4Client(ctx, string(token)), nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterGithubIssueReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&trainingv1alpha1.ClusterGithubIssue{}, builder.WithPredicates(r.Scope.Predicate(), reconcileTriggerPredicate())).
+		Complete(r)
+}
+
+// clusterGithubIssueClient lets the GithubIssueReconciler manage ClusterGithubIssue objects,
+// the GithubIssue objects it reads and writes are translated to ClusterGithubIssue objects
+type clusterGithubIssueClient struct {
+	client.Client
+}
+
+// Get fetches the ClusterGithubIssue with the name of the key into a GithubIssue
+func (c *clusterGithubIssueClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	githubissue, ok := obj.(*trainingv1alpha1.GithubIssue)
+	if !ok {
+		return c.Client.Get(ctx, key, obj)
+	}
+
+	var clusterGithubIssue trainingv1alpha1.ClusterGithubIssue
+	if err := c.Client.Get(ctx, client.ObjectKey{Name: key.Name}, &clusterGithubIssue); err != nil {
+		return err
+	}
+
+	clusterToGithubIssue(&clusterGithubIssue, githubissue)
+	return nil
+}
+
+// Update updates the ClusterGithubIssue a GithubIssue was read from
+func (c *clusterGithubIssueClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	githubissue, ok := obj.(*trainingv1alpha1.GithubIssue)
+	if !ok {
+		return c.Client.Update(ctx, obj, opts...)
+	}
+
+	clusterGithubIssue := githubToClusterIssue(githubissue)
+	if err := c.Client.Update(ctx, clusterGithubIssue, opts...); err != nil {
+		return err
+	}
+
+	clusterToGithubIssue(clusterGithubIssue, githubissue)
+	return nil
+}
+
+// Status returns a writer which updates the status of ClusterGithubIssue objects
+func (c *clusterGithubIssueClient) Status() client.StatusWriter {
+	return &clusterGithubIssueStatusWriter{StatusWriter: c.Client.Status()}
+}
+
+// clusterGithubIssueStatusWriter translates the status updates
+// of GithubIssue objects to ClusterGithubIssue objects
+type clusterGithubIssueStatusWriter struct {
+	client.StatusWriter
+}
+
+// Update updates the status of the ClusterGithubIssue a GithubIssue was read from
+func (w *clusterGithubIssueStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	githubissue, ok := obj.(*trainingv1alpha1.GithubIssue)
+	if !ok {
+		return w.StatusWriter.Update(ctx, obj, opts...)
+	}
+
+	clusterGithubIssue := githubToClusterIssue(githubissue)
+	if err := w.StatusWriter.Update(ctx, clusterGithubIssue, opts...); err != nil {
+		return err
+	}
+
+	clusterToGithubIssue(clusterGithubIssue, githubissue)
+	return nil
+}
+
+// clusterGithubIssueRecorder emits the events of the GithubIssue objects
+// the reconciler reads on the ClusterGithubIssue they were read from
+type clusterGithubIssueRecorder struct {
+	record.EventRecorder
+}
+
+// Event emits an event on the ClusterGithubIssue a GithubIssue was read from
+func (r *clusterGithubIssueRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if githubissue, ok := object.(*trainingv1alpha1.GithubIssue); ok {
+		object = githubToClusterIssue(githubissue)
+	}
+	r.EventRecorder.Event(object, eventtype, reason, message)
+}
+
+// this function copies a ClusterGithubIssue into a GithubIssue
+func clusterToGithubIssue(clusterGithubIssue *trainingv1alpha1.ClusterGithubIssue, githubissue *trainingv1alpha1.GithubIssue) {
+	clusterGithubIssue.ObjectMeta.DeepCopyInto(&githubissue.ObjectMeta)
+	clusterGithubIssue.Spec.DeepCopyInto(&githubissue.Spec)
+	clusterGithubIssue.Status.DeepCopyInto(&githubissue.Status)
+}
+
+// this function returns a ClusterGithubIssue holding the contents of a GithubIssue
+func githubToClusterIssue(githubissue *trainingv1alpha1.GithubIssue) *trainingv1alpha1.ClusterGithubIssue {
+	clusterGithubIssue := &trainingv1alpha1.ClusterGithubIssue{}
+	githubissue.ObjectMeta.DeepCopyInto(&clusterGithubIssue.ObjectMeta)
+	githubissue.Spec.DeepCopyInto(&clusterGithubIssue.Spec)
+	githubissue.Status.DeepCopyInto(&clusterGithubIssue.Status)
+	return clusterGithubIssue
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	ghmock "github.com/migueleliasweb/go-github-mock/src/mock"
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// this function creates a cluster-scoped object with the same spec as a GithubIssue object
+func generateClusterGithubIssueObject() *trainingv1alpha1.ClusterGithubIssue {
+	githubIssue := GenerateGithubIssueObject()
+
+	return &trainingv1alpha1.ClusterGithubIssue{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: githubIssue.Name,
+		},
+		Spec: githubIssue.Spec,
+	}
+}
+
+func TestCreateClusterGithubIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	clusterGithubIssue := generateClusterGithubIssueObject()
+
+	obj := []client.Object{clusterGithubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// record the body of the issue which is created
+	var createdBody string
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepo,
+			[]github.Issue{},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PostReposIssuesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				issueRequest := github.IssueRequest{}
+				g.Expect(json.NewDecoder(r.Body).Decode(&issueRequest)).To(Succeed())
+				createdBody = issueRequest.GetBody()
+				w.WriteHeader(http.StatusCreated)
+				w.Write(ghmock.MustMarshal(github.Issue{
+					Number: github.Int(5),
+					Title:  issueRequest.Title,
+					Body:   issueRequest.Body,
+					State:  github.String("open"),
+				}))
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	r := &ClusterGithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name: clusterGithubIssue.ObjectMeta.Name,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the issue is claimed by the name of the object
+	g.Expect(getIssueMarkerOwner(createdBody)).To(Equal(clusterGithubIssue.Name))
+
+	clusterGithubIssueReconciled := trainingv1alpha1.ClusterGithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &clusterGithubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(controllerutil.ContainsFinalizer(&clusterGithubIssueReconciled, ghIssueFinalizer)).To(BeTrue())
+	g.Expect(clusterGithubIssueReconciled.Status.IssueNumber).To(Equal(5))
+	g.Expect(apimeta.IsStatusConditionTrue(clusterGithubIssueReconciled.Status.Conditions, issueOpenConditionType)).To(BeTrue())
+}
+
+func TestCloseClusterGithubIssueOnDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	clusterGithubIssue := generateClusterGithubIssueObject()
+	clusterGithubIssue.Status.IssueNumber = 5
+
+	obj := []client.Object{clusterGithubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	issue := github.Issue{
+		Number: github.Int(5),
+		Title:  github.String(clusterGithubIssue.Spec.Title),
+		Body:   github.String(clusterGithubIssue.Spec.Description),
+		State:  github.String("open"),
+	}
+
+	// record the state the issue is changed to
+	var state string
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			issue,
+			issue,
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PatchReposIssuesByOwnerByRepoByIssueNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				issueRequest := github.IssueRequest{}
+				g.Expect(json.NewDecoder(r.Body).Decode(&issueRequest)).To(Succeed())
+				state = issueRequest.GetState()
+				w.Write(ghmock.MustMarshal(issue))
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	r := &ClusterGithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name: clusterGithubIssue.ObjectMeta.Name,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// delete the object and call reconcile again
+	clusterGithubIssueReconciled := trainingv1alpha1.ClusterGithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &clusterGithubIssueReconciled)).To(Succeed())
+	g.Expect(cl.Delete(ctx, &clusterGithubIssueReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(state).To(Equal("closed"))
+
+	err = cl.Get(ctx, req.NamespacedName, &clusterGithubIssueReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestClusterGithubIssueCredentialsSecret(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "github-token",
+			Namespace: "githubissues-operator-system",
+		},
+		Data: map[string][]byte{
+			credentialsSecretKey: []byte(GenerateRandomString()),
+		},
+	}
+
+	obj := []client.Object{secret}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// without a credentials secret the clients of the reconciler are used
+	r := &ClusterGithubIssueReconciler{Client: cl, Scheme: s, GithubClient: github.NewClient(nil)}
+	ghClient, _, err := r.getGithubClients(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ghClient).To(BeIdenticalTo(r.GithubClient))
+
+	// with a credentials secret new clients are created from its token
+	r.CredentialsSecret = types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}
+	ghClient, ghV4Client, err := r.getGithubClients(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ghClient).ToNot(BeIdenticalTo(r.GithubClient))
+	g.Expect(ghV4Client).ToNot(BeNil())
+
+	// the clients created from the secret send their requests through the github transport
+	r.GithubTransport = &CassetteReplayer{}
+	ghClient, _, err = r.getGithubClients(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, _, err = ghClient.Users.Get(ctx, "")
+	g.Expect(err).To(MatchError(ContainSubstring("no recorded interaction left for GET /user")))
+
+	// a secret without a token is an error
+	delete(secret.Data, credentialsSecretKey)
+	g.Expect(cl.Update(ctx, secret)).To(Succeed())
+	_, _, err = r.getGithubClients(ctx)
+	g.Expect(err).To(HaveOccurred())
+}
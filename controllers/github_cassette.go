@@ -0,0 +1,354 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	CassetteModeRecord = "record"
+	CassetteModeReplay = "replay"
+
+	redactedValue = "REDACTED"
+	redactedEmail = "redacted@example.com"
+
+	// the number of interactions a CassetteRecorder records if MaxInteractions is not set
+	defaultMaxCassetteInteractions = 10000
+
+	// the end of a cassette file, it is overwritten by the next recorded interaction
+	cassetteEnd = "\n]}\n"
+)
+
+var (
+	// headers which are never written to a cassette as they are
+	sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Private-Token", "X-Github-Sso"}
+
+	githubTokenPattern = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})\b`)
+	emailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+)
+
+// Cassette holds the github api interactions recorded by a CassetteRecorder
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and the response it got
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// CassetteRecorder is a http.RoundTripper which sends requests through Transport
+// and appends every interaction, redacted, to the cassette file at Path
+type CassetteRecorder struct {
+	Path      string
+	Transport http.RoundTripper
+	// Secrets are values, such as the personal access token, which are redacted
+	// wherever they appear in addition to anything that looks like a token or an email
+	Secrets []string
+	// MaxInteractions is the number of interactions after which the recorder stops
+	// recording, defaultMaxCassetteInteractions are recorded if it is 0
+	MaxInteractions int
+
+	mu       sync.Mutex
+	file     *os.File
+	end      int64
+	recorded int
+}
+
+// CassetteReplayer is a http.RoundTripper which answers requests from a cassette
+// instead of sending them, so a recorded session can be replayed in tests
+type CassetteReplayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// this function returns a context under which GetGithubClient and NewGithubClient
+// build clients that send their requests through transport
+func WithGithubTransport(ctx context.Context, transport http.RoundTripper) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
+}
+
+// this function returns the round tripper for the given cassette mode
+func NewCassetteTransport(mode, path string, secrets ...string) (http.RoundTripper, error) {
+	switch mode {
+	case CassetteModeRecord:
+		return &CassetteRecorder{Path: path, Transport: http.DefaultTransport, Secrets: secrets}, nil
+	case CassetteModeReplay:
+		return LoadCassette(path)
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q, expected %q or %q", mode, CassetteModeRecord, CassetteModeReplay)
+	}
+}
+
+func (c *CassetteRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = body
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    c.redact(req.URL.String()),
+			Header: c.redactHeader(req.Header),
+			Body:   c.redact(string(reqBody)),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     c.redactHeader(resp.Header),
+			Body:       c.redact(string(respBody)),
+		},
+	}
+
+	// a cassette which can't be written doesn't fail the request, the
+	// operator keeps working and only the recording is incomplete
+	if err := c.record(req.Context(), interaction); err != nil {
+		log.FromContext(req.Context()).Error(err, "unable to record github api interaction", "path", c.Path)
+	}
+
+	return resp, nil
+}
+
+// this function appends an interaction to the cassette file. only the new interaction
+// is written and the file is a complete cassette after every interaction, so nothing
+// is lost when the operator is killed
+func (c *CassetteRecorder) record(ctx context.Context, interaction Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	maxInteractions := c.MaxInteractions
+	if maxInteractions == 0 {
+		maxInteractions = defaultMaxCassetteInteractions
+	}
+	if c.recorded >= maxInteractions {
+		if c.recorded == maxInteractions {
+			log.FromContext(ctx).Info("Cassette is full, not recording further github api interactions", "path", c.Path, "interactions", maxInteractions)
+			c.recorded++
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(interaction, "    ", "  ")
+	if err != nil {
+		return err
+	}
+
+	if c.file == nil {
+		file, err := os.OpenFile(c.Path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		start := `{"interactions": [`
+		if _, err := file.WriteString(start); err != nil {
+			file.Close()
+			return err
+		}
+		c.file = file
+		c.end = int64(len(start))
+	}
+
+	separator := "\n    "
+	if c.recorded > 0 {
+		separator = ",\n    "
+	}
+	entry := separator + string(data)
+	if _, err := c.file.WriteAt([]byte(entry+cassetteEnd), c.end); err != nil {
+		return err
+	}
+	if err := c.file.Truncate(c.end + int64(len(entry)+len(cassetteEnd))); err != nil {
+		return err
+	}
+	c.end += int64(len(entry))
+	c.recorded++
+
+	return nil
+}
+
+// this function closes the cassette file, later interactions start a new cassette
+func (c *CassetteRecorder) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	c.recorded = 0
+	return err
+}
+
+// this function removes secrets, tokens and emails from a recorded value
+func (c *CassetteRecorder) redact(value string) string {
+	for _, secret := range c.Secrets {
+		if secret != "" {
+			value = strings.ReplaceAll(value, secret, redactedValue)
+		}
+	}
+	value = githubTokenPattern.ReplaceAllString(value, redactedValue)
+	return emailPattern.ReplaceAllString(value, redactedEmail)
+}
+
+func (c *CassetteRecorder) redactHeader(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+
+	redacted := http.Header{}
+	for name, values := range header {
+		for _, value := range values {
+			redacted.Add(name, c.redact(value))
+		}
+	}
+	for _, name := range sensitiveHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, redactedValue)
+		}
+	}
+
+	return redacted
+}
+
+// this function reads a cassette written by a CassetteRecorder
+func LoadCassette(path string) (*CassetteReplayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette := Cassette{}
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+
+	return &CassetteReplayer{
+		interactions: cassette.Interactions,
+		used:         make([]bool, len(cassette.Interactions)),
+	}, nil
+}
+
+// RoundTrip answers a request with the first unused interaction with the same
+// method, path and query. the host is ignored, so a cassette recorded against
+// github.com replays against any base url
+func (c *CassetteReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, interaction := range c.interactions {
+		if c.used[i] || interaction.Request.Method != req.Method || !sameRequestURI(interaction.Request.URL, req.URL.RequestURI()) {
+			continue
+		}
+		c.used[i] = true
+
+		header := interaction.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded interaction left for %s %s", req.Method, req.URL.RequestURI())
+}
+
+// this function returns the number of recorded interactions which were not replayed
+func (c *CassetteReplayer) Unused() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	unused := 0
+	for _, used := range c.used {
+		if !used {
+			unused++
+		}
+	}
+
+	return unused
+}
+
+func sameRequestURI(recorded, requestURI string) bool {
+	u, err := url.Parse(recorded)
+	if err != nil {
+		return false
+	}
+	return u.RequestURI() == requestURI
+}
@@ -19,19 +19,24 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/google/go-github/v45/github"
+	"github.com/shurcooL/githubv4"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
 )
@@ -39,8 +44,26 @@ import (
 // GithubIssueReconciler reconciles a GithubIssue object
 type GithubIssueReconciler struct {
 	client.Client
-	Scheme       *runtime.Scheme
-	GithubClient *github.Client
+	Scheme         *runtime.Scheme
+	GithubClient   *github.Client
+	GithubV4Client *githubv4.Client
+
+	// Scope restricts the objects handled by the reconciler
+	Scope Scope
+
+	// Throttle limits the issues created by the reconciler, issues are created without limits if it is nil
+	Throttle *Throttle
+
+	// Trackers are the issue trackers of repository hosts other than github, the
+	// issues of any other host are managed on github with the github client
+	Trackers map[string]IssueTracker
+
+	// DryRun makes the reconciler only plan the changes to issues, without changing
+	// anything on github, objects can also request it with the dry-run annotation
+	DryRun bool
+
+	// Recorder emits the events of objects, no events are emitted if it is nil
+	Recorder record.EventRecorder
 }
 
 const (
@@ -51,11 +74,46 @@ const (
 
 	issueHasPRConditionType   string = "IssueHasPR"
 	issueHasPRConditionReason string = "PullRequestExists"
+
+	issueAdoptedConditionType    string = "IssueAdopted"
+	issueAdoptedConditionReason  string = "IssueAdopted"
+	issueClaimedConditionReason  string = "IssueClaimedByAnotherResource"
+	issueNotFoundConditionReason string = "IssueNotFound"
+
+	issueTransferredConditionType   string = "IssueTransferred"
+	issueTransferredConditionReason string = "IssueTransferred"
+
+	issueRecreatedConditionType   string = "RecreatedAsDiscussion"
+	issueRecreatedConditionReason string = "DiscussionCreated"
+
+	policyViolationConditionType   string = "PolicyViolation"
+	policyViolatedConditionReason  string = "RepoPolicyViolated"
+	policySatisfiedConditionReason string = "RepoPolicySatisfied"
+
+	throttledConditionType      string = "Throttled"
+	throttledConditionReason    string = "LimitReached"
+	notThrottledConditionReason string = "WithinLimits"
+
+	conflictConditionType     string = "Conflict"
+	conflictConditionReason   string = "SpecAndIssueChanged"
+	noConflictConditionReason string = "InSync"
+
+	syncFailedConditionType      string = "SyncFailed"
+	syncFailedConditionReason    string = "ReconcileError"
+	syncSucceededConditionReason string = "Reconciled"
+
+	// issueMarkerFormat is the hidden comment stamped into the body of managed
+	// issues, it records the namespace and name of the object owning the issue
+	issueMarkerFormat string = "<!-- githubissues-operator: %s -->"
 )
 
+var issueMarkerRegexp = regexp.MustCompile(`\n*<!-- githubissues-operator: (\S+) -->\s*$`)
+
 //+kubebuilder:rbac:groups=training.redhat.com,resources=githubissues,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=training.redhat.com,resources=githubissues/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=training.redhat.com,resources=githubissues/finalizers,verbs=update
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubmilestones,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -67,6 +125,16 @@ const (
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
 func (r *GithubIssueReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.reconcileGithubIssue(ctx, req)
+	if err != nil && !errors.IsConflict(err) {
+		r.recordSyncFailure(ctx, req, err)
+	}
+	return result, err
+}
+
+// this function syncs an object with its issue, the errors it
+// returns are recorded on the object by Reconcile
+func (r *GithubIssueReconciler) reconcileGithubIssue(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	log.Info("Processing GithubIssueReconciler")
 
@@ -84,17 +152,51 @@ func (r *GithubIssueReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// create github client and use personal access token to authenticate
-	ghClient := r.GithubClient
-	if ghClient == nil {
-		err := fmt.Errorf("github client is not available")
+	// select the issue tracker which holds the issues of the repository of the object
+	tracker, err := r.getIssueTracker(&githubissue)
+	if err != nil {
+		log.Error(err, "unable to select issue tracker of githubissue", "repo", githubissue.Spec.Repo)
 		return ctrl.Result{}, err
 	}
+	onGithub := isGithubTracker(tracker)
+
+	// in dry-run mode the changes to the issue are recorded by the planning tracker
+	// instead of being made, the issue is still read from the tracker. the changes
+	// to the issue of a paused object are dropped the same way
+	var plan *planningTracker
+	paused := isPaused(&githubissue)
+	if r.isDryRun(&githubissue) || paused {
+		plan = &planningTracker{IssueTracker: tracker}
+		tracker = plan
+	}
 
 	// examine DeletionTimestamp to determine if object is under deletion
 	if !githubissue.ObjectMeta.DeletionTimestamp.IsZero() {
+		// an object which violates the repository policies of its namespace is let go
+		// without touching its issue, since the repository may not be managed from it
+		allowed, err := r.enforceRepoPolicies(ctx, &githubissue)
+		if err != nil {
+			log.Error(err, "unable to check repository policies of githubissue")
+			return ctrl.Result{}, err
+		}
+		if !allowed {
+			log.Info("GithubIssue violates repository policies, removing its finalizer without closing its issue")
+			controllerutil.RemoveFinalizer(&githubissue, ghIssueFinalizer)
+			if err := r.Update(ctx, &githubissue); err != nil {
+				log.Error(err, "failed to update githubissue")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+
+		// the issue of an object which is deleted in dry-run mode or while it is paused is
+		// closed once the object is reconciled without them, until then the finalizer is kept
+		if plan != nil {
+			return r.holdObjectDeletion(ctx, &githubissue, plan, paused)
+		}
+
 		// handle finalizer deletion on object
-		if err := r.deleteFinalizer(ctx, &githubissue, ghClient); err != nil {
+		if err := r.deleteFinalizer(ctx, &githubissue, tracker); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{}, nil
@@ -102,44 +204,227 @@ func (r *GithubIssueReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// the object is not being deleted, so if it does not have a finalizer,
 	// then lets add the finalizer and update the object
-	if err := r.addFinalizer(ctx, &githubissue, ghClient); err != nil {
+	if err := r.addFinalizer(ctx, &githubissue); err != nil {
+		return ctrl.Result{}, nil
+	}
+
+	// a failure of an earlier reconciliation is cleared, it is recorded
+	// again by Reconcile if this reconciliation fails as well
+	r.clearSyncFailedCondition(&githubissue)
+
+	// hold back objects which violate the repository policies of their namespace, objects
+	// created before a policy or while the admission webhook wasn't served are caught here
+	allowed, err := r.enforceRepoPolicies(ctx, &githubissue)
+	if err != nil {
+		log.Error(err, "unable to check repository policies of githubissue")
+		return ctrl.Result{}, err
+	}
+	if !allowed {
+		log.Info("GithubIssue violates repository policies, not syncing it with github")
+		if err := r.Status().Update(ctx, &githubissue); err != nil {
+			log.Error(err, "unable to update githubissue status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// complete a transfer of the issue which was recorded in the status, but not in the spec
+	if isIssueTransferPending(&githubissue) {
+		if err := r.completeIssueTransfer(ctx, &githubissue); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// an issue which was recreated as a discussion is closed and no longer synced
+	if apimeta.IsStatusConditionTrue(githubissue.Status.Conditions, issueRecreatedConditionType) {
+		log.Info("Issue was recreated as a discussion, not syncing it", "discussion", githubissue.Status.DiscussionURL)
 		return ctrl.Result{}, nil
 	}
 
 	// pull information from request
-	owner, repo := r.extractOwnerRepoInfo(&githubissue)
+	owner, repo, err := r.extractOwnerRepoInfo(&githubissue)
+	if err != nil {
+		log.Error(err, "unable to parse repository of githubissue", "repo", githubissue.Spec.Repo)
+		return ctrl.Result{}, err
+	}
 	title := githubissue.Spec.Title
 	description := githubissue.Spec.Description
+	labels := githubissue.Spec.Labels
+	assignees := githubissue.Spec.Assignees
 
-	// list all issues for the authenticated user
-	issues, err := r.getIssuesInRepo(ctx, ghClient, owner, repo)
+	// look up the issue that is linked to the object, either by its number
+	// or by the title of the issue in the request
+	adopting := r.getRequestedIssueNumber(&githubissue) != 0
+	issue, err := r.findIssue(ctx, tracker, &githubissue, owner, repo)
 	if err != nil {
+		// an issue requested for adoption which doesn't exist is reported like a claimed
+		// issue and not retried until the spec changes, retrying wouldn't make it exist
+		if adopting && isIssueNotFound(err) {
+			log.Info("Issue requested for adoption does not exist", "issue", r.getRequestedIssueNumber(&githubissue))
+			r.setIssueAdoptedCondition(&githubissue, metav1.ConditionFalse, issueNotFoundConditionReason,
+				fmt.Sprintf("Issue #%d does not exist in %s/%s", r.getRequestedIssueNumber(&githubissue), owner, repo))
+			if err := r.Status().Update(ctx, &githubissue); err != nil {
+				log.Error(err, "unable to update githubissue status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
 		log.Error(err, "unable to fetch issues from github repository", "owner", owner, "repo", repo)
 		return ctrl.Result{}, err
 	}
 
-	// check if the title of the issue in the request exists in the list of issues in the repo
-	// and act accordingly to either update the issue or create it
-	issue := r.getExistingIssue(issues, title)
+	// refuse to manage an issue which is already claimed by another object
+	if issue != nil && r.isIssueClaimedByAnother(issue, &githubissue) {
+		claimedBy := getIssueMarkerOwner(issue.Body)
+		log.Info("Issue is already claimed by another object", "issue", issue.Number, "claimedBy", claimedBy)
+		r.setIssueAdoptedCondition(&githubissue, metav1.ConditionFalse, issueClaimedConditionReason,
+			fmt.Sprintf("The issue is already claimed by %s", claimedBy))
+		if err := r.Status().Update(ctx, &githubissue); err != nil {
+			log.Error(err, "unable to update githubissue status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
 
+	// the status of a paused object keeps reporting its issue, which is left untouched
+	if paused {
+		return r.updatePausedStatus(ctx, issue, &githubissue)
+	}
+	apimeta.RemoveStatusCondition(&githubissue.Status.Conditions, pausedConditionType)
+
+	// create the issue if it does not exist in the repo, unless a limit on the
+	// issues created by the operator was reached
 	if issue == nil {
-		createdIssue, err := r.createNewIssue(ctx, ghClient, title, description, owner, repo)
+		limit, wait, reservation, err := r.Throttle.check(ctx, r.Client, &githubissue, owner, repo)
+		if err != nil {
+			log.Error(err, "unable to check issue creation limits", "owner", owner, "repo", repo)
+			return ctrl.Result{}, err
+		}
+		if limit != "" {
+			log.Info("Issue creation is throttled", "limit", limit, "retryAfter", wait)
+			throttledReconciles.WithLabelValues(limit, githubissue.Namespace).Inc()
+			r.setThrottledCondition(&githubissue, metav1.ConditionTrue, throttledConditionReason, throttledMessage(limit, wait))
+			if err := r.Status().Update(ctx, &githubissue); err != nil {
+				log.Error(err, "unable to update githubissue status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+
+		createdIssue, err := r.createNewIssue(ctx, tracker, title, withIssueMarker(description, &githubissue), labels, assignees, githubissue.Spec.IssueType, owner, repo)
 		if err != nil {
+			r.Throttle.release(reservation)
 			log.Error(err, "failed to create new issue on github repository", "owner", owner, "repo", repo)
 			return ctrl.Result{}, err
 		}
+		// a planned creation doesn't count against the limits
+		if plan == nil {
+			r.Throttle.recordCreation(githubissue.Namespace)
+		} else {
+			r.Throttle.release(reservation)
+		}
 		issue = createdIssue
+
+		if apimeta.FindStatusCondition(githubissue.Status.Conditions, throttledConditionType) != nil {
+			r.setThrottledCondition(&githubissue, metav1.ConditionFalse, notThrottledConditionReason, "The issue was created")
+		}
 	}
 
-	body := issue.GetBody()
-	if body != description {
-		if err := r.updateIssueDescription(ctx, ghClient, issue, description, owner, repo); err != nil {
-			log.Error(err, "failed to update issue on github repository", "owner", owner, "repo", repo, "issue", issue)
+	// transfer the issue to another repository, the object is synced
+	// again with the repository the issue was transferred to
+	if githubissue.Spec.TransferTo != "" && !onGithub {
+		log.Info("Transferring issues is only supported on github, ignoring transferTo", "repo", githubissue.Spec.Repo)
+	}
+	if githubissue.Spec.TransferTo != "" && onGithub && plan != nil {
+		plan.record("transfer %s to %s", plannedIssueRef(issue.Number), githubissue.Spec.TransferTo)
+		return r.updatePlannedStatus(ctx, &githubissue, plan)
+	}
+	if githubissue.Spec.TransferTo != "" && onGithub {
+		if err := r.handleIssueTransfer(ctx, issue, &githubissue, owner, repo); err != nil {
+			log.Error(err, "failed to transfer issue", "owner", owner, "repo", repo, "issue", issue.Number)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// decide in which direction the object and the issue are synced, in bidirectional
+	// mode the side which changed since the last sync wins
+	push, pull := r.resolveSyncDirection(issue, &githubissue)
+
+	if pull {
+		if err := r.pullIssueIntoSpec(ctx, issue, &githubissue); err != nil {
+			log.Error(err, "failed to update githubissue from issue", "issue", issue.Number)
 			return ctrl.Result{}, err
 		}
-		body = description
 	}
-	githubissue.Status.ActiveDescription = body
+
+	if push {
+		if err := r.pushSpecToIssue(ctx, tracker, issue, &githubissue, owner, repo, adopting); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// lock or unlock the conversation of the issue
+	if push {
+		if err := r.syncIssueLock(ctx, tracker, issue, &githubissue, owner, repo); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if githubissue.Spec.RecreateAsDiscussion != nil && !onGithub {
+		log.Info("Discussions are only supported on github, ignoring recreateAsDiscussion", "repo", githubissue.Spec.Repo)
+	}
+	if githubissue.Spec.RecreateAsDiscussion != nil && onGithub && plan != nil {
+		plan.record("recreate %s as a discussion in the %s category", plannedIssueRef(issue.Number), githubissue.Spec.RecreateAsDiscussion.Category)
+	}
+
+	// in dry-run mode the issue was not changed, so the status is left as it was and
+	// only lists the planned changes. pinning the issue, its type and its project items
+	// are changed through the graphql api of github and are not planned
+	if plan != nil {
+		return r.updatePlannedStatus(ctx, &githubissue, plan)
+	}
+	apimeta.RemoveStatusCondition(&githubissue.Status.Conditions, plannedConditionType)
+
+	if push || pull {
+		r.recordSyncState(issue, &githubissue)
+	}
+	githubissue.Status.Locked = issue.Locked
+	githubissue.Status.LockReason = issue.LockReason
+
+	// pin the issue and set its type, which are only available through the graphql api of github
+	if push && onGithub && (githubissue.Spec.Pinned != nil || githubissue.Spec.IssueType != "") {
+		if err := r.syncIssueGraphQLFields(ctx, issue, &githubissue, owner, repo); err != nil {
+			log.Error(err, "failed to update issue on github repository", "owner", owner, "repo", repo, "issue", issue.Number)
+			return ctrl.Result{}, err
+		}
+	}
+
+	// add the issue to projects and keep the values of the fields of its items in sync
+	if push && onGithub && (len(githubissue.Spec.Projects) > 0 || len(githubissue.Status.ProjectItems) > 0) {
+		if err := r.syncIssueProjects(ctx, issue, &githubissue, owner); err != nil {
+			log.Error(err, "failed to update project items of issue", "owner", owner, "repo", repo, "issue", issue.Number)
+			return ctrl.Result{}, err
+		}
+	}
+
+	// recreate the issue as a discussion once it is in sync with the object
+	if push && onGithub && githubissue.Spec.RecreateAsDiscussion != nil {
+		if err := r.recreateIssueAsDiscussion(ctx, tracker, issue, &githubissue, owner, repo); err != nil {
+			log.Error(err, "failed to recreate issue as a discussion", "owner", owner, "repo", repo, "issue", issue.Number)
+			return ctrl.Result{}, err
+		}
+	}
+
+	githubissue.Status.ActiveTitle = issue.Title
+	githubissue.Status.ActiveDescription = stripIssueMarker(issue.Body)
+	githubissue.Status.IssueNumber = issue.Number
+	githubissue.Status.Milestone = issue.Milestone
+
+	if adopting {
+		r.setIssueAdoptedCondition(&githubissue, metav1.ConditionTrue, issueAdoptedConditionReason, "The issue was adopted")
+	}
 
 	// set conditions on issue
 	log.Info("Setting conditions on object")
@@ -158,12 +443,11 @@ func (r *GithubIssueReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 // this function sets the condition of the issue that indicates
 // whether the issue has pull requests
-func (r *GithubIssueReconciler) setIssueHasPRCondition(issue *github.Issue, githubissue *trainingv1alpha1.GithubIssue) {
-	hasPR := issue.GetPullRequestLinks()
+func (r *GithubIssueReconciler) setIssueHasPRCondition(issue *Issue, githubissue *trainingv1alpha1.GithubIssue) {
 	conditionStatus := metav1.ConditionTrue
 	message := "The issue has a PR"
 
-	if hasPR == nil {
+	if !issue.HasPullRequest {
 		conditionStatus = metav1.ConditionFalse
 		message = "The issue does not have a PR"
 
@@ -179,10 +463,88 @@ func (r *GithubIssueReconciler) setIssueHasPRCondition(issue *github.Issue, gith
 	apimeta.SetStatusCondition(&githubissue.Status.Conditions, issueCondition)
 }
 
+// this function sets the condition of the issue that indicates
+// whether an existing issue was adopted by the object
+func (r *GithubIssueReconciler) setIssueAdoptedCondition(githubissue *trainingv1alpha1.GithubIssue, status metav1.ConditionStatus, reason, message string) {
+	issueCondition := metav1.Condition{
+		Type:               issueAdoptedConditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: githubissue.Generation,
+	}
+
+	apimeta.SetStatusCondition(&githubissue.Status.Conditions, issueCondition)
+}
+
+// this function sets the condition of the issue that indicates
+// whether the creation of the issue is held back by a limit
+func (r *GithubIssueReconciler) setThrottledCondition(githubissue *trainingv1alpha1.GithubIssue, status metav1.ConditionStatus, reason, message string) {
+	issueCondition := metav1.Condition{
+		Type:               throttledConditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: githubissue.Generation,
+	}
+
+	apimeta.SetStatusCondition(&githubissue.Status.Conditions, issueCondition)
+}
+
+// this function records the error which failed the reconciliation of an object in
+// its status, so the failure is visible on the object and to the template owning it
+func (r *GithubIssueReconciler) recordSyncFailure(ctx context.Context, req ctrl.Request, syncErr error) {
+	log := log.FromContext(ctx)
+
+	var githubissue trainingv1alpha1.GithubIssue
+	if err := r.Get(ctx, req.NamespacedName, &githubissue); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "unable to fetch githubissue")
+		}
+		return
+	}
+
+	apimeta.SetStatusCondition(&githubissue.Status.Conditions, metav1.Condition{
+		Type:               syncFailedConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             syncFailedConditionReason,
+		Message:            syncFailureMessage(syncErr),
+		ObservedGeneration: githubissue.Generation,
+	})
+	if err := r.Status().Update(ctx, &githubissue); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "unable to update githubissue status")
+	}
+}
+
+// this function returns the message of an error which failed the reconciliation, errors
+// of the github api are reported by their message without the request which failed
+func syncFailureMessage(err error) string {
+	if ghErr, ok := err.(*github.ErrorResponse); ok {
+		return ghErr.Message
+	}
+	return err.Error()
+}
+
+// this function clears the condition of the issue that indicates whether the
+// last reconciliation failed, it is only reported on objects which failed before
+func (r *GithubIssueReconciler) clearSyncFailedCondition(githubissue *trainingv1alpha1.GithubIssue) {
+	if apimeta.FindStatusCondition(githubissue.Status.Conditions, syncFailedConditionType) == nil {
+		return
+	}
+
+	apimeta.SetStatusCondition(&githubissue.Status.Conditions, metav1.Condition{
+		Type:               syncFailedConditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             syncSucceededConditionReason,
+		Message:            "The object was reconciled",
+		ObservedGeneration: githubissue.Generation,
+	})
+}
+
 // this function sets the condition of the issue that indicates
 // whether the issue is currently in open state
-func (r *GithubIssueReconciler) setIssueOpenCondition(issue *github.Issue, githubissue *trainingv1alpha1.GithubIssue) {
-	issueState := issue.GetState()
+func (r *GithubIssueReconciler) setIssueOpenCondition(issue *Issue, githubissue *trainingv1alpha1.GithubIssue) {
+	issueState := issue.State
 	conditionStatus := metav1.ConditionTrue
 	message := "The issue is in open state"
 
@@ -202,41 +564,82 @@ func (r *GithubIssueReconciler) setIssueOpenCondition(issue *github.Issue, githu
 }
 
 // this function handles the deletion of a finalizer to an object
-func (r *GithubIssueReconciler) deleteFinalizer(ctx context.Context, githubissue *trainingv1alpha1.GithubIssue, ghClient *github.Client) error {
+func (r *GithubIssueReconciler) deleteFinalizer(ctx context.Context, githubissue *trainingv1alpha1.GithubIssue, tracker IssueTracker) error {
 	log := log.FromContext(ctx)
 	log.Info("Handling finalizer deletion")
 
 	if controllerutil.ContainsFinalizer(githubissue, ghIssueFinalizer) {
-		owner, repo := r.extractOwnerRepoInfo(githubissue)
-		issues, err := r.getIssuesInRepo(ctx, ghClient, owner, repo)
-		if err != nil {
-			log.Error(err, "unable to fetch issues from github repository", "owner", owner, "repo", repo)
+		// an object without an issue, e.g. one whose creation was throttled, is let go right away
+		if _, err := r.closeIssueOfDeletedObject(ctx, tracker, githubissue); err != nil {
 			return err
 		}
 
-		title := githubissue.Spec.Title
-		issue := r.getExistingIssue(issues, title)
+		controllerutil.RemoveFinalizer(githubissue, ghIssueFinalizer)
+		if err := r.Update(ctx, githubissue); err != nil {
+			log.Error(err, "failed to update githubissue")
+			return err
+		}
+	}
+	return nil
+}
 
-		if issue != nil {
-			issueNumber := issue.GetNumber()
+// this function closes the issue of a deleted object as requested by its close policy, an issue
+// claimed by another object is left untouched. the issue is returned, or nil if there is none
+func (r *GithubIssueReconciler) closeIssueOfDeletedObject(ctx context.Context, tracker IssueTracker, githubissue *trainingv1alpha1.GithubIssue) (*Issue, error) {
+	log := log.FromContext(ctx)
 
-			if err := r.closeIssue(ctx, ghClient, issueNumber, owner, repo); err != nil {
-				log.Error(err, "failed to lock issue", "owner", owner, "repo", repo, "issue", issue)
-				return err
-			}
+	// an object whose repository can't be parsed has no issue to close
+	owner, repo, err := r.extractOwnerRepoInfo(githubissue)
+	if err != nil {
+		log.Info("Repository of githubissue can't be parsed, not closing an issue", "repo", githubissue.Spec.Repo)
+		return nil, nil
+	}
+	// an issue which doesn't exist, e.g. a nonexistent issue requested for adoption, can't be closed
+	issue, err := r.findIssue(ctx, tracker, githubissue, owner, repo)
+	if isIssueNotFound(err) {
+		log.Info("Issue of githubissue does not exist, not closing it")
+		return nil, nil
+	}
+	if err != nil {
+		log.Error(err, "unable to fetch issues from github repository", "owner", owner, "repo", repo)
+		return nil, err
+	}
 
-			controllerutil.RemoveFinalizer(githubissue, ghIssueFinalizer)
-			if err := r.Update(ctx, githubissue); err != nil {
-				log.Error(err, "failed to update githubissue")
-				return err
-			}
+	// an issue claimed by another object is left untouched
+	if issue == nil || r.isIssueClaimedByAnother(issue, githubissue) {
+		return issue, nil
+	}
+	issueNumber := issue.Number
+
+	// explain why the issue is closed if the close policy requests it
+	if closePolicy := githubissue.Spec.ClosePolicy; closePolicy != nil && closePolicy.Comment != "" && issue.State != issueStateClosed {
+		if err := tracker.Comment(ctx, owner, repo, issueNumber, closePolicy.Comment); err != nil {
+			log.Error(err, "failed to comment on issue", "owner", owner, "repo", repo, "issue", issueNumber)
+			return nil, err
 		}
 	}
-	return nil
+
+	// an issue which is already closed is not closed again, so that nothing is planned for it
+	if issue.State != issueStateClosed {
+		if err := r.closeIssue(ctx, tracker, issueNumber, owner, repo); err != nil {
+			log.Error(err, "failed to close issue", "owner", owner, "repo", repo, "issue", issue)
+			return nil, err
+		}
+	}
+
+	// freeze the conversation of the closed issue if the close policy requests it
+	if closePolicy := githubissue.Spec.ClosePolicy; closePolicy != nil && closePolicy.Lock && !issue.Locked {
+		if err := r.lockIssue(ctx, tracker, issueNumber, string(closePolicy.LockReason), owner, repo); err != nil {
+			log.Error(err, "failed to lock issue", "owner", owner, "repo", repo, "issue", issue)
+			return nil, err
+		}
+	}
+
+	return issue, nil
 }
 
 // this function handles the addition of a finalizer to an object
-func (r *GithubIssueReconciler) addFinalizer(ctx context.Context, githubissue *trainingv1alpha1.GithubIssue, ghClient *github.Client) error {
+func (r *GithubIssueReconciler) addFinalizer(ctx context.Context, githubissue *trainingv1alpha1.GithubIssue) error {
 	log := log.FromContext(ctx)
 	log.Info("Handling finalizer addition")
 
@@ -252,133 +655,421 @@ func (r *GithubIssueReconciler) addFinalizer(ctx context.Context, githubissue *t
 }
 
 // this function changes the state of an issue to closed
-// IssueRequest is initiated with what needs to be updated and
-// not setting a value for a parameter means keeping the current parameters the same
-func (r *GithubIssueReconciler) closeIssue(ctx context.Context, ghClient *github.Client, issueNumber int, owner, repo string) error {
+func (r *GithubIssueReconciler) closeIssue(ctx context.Context, tracker IssueTracker, issueNumber int, owner, repo string) error {
 	log := log.FromContext(ctx)
 
-	closedState := "closed"
-	issueRequest := github.IssueRequest{
-		State: &closedState,
-	}
-
-	_, response, err := ghClient.Issues.Edit(ctx, owner, repo, issueNumber, &issueRequest)
-
-	if err != nil {
+	if err := tracker.Close(ctx, owner, repo, issueNumber); err != nil {
 		log.Error(err, "unable to close issue")
 		return err
 	}
 
-	if response.StatusCode != http.StatusOK {
-		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
-		return err
-	}
-
 	return nil
 }
 
 // this function creates a new issue
 // IssueRequest is initiated with what needs to be updated and
 // not setting a value for a parameter means keeping the current parameters the same
-func (r *GithubIssueReconciler) createNewIssue(ctx context.Context, ghClient *github.Client, title, description, owner, repo string) (*github.Issue, error) {
+func (r *GithubIssueReconciler) createNewIssue(ctx context.Context, tracker IssueTracker, title, description string, labels, assignees []string, issueType, owner, repo string) (*Issue, error) {
 	log := log.FromContext(ctx)
 
-	issueRequest := github.IssueRequest{
+	issueRequest := IssueRequest{
 		Title: &title,
 		Body:  &description,
 	}
+	if len(labels) > 0 {
+		issueRequest.Labels = &labels
+	}
+	if len(assignees) > 0 {
+		issueRequest.Assignees = &assignees
+	}
+	if issueType != "" {
+		issueRequest.IssueType = &issueType
+	}
 
-	issue, response, err := ghClient.Issues.Create(ctx, owner, repo, &issueRequest)
+	issue, err := tracker.Create(ctx, owner, repo, &issueRequest)
 
 	if err != nil {
 		log.Error(err, "unable to create issue")
 		return issue, err
 	}
 
-	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
-		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
-		return issue, err
+	return issue, nil
+
+}
+
+// this function applies the spec of the object to the issue, the issue
+// is updated in place to reflect the state of the issue on github
+func (r *GithubIssueReconciler) pushSpecToIssue(ctx context.Context, tracker IssueTracker, issue *Issue, githubissue *trainingv1alpha1.GithubIssue, owner, repo string, adopting bool) error {
+	log := log.FromContext(ctx)
+	title := githubissue.Spec.Title
+	description := githubissue.Spec.Description
+
+	// keep the title of the issue in sync with the spec, a title which was changed in the spec
+	// is applied to the issue while a title which was changed on github is either corrected
+	// back or accepted into the spec according to the title drift policy
+	if issueTitle := issue.Title; title != "" && issueTitle != title {
+		if title == githubissue.Status.ActiveTitle && githubissue.Spec.TitleDriftPolicy == trainingv1alpha1.TitleDriftPolicyAccept {
+			log.Info("Accepting title of issue into the spec", "issue", issue.Number, "title", issueTitle)
+			githubissue.Spec.Title = issueTitle
+			if err := r.Update(ctx, githubissue); err != nil {
+				log.Error(err, "failed to update githubissue")
+				return err
+			}
+		} else {
+			if err := r.updateIssueTitle(ctx, tracker, issue, title, owner, repo); err != nil {
+				log.Error(err, "failed to update issue on github repository", "owner", owner, "repo", repo, "issue", issue)
+				return err
+			}
+			issue.Title = title
+		}
 	}
 
-	return issue, nil
+	// update the description of the issue, an adopted issue
+	// is also updated to stamp the marker into its body
+	if stripIssueMarker(issue.Body) != description || (adopting && getIssueMarkerOwner(issue.Body) == "") {
+		body := withIssueMarker(description, githubissue)
+		if err := r.updateIssueDescription(ctx, tracker, issue, body, owner, repo); err != nil {
+			log.Error(err, "failed to update issue on github repository", "owner", owner, "repo", repo, "issue", issue)
+			return err
+		}
+		issue.Body = body
+	}
+
+	// update the labels of the issue
+	labels := githubissue.Spec.Labels
+	if len(labels) > 0 && !equalUnordered(labels, issue.Labels) {
+		if err := r.updateIssueLabels(ctx, tracker, issue, labels, owner, repo); err != nil {
+			log.Error(err, "failed to update issue on github repository", "owner", owner, "repo", repo, "issue", issue)
+			return err
+		}
+		issue.Labels = labels
+	}
+
+	// update the assignees of the issue
+	assignees := githubissue.Spec.Assignees
+	if len(assignees) > 0 && !equalUnordered(assignees, issue.Assignees) {
+		if err := r.updateIssueAssignees(ctx, tracker, issue, assignees, owner, repo); err != nil {
+			log.Error(err, "failed to update issue on github repository", "owner", owner, "repo", repo, "issue", issue)
+			return err
+		}
+		issue.Assignees = assignees
+	}
+
+	// open or close the issue
+	if state := githubissue.Spec.State; state != "" && state != issue.State {
+		if err := r.updateIssueState(ctx, tracker, issue, state, owner, repo); err != nil {
+			log.Error(err, "failed to update issue on github repository", "owner", owner, "repo", repo, "issue", issue)
+			return err
+		}
+		issue.State = state
+	}
+
+	// add the issue to the milestone referenced by the object
+	if githubissue.Spec.MilestoneRef != nil {
+		if err := r.syncIssueMilestone(ctx, tracker, issue, githubissue, owner, repo); err != nil {
+			log.Error(err, "failed to update issue on github repository", "owner", owner, "repo", repo, "issue", issue)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// this function locks or unlocks the conversation of an issue according to the spec, an
+// issue which is closed is also locked if the close policy of the object requests it
+func (r *GithubIssueReconciler) syncIssueLock(ctx context.Context, tracker IssueTracker, issue *Issue, githubissue *trainingv1alpha1.GithubIssue, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	locked := githubissue.Spec.Locked
+	lockReason := string(githubissue.Spec.LockReason)
+	if closePolicy := githubissue.Spec.ClosePolicy; closePolicy != nil && closePolicy.Lock && issue.State == issueStateClosed {
+		lockOnClose := true
+		locked = &lockOnClose
+		lockReason = string(closePolicy.LockReason)
+	}
+
+	// the lock of the issue is left untouched if it is not set in the spec
+	if locked == nil {
+		return nil
+	}
+
+	issueNumber := issue.Number
+	switch {
+	case *locked && (!issue.Locked || (lockReason != "" && lockReason != issue.LockReason)):
+		if err := r.lockIssue(ctx, tracker, issueNumber, lockReason, owner, repo); err != nil {
+			log.Error(err, "failed to lock issue", "owner", owner, "repo", repo, "issue", issueNumber)
+			return err
+		}
+		issue.Locked = true
+		issue.LockReason = lockReason
+	case !*locked && issue.Locked:
+		if err := r.unlockIssue(ctx, tracker, issueNumber, owner, repo); err != nil {
+			log.Error(err, "failed to unlock issue", "owner", owner, "repo", repo, "issue", issueNumber)
+			return err
+		}
+		issue.Locked = false
+		issue.LockReason = ""
+	}
 
+	return nil
+}
+
+// this function locks the conversation of an issue with an optional reason
+func (r *GithubIssueReconciler) lockIssue(ctx context.Context, tracker IssueTracker, issueNumber int, lockReason, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	if err := tracker.Lock(ctx, owner, repo, issueNumber, lockReason); err != nil {
+		log.Error(err, "unable to lock issue")
+		return err
+	}
+
+	return nil
+}
+
+// this function unlocks the conversation of an issue
+func (r *GithubIssueReconciler) unlockIssue(ctx context.Context, tracker IssueTracker, issueNumber int, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	if err := tracker.Unlock(ctx, owner, repo, issueNumber); err != nil {
+		log.Error(err, "unable to unlock issue")
+		return err
+	}
+
+	return nil
+}
+
+// this function updates the title of an issue
+// IssueRequest is initiated with what needs to be updated and
+// not setting a value for a parameter means keeping the current parameters the same
+func (r *GithubIssueReconciler) updateIssueTitle(ctx context.Context, tracker IssueTracker, issue *Issue, title, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	issueRequest := IssueRequest{
+		Title: &title,
+	}
+
+	updated, err := tracker.Update(ctx, owner, repo, issue.Number, &issueRequest)
+	if err != nil {
+		log.Error(err, "unable to update issue title")
+		return err
+	}
+	setIssueUpdatedAt(issue, updated)
+
+	return nil
 }
 
 // this function updates the description of an issue
 // IssueRequest is initiated with what needs to be updated and
 // not setting a value for a parameter means keeping the current parameters the same
-func (r *GithubIssueReconciler) updateIssueDescription(ctx context.Context, ghClient *github.Client, issue *github.Issue, description, owner, repo string) error {
+func (r *GithubIssueReconciler) updateIssueDescription(ctx context.Context, tracker IssueTracker, issue *Issue, description, owner, repo string) error {
 	log := log.FromContext(ctx)
 
-	issueRequest := github.IssueRequest{
+	issueRequest := IssueRequest{
 		Body: &description,
 	}
 
-	issueNumber := issue.GetNumber()
-	_, response, err := ghClient.Issues.Edit(ctx, owner, repo, issueNumber, &issueRequest)
-
+	updated, err := tracker.Update(ctx, owner, repo, issue.Number, &issueRequest)
 	if err != nil {
 		log.Error(err, "unable to update issue description")
 		return err
 	}
+	setIssueUpdatedAt(issue, updated)
+
+	return nil
+}
+
+// this function returns the issue linked to the object, an issue that was requested
+// for adoption or was already linked is fetched by its number, otherwise the issue
+// is looked up by its title. nil is returned if no such issue exists
+func (r *GithubIssueReconciler) findIssue(ctx context.Context, tracker IssueTracker, githubissue *trainingv1alpha1.GithubIssue, owner, repo string) (*Issue, error) {
+	log := log.FromContext(ctx)
+
+	issueNumber := r.getRequestedIssueNumber(githubissue)
+	if issueNumber == 0 {
+		issueNumber = githubissue.Status.IssueNumber
+	}
+
+	if issueNumber != 0 {
+		issue, err := tracker.Get(ctx, owner, repo, issueNumber)
+		if err != nil {
+			log.Error(err, "unable to fetch issue", "issue", issueNumber)
+			return nil, err
+		}
+		return issue, nil
+	}
 
-	if response.StatusCode != http.StatusOK {
-		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+	issue, err := tracker.Find(ctx, owner, repo, githubissue.Spec.Title)
+	if err != nil {
+		log.Error(err, "unable to fetch issues")
+		return nil, err
+	}
+
+	return issue, nil
+}
+
+// this function checks whether the marker in the body of an issue
+// names an object other than the given one
+func (r *GithubIssueReconciler) isIssueClaimedByAnother(issue *Issue, githubissue *trainingv1alpha1.GithubIssue) bool {
+	claimedBy := getIssueMarkerOwner(issue.Body)
+	return claimedBy != "" && claimedBy != issueMarkerOwner(githubissue)
+}
+
+// this function updates the labels of an issue
+// IssueRequest is initiated with what needs to be updated and
+// not setting a value for a parameter means keeping the current parameters the same
+func (r *GithubIssueReconciler) updateIssueLabels(ctx context.Context, tracker IssueTracker, issue *Issue, labels []string, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	issueRequest := IssueRequest{
+		Labels: &labels,
+	}
+
+	updated, err := tracker.Update(ctx, owner, repo, issue.Number, &issueRequest)
+	if err != nil {
+		log.Error(err, "unable to update issue labels")
 		return err
 	}
+	setIssueUpdatedAt(issue, updated)
 
 	return nil
 }
 
-// this function checks whether a title of an issue exists in the current open issues
-// in a repository and returns the issue if it exsists and nil otherwise
-func (r *GithubIssueReconciler) getExistingIssue(issues []*github.Issue, title string) *github.Issue {
-	for _, issue := range issues {
-		if issueTitle := issue.GetTitle(); issueTitle == title {
-			return issue
-		}
+// this function replaces the assignees of an issue
+// IssueRequest is initiated with what needs to be updated and
+// not setting a value for a parameter means keeping the current parameters the same
+func (r *GithubIssueReconciler) updateIssueAssignees(ctx context.Context, tracker IssueTracker, issue *Issue, assignees []string, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	issueRequest := IssueRequest{
+		Assignees: &assignees,
 	}
+
+	updated, err := tracker.Update(ctx, owner, repo, issue.Number, &issueRequest)
+	if err != nil {
+		log.Error(err, "unable to update issue assignees")
+		return err
+	}
+	setIssueUpdatedAt(issue, updated)
+
 	return nil
 }
 
-// this function returns the issues in a repository
-// and an error if there is a problem with fetching the issues
-// a problem may be in the status code (i.e. 403 Status Code) or general
-func (r *GithubIssueReconciler) getIssuesInRepo(ctx context.Context, ghClient *github.Client, owner, repo string) ([]*github.Issue, error) {
+// this function changes the state of an issue to open or closed
+// IssueRequest is initiated with what needs to be updated and
+// not setting a value for a parameter means keeping the current parameters the same
+func (r *GithubIssueReconciler) updateIssueState(ctx context.Context, tracker IssueTracker, issue *Issue, state, owner, repo string) error {
 	log := log.FromContext(ctx)
-	issues, response, err := ghClient.Issues.ListByRepo(ctx, owner, repo, &github.IssueListByRepoOptions{State: "all"})
 
-	if err != nil {
-		log.Error(err, "unable to fetch issues from github")
-		return issues, err
+	issueRequest := IssueRequest{
+		State: &state,
 	}
 
-	if response.StatusCode != http.StatusOK {
-		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
-		return issues, err
+	updated, err := tracker.Update(ctx, owner, repo, issue.Number, &issueRequest)
+	if err != nil {
+		log.Error(err, "unable to update issue state")
+		return err
 	}
+	setIssueUpdatedAt(issue, updated)
 
-	return issues, nil
+	return nil
 }
 
 // this function takes a GithubIssue object and extracts
 // the owner and repo information from the repository URL in the spec
-func (r *GithubIssueReconciler) extractOwnerRepoInfo(githubissue *trainingv1alpha1.GithubIssue) (string, string) {
-	repositoryURL := githubissue.Spec.Repo
-	re := regexp.MustCompile(`([^\/]+)\/([^\/]+)$`)
+func (r *GithubIssueReconciler) extractOwnerRepoInfo(githubissue *trainingv1alpha1.GithubIssue) (string, string, error) {
+	return parseOwnerRepo(githubissue.Spec.Repo)
+}
 
-	ownerRepo := re.FindString(repositoryURL)
-	ownerRepoSlice := strings.Split(ownerRepo, "/")
+// this function extracts the owner and repo information from a repository URL, which may
+// also be the URL of an issue in the repository. outside of github.com the owner is everything
+// between the host and the repository, such as group/subgroup on gitlab. an error is returned
+// if the URL doesn't end with an owner and a repository
+func parseOwnerRepo(repositoryURL string) (string, string, error) {
+	trimmedURL := strings.TrimSuffix(repositoryURL, "/")
+	trimmedURL = regexp.MustCompile(`(?:/-)?/issues/\d+$`).ReplaceAllString(trimmedURL, "")
+	trimmedURL = regexp.MustCompile(`^(?i)https?://`).ReplaceAllString(trimmedURL, "")
+
+	segments := strings.Split(trimmedURL, "/")
+	if len(segments) > 2 && strings.Contains(segments[0], ".") && !strings.EqualFold(segments[0], githubHost) {
+		segments = segments[1:]
+	} else if len(segments) > 2 {
+		segments = segments[len(segments)-2:]
+	}
 
-	owner := ownerRepoSlice[0]
-	repo := ownerRepoSlice[1]
+	if len(segments) < 2 {
+		return "", "", fmt.Errorf("repository %q is not of the form owner/repo", repositoryURL)
+	}
+	for _, segment := range segments {
+		if segment == "" {
+			return "", "", fmt.Errorf("repository %q is not of the form owner/repo", repositoryURL)
+		}
+	}
 
-	return owner, repo
+	return strings.Join(segments[:len(segments)-1], "/"), segments[len(segments)-1], nil
+}
+
+// this function returns the number of the issue that the object requests to adopt,
+// either from the spec or from an issue URL in the repository field. 0 is returned
+// if no specific issue was requested
+func (r *GithubIssueReconciler) getRequestedIssueNumber(githubissue *trainingv1alpha1.GithubIssue) int {
+	if githubissue.Spec.IssueNumber != 0 {
+		return githubissue.Spec.IssueNumber
+	}
+
+	re := regexp.MustCompile(`/issues/(\d+)/?$`)
+	match := re.FindStringSubmatch(githubissue.Spec.Repo)
+	if match == nil {
+		return 0
+	}
+
+	issueNumber, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+
+	return issueNumber
+}
+
+// this function returns the identifier of an object which is stamped into the marker
+// of the issues it manages, cluster-scoped objects are identified by their name only
+func issueMarkerOwner(githubissue *trainingv1alpha1.GithubIssue) string {
+	if githubissue.Namespace == "" {
+		return githubissue.Name
+	}
+	return githubissue.Namespace + "/" + githubissue.Name
+}
+
+// this function appends the marker of the object to the body of an issue
+func withIssueMarker(body string, githubissue *trainingv1alpha1.GithubIssue) string {
+	marker := fmt.Sprintf(issueMarkerFormat, issueMarkerOwner(githubissue))
+	if body == "" {
+		return marker
+	}
+	return body + "\n\n" + marker
+}
+
+// this function removes the marker from the body of an issue
+func stripIssueMarker(body string) string {
+	return issueMarkerRegexp.ReplaceAllString(body, "")
+}
+
+// this function returns the identifier of the object named by the marker
+// in the body of an issue and an empty string if there is no marker
+func getIssueMarkerOwner(body string) string {
+	match := issueMarkerRegexp.FindStringSubmatch(body)
+	if match == nil {
+		return ""
+	}
+	return match[1]
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GithubIssueReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&trainingv1alpha1.GithubIssue{}).
+		For(&trainingv1alpha1.GithubIssue{}, builder.WithPredicates(r.Scope.Predicate(), reconcileTriggerPredicate())).
+		Watches(&source.Kind{Type: &trainingv1alpha1.GithubMilestone{}},
+			handler.EnqueueRequestsFromMapFunc(r.findGithubIssuesForMilestone)).
+		Watches(&source.Kind{Type: &trainingv1alpha1.GithubRepoPolicy{}},
+			handler.EnqueueRequestsFromMapFunc(r.findGithubIssuesForRepoPolicy)).
 		Complete(r)
 }
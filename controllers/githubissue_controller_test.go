@@ -18,28 +18,47 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v45/github"
 	ghmock "github.com/migueleliasweb/go-github-mock/src/mock"
 	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	"github.com/mzeevi/githubissues-operator/internal/githubfake"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/shurcooL/githubv4"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+const (
+	// e2eRepoOwner and e2eRepoName are the repository of the fake github api the suite files issues in
+	e2eRepoOwner = "mzeevi"
+	e2eRepoName  = "githubissues-operator"
+)
+
 var _ = Describe("GithubIssue controller", func() {
 	Context("When updating GithubIssue objects", func() {
 		name := "e2e-test-" + GenerateRandomString()
 		title := "e2e-test-" + GenerateRandomString()
 		description := "e2e-test-" + GenerateRandomString()
-		repoName := "https://github.com/mzeevi/githubissues-operator"
+		repoName := "https://github.com/" + e2eRepoOwner + "/" + e2eRepoName
 
 		It("Should update the status of the object and its conditions", func() {
 			By("Creating a new GithubIssue object")
@@ -92,6 +111,11 @@ var _ = Describe("GithubIssue controller", func() {
 				err := k8sClient.Get(ctx, githubIssueLookupKey, &deletedGithubIssue)
 				return errors.IsNotFound(err)
 			}, timeout, interval).Should(BeTrue())
+
+			issue, ok := githubServer.Issue(e2eRepoOwner, e2eRepoName, updatedGithubIssue.Status.IssueNumber)
+			Expect(ok).To(BeTrue())
+			Expect(issue.Title).To(Equal(title))
+			Expect(issue.State).To(Equal("closed"))
 		})
 	})
 })
@@ -145,7 +169,7 @@ func TestFailedCreateIssue(t *testing.T) {
 	ghClient := github.NewClient(mockedHTTPClient)
 
 	// create a NamespaceLabelReconciler object with the scheme and fake client
-	r := &GithubIssueReconciler{cl, s, ghClient}
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
 
 	// mock request to simulate Reconcile() being called on an event for a
 	// watched resource .
@@ -160,6 +184,15 @@ func TestFailedCreateIssue(t *testing.T) {
 
 	g.Expect(ok).To(BeTrue())
 	g.Expect(ghErr.Message).To(Equal(wantedError))
+
+	// the error is recorded on the object
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	syncFailed := apimeta.FindStatusCondition(githubIssueReconciled.Status.Conditions, syncFailedConditionType)
+	g.Expect(syncFailed).ToNot(BeNil())
+	g.Expect(syncFailed.Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(syncFailed.Message).To(ContainSubstring(wantedError))
+	g.Expect(syncFailed.ObservedGeneration).To(Equal(githubIssueReconciled.Generation))
 }
 
 func TestFailedUpdateIssue(t *testing.T) {
@@ -225,7 +258,7 @@ func TestFailedUpdateIssue(t *testing.T) {
 	ghClient := github.NewClient(mockedHTTPClient)
 
 	// create a GithubIssueReconciler object with the scheme and fake client
-	r := &GithubIssueReconciler{cl, s, ghClient}
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
 
 	// mock request to simulate reconcile() being called on an event for a
 	// watched resource .
@@ -330,7 +363,7 @@ func TestCloseIssueOnDelete(t *testing.T) {
 	ghClient := github.NewClient(mockedHTTPClient)
 
 	// create a GithubIssueReconciler object with the scheme and fake client
-	r := &GithubIssueReconciler{cl, s, ghClient}
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
 
 	// mock request to simulate reconcile() being called on an event for a
 	// watched resource .
@@ -357,14 +390,15 @@ func TestCloseIssueOnDelete(t *testing.T) {
 	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(res).ToNot(BeNil())
 
-	owner, repo := r.extractOwnerRepoInfo(&githubIssueReconciled)
+	owner, repo, err := r.extractOwnerRepoInfo(&githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
 	title := githubIssueReconciled.Spec.Title
 
-	issues, err := r.getIssuesInRepo(ctx, ghClient, owner, repo)
+	tracker := &GithubIssueTracker{Client: ghClient}
+	issue, err := tracker.Find(ctx, owner, repo, title)
 	g.Expect(err).ToNot(HaveOccurred())
 
-	issue := r.getExistingIssue(issues, title)
-	issueState := issue.GetState()
+	issueState := issue.State
 
 	g.Eventually(issueState, timeout, interval).Should(Equal("closed"))
 
@@ -415,7 +449,7 @@ func TestCreateIssueIfDoesntExist(t *testing.T) {
 	ghClient := github.NewClient(mockedHTTPClient)
 
 	// create a NamespaceLabelReconciler object with the scheme and fake client
-	r := &GithubIssueReconciler{cl, s, ghClient}
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
 
 	// mock request to simulate Reconcile() being called on an event for a
 	// watched resource .
@@ -452,13 +486,1771 @@ func TestExtractOwnerRepoInfo(t *testing.T) {
 	ghClient := github.NewClient(&http.Client{})
 
 	// create a NamespaceLabelReconciler object with the scheme and fake client
-	r := &GithubIssueReconciler{cl, s, ghClient}
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
 
-	owner, repo := r.extractOwnerRepoInfo(githubIssue)
+	owner, repo, err := r.extractOwnerRepoInfo(githubIssue)
 	expectedOwner := testOwnerName
 	expectedRepo := testRepoName
 
+	g.Expect(err).ToNot(HaveOccurred())
 	g.Expect(owner).To(Equal(expectedOwner))
 	g.Expect(repo).To(Equal(expectedRepo))
 
+	// the owner of a repository outside of github.com may be a group with subgroups
+	githubIssue.Spec.Repo = "https://gitlab.example.com/group/subgroup/project/-/issues/7"
+	owner, repo, err = r.extractOwnerRepoInfo(githubIssue)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(owner).To(Equal("group/subgroup"))
+	g.Expect(repo).To(Equal("project"))
+
+	// a repository without an owner and a name is an error
+	githubIssue.Spec.Repo = "https://github.com/"
+	_, _, err = r.extractOwnerRepoInfo(githubIssue)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestAdoptExistingIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create githubissue object which requests to adopt an existing issue
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.IssueNumber = 42
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with mock data and record
+	// the body of the issue which is sent on update
+	var updatedBody string
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number: github.Int(42),
+				Title:  github.String(githubIssue.Spec.Title),
+				Body:   github.String("Existing issue body"),
+				State:  github.String("open"),
+			},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PatchReposIssuesByOwnerByRepoByIssueNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				issueRequest := github.IssueRequest{}
+				g.Expect(json.NewDecoder(r.Body).Decode(&issueRequest)).To(Succeed())
+				updatedBody = issueRequest.GetBody()
+				w.Write(ghmock.MustMarshal(github.Issue{Number: github.Int(42), Body: issueRequest.Body}))
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the description and the marker of the object are stamped into the adopted issue
+	g.Expect(stripIssueMarker(updatedBody)).To(Equal(githubIssue.Spec.Description))
+	g.Expect(getIssueMarkerOwner(updatedBody)).To(Equal(issueMarkerOwner(githubIssue)))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(Equal(42))
+	g.Expect(githubIssueReconciled.Status.ActiveDescription).To(Equal(githubIssue.Spec.Description))
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, issueAdoptedConditionType)).To(BeTrue())
+}
+
+func TestRefuseAdoptingClaimedIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create githubissue object which requests to adopt an existing issue by its URL
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Repo = testRepo + "/issues/42"
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with an issue that is claimed by another object,
+	// no update of the issue is expected
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number: github.Int(42),
+				Title:  github.String(githubIssue.Spec.Title),
+				Body:   github.String("Existing issue body\n\n<!-- githubissues-operator: other/githubissue -->"),
+				State:  github.String("open"),
+			},
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	condition := apimeta.FindStatusCondition(githubIssueReconciled.Status.Conditions, issueAdoptedConditionType)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(condition.Reason).To(Equal(issueClaimedConditionReason))
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(BeZero())
+}
+
+func TestRefuseAdoptingMissingIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	// create githubissue object which requests to adopt an issue that doesn't exist
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.IssueNumber = 42
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+
+	// the missing issue is reported and not retried, and no issue is created instead
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(server.Issues(testOwnerName, testRepoName)).To(BeEmpty())
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+
+	condition := apimeta.FindStatusCondition(githubIssueReconciled.Status.Conditions, issueAdoptedConditionType)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(condition.Reason).To(Equal(issueNotFoundConditionReason))
+	g.Expect(condition.Message).To(ContainSubstring("#42"))
+	g.Expect(apimeta.FindStatusCondition(githubIssueReconciled.Status.Conditions, syncFailedConditionType)).To(BeNil())
+
+	// the object is let go when it is deleted, there is no issue to close
+	g.Expect(cl.Delete(ctx, &githubIssueReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestExtractOwnerRepoInfoFromIssueURL(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Repo = testRepo + "/issues/42"
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	ghClient := github.NewClient(&http.Client{})
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	owner, repo, err := r.extractOwnerRepoInfo(githubIssue)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(owner).To(Equal(testOwnerName))
+	g.Expect(repo).To(Equal(testRepoName))
+	g.Expect(r.getRequestedIssueNumber(githubIssue)).To(Equal(42))
+}
+
+func TestRenameIssueOnTitleChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create githubissue object which is linked to an issue and whose title was changed
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Status.IssueNumber = 7
+	githubIssue.Status.ActiveTitle = "old title"
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with mock data and record
+	// the title of the issue which is sent on update
+	var updatedTitle string
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number: github.Int(7),
+				Title:  github.String("old title"),
+				Body:   github.String(githubIssue.Spec.Description),
+				State:  github.String("open"),
+			},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PatchReposIssuesByOwnerByRepoByIssueNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				issueRequest := github.IssueRequest{}
+				g.Expect(json.NewDecoder(r.Body).Decode(&issueRequest)).To(Succeed())
+				updatedTitle = issueRequest.GetTitle()
+				w.Write(ghmock.MustMarshal(github.Issue{Number: github.Int(7), Title: issueRequest.Title}))
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the same issue is renamed instead of opening a new one
+	g.Expect(updatedTitle).To(Equal(githubIssue.Spec.Title))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(githubIssueReconciled.Status.ActiveTitle).To(Equal(githubIssue.Spec.Title))
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(Equal(7))
+}
+
+func TestAcceptTitleDrift(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create githubissue object which accepts titles that were changed on github
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.TitleDriftPolicy = trainingv1alpha1.TitleDriftPolicyAccept
+	githubIssue.Status.IssueNumber = 7
+	githubIssue.Status.ActiveTitle = githubIssue.Spec.Title
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with an issue that was renamed on github,
+	// no update of the issue is expected
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number: github.Int(7),
+				Title:  github.String("renamed on github"),
+				Body:   github.String(githubIssue.Spec.Description),
+				State:  github.String("open"),
+			},
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(githubIssueReconciled.Spec.Title).To(Equal("renamed on github"))
+	g.Expect(githubIssueReconciled.Status.ActiveTitle).To(Equal("renamed on github"))
+}
+
+func TestBidirectionalSyncPullsIssueChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create githubissue object which was synced before the issue was changed on github
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.SyncDirection = trainingv1alpha1.SyncDirectionBidirectional
+	githubIssue.Status.IssueNumber = 7
+	githubIssue.Status.LastAppliedHash = hashSyncedFields(getSpecSyncedFields(githubIssue))
+	githubIssue.Status.GithubUpdatedAt = &metav1.Time{Time: time.Now().Add(-time.Hour).Truncate(time.Second)}
+	updatedAt := time.Now()
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with an issue that was edited on github,
+	// no update of the issue is expected
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number:    github.Int(7),
+				Title:     github.String("edited on github"),
+				Body:      github.String("edited body"),
+				State:     github.String("closed"),
+				Labels:    []*github.Label{{Name: github.String("bug")}},
+				UpdatedAt: &updatedAt,
+			},
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(githubIssueReconciled.Spec.Title).To(Equal("edited on github"))
+	g.Expect(githubIssueReconciled.Spec.Description).To(Equal("edited body"))
+	g.Expect(githubIssueReconciled.Spec.State).To(Equal("closed"))
+	g.Expect(githubIssueReconciled.Spec.Labels).To(Equal([]string{"bug"}))
+	g.Expect(apimeta.IsStatusConditionFalse(githubIssueReconciled.Status.Conditions, conflictConditionType)).To(BeTrue())
+}
+
+func TestBidirectionalSyncPushesRepeatedSpecChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	// every request to the fake github happens a second after the previous one
+	server := githubfake.NewServer()
+	defer server.Close()
+	clock := time.Now().Truncate(time.Second)
+	server.Now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	server.AddRepo(testOwnerName, testRepoName)
+
+	// labels and state are left to the issue, they are not managed by the object
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.SyncDirection = trainingv1alpha1.SyncDirectionBidirectional
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the spec is edited twice in a row, both edits are pushed to the issue
+	// without being mistaken for changes on github
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	for _, description := range []string{"first edit", "second edit"} {
+		g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+		githubIssueReconciled.Spec.Description = description
+		g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+
+		_, err = r.Reconcile(ctx, req)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		issue, _ := server.Issue(testOwnerName, testRepoName, 1)
+		g.Expect(stripIssueMarker(issue.Body)).To(Equal(description))
+
+		g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+		g.Expect(githubIssueReconciled.Spec.Description).To(Equal(description))
+		g.Expect(githubIssueReconciled.Spec.Labels).To(BeEmpty())
+		g.Expect(githubIssueReconciled.Spec.State).To(BeEmpty())
+		g.Expect(apimeta.IsStatusConditionFalse(githubIssueReconciled.Status.Conditions, conflictConditionType)).To(BeTrue())
+	}
+
+	// a reconcile without changes on either side neither pushes nor pulls
+	requests := len(server.Requests())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, request := range server.Requests()[requests:] {
+		g.Expect(request).To(HavePrefix(http.MethodGet + " "))
+	}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(githubIssueReconciled.Spec.Description).To(Equal("second edit"))
+	g.Expect(apimeta.IsStatusConditionFalse(githubIssueReconciled.Status.Conditions, conflictConditionType)).To(BeTrue())
+}
+
+func TestBidirectionalSyncConflict(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create githubissue object whose spec changed since the last sync
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.SyncDirection = trainingv1alpha1.SyncDirectionBidirectional
+	githubIssue.Status.IssueNumber = 7
+	githubIssue.Status.LastAppliedHash = hashSyncedFields(getSpecSyncedFields(githubIssue))
+	githubIssue.Status.GithubUpdatedAt = &metav1.Time{Time: time.Now().Add(-time.Hour).Truncate(time.Second)}
+	updatedAt := time.Now()
+	githubIssue.Spec.Description = "edited in the cluster"
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with an issue that was also edited on github,
+	// no update of the issue is expected
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number:    github.Int(7),
+				Title:     github.String(githubIssue.Spec.Title),
+				Body:      github.String("edited on github"),
+				State:     github.String("open"),
+				UpdatedAt: &updatedAt,
+			},
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(githubIssueReconciled.Spec.Description).To(Equal("edited in the cluster"))
+	g.Expect(githubIssueReconciled.Status.ActiveDescription).To(Equal("edited on github"))
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, conflictConditionType)).To(BeTrue())
+}
+
+func TestLockIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create githubissue object which requests to lock the conversation of its issue
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Locked = github.Bool(true)
+	githubIssue.Spec.LockReason = "resolved"
+	githubIssue.Status.IssueNumber = 7
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with mock data and record
+	// the reason which is sent when locking the issue
+	var lockReason string
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number: github.Int(7),
+				Title:  github.String(githubIssue.Spec.Title),
+				Body:   github.String(githubIssue.Spec.Description),
+				State:  github.String("open"),
+			},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PutReposIssuesLockByOwnerByRepoByIssueNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lockOptions := github.LockIssueOptions{}
+				g.Expect(json.NewDecoder(r.Body).Decode(&lockOptions)).To(Succeed())
+				lockReason = lockOptions.LockReason
+				w.WriteHeader(http.StatusNoContent)
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(lockReason).To(Equal("resolved"))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(githubIssueReconciled.Status.Locked).To(BeTrue())
+	g.Expect(githubIssueReconciled.Status.LockReason).To(Equal("resolved"))
+}
+
+func TestPinIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create githubissue object which requests to pin its issue
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Pinned = github.Bool(true)
+	githubIssue.Status.IssueNumber = 7
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with mock data
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number: github.Int(7),
+				NodeID: github.String("I_7"),
+				Title:  github.String(githubIssue.Spec.Title),
+				Body:   github.String(githubIssue.Spec.Description),
+				State:  github.String("open"),
+			},
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create graphql server which reports the issue as unpinned and records the pinned issue
+	var pinnedIssueID string
+	graphqlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+
+		if strings.Contains(request.Query, "pinIssue") {
+			pinnedIssueID = request.Variables["input"].(map[string]interface{})["issueId"].(string)
+			w.Write([]byte(`{"data":{"pinIssue":{"issue":{"id":"I_7"}}}}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"repository":{"issue":{"isPinned":false}}}}`))
+	}))
+	defer graphqlServer.Close()
+
+	ghV4Client := githubv4.NewEnterpriseClient(graphqlServer.URL, graphqlServer.Client())
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient, GithubV4Client: ghV4Client}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(pinnedIssueID).To(Equal("I_7"))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(githubIssueReconciled.Status.Pinned).To(BeTrue())
+}
+
+func TestTransferIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create githubissue object which adopted an issue and requests to transfer it to another repository
+	transferTo := "https://github.com/" + testOwnerName + "/otherRepo"
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.TransferTo = transferTo
+	githubIssue.Spec.IssueNumber = 7
+	githubIssue.Status.IssueNumber = 7
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with mock data
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number:        github.Int(7),
+				NodeID:        github.String("I_7"),
+				RepositoryURL: github.String("https://api.github.com/repos/" + testOwnerName + "/" + testRepoName),
+				Title:         github.String(githubIssue.Spec.Title),
+				Body:          github.String(githubIssue.Spec.Description),
+				State:         github.String("open"),
+			},
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create graphql server which resolves the target repository and transfers the issue
+	graphqlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Query string `json:"query"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+
+		if strings.Contains(request.Query, "transferIssue") {
+			w.Write([]byte(`{"data":{"transferIssue":{"issue":{"number":12}}}}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"repository":{"id":"R_2"}}}`))
+	}))
+	defer graphqlServer.Close()
+
+	ghV4Client := githubv4.NewEnterpriseClient(graphqlServer.URL, graphqlServer.Client())
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient, GithubV4Client: ghV4Client}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	res, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.Requeue).To(BeTrue())
+
+	// the spec points at the new repository, while the new issue number is only tracked in the status
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(githubIssueReconciled.Spec.Repo).To(Equal(transferTo))
+	g.Expect(githubIssueReconciled.Spec.IssueNumber).To(BeZero())
+	g.Expect(githubIssueReconciled.Spec.TransferTo).To(BeEmpty())
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(Equal(12))
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, issueTransferredConditionType)).To(BeTrue())
+	g.Expect(r.getRequestedIssueNumber(&githubIssueReconciled)).To(BeZero())
+}
+
+func TestRecreateIssueAsDiscussion(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	// create githubissue object which requests to recreate its issue as a discussion
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.RecreateAsDiscussion = &trainingv1alpha1.DiscussionRecreation{Category: "ideas"}
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create graphql server which resolves the discussion categories and creates the discussion
+	discussionURL := "https://github.com/" + testOwnerName + "/" + testRepoName + "/discussions/3"
+	var createdDiscussions []map[string]interface{}
+	graphqlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+
+		if strings.Contains(request.Query, "createDiscussion") {
+			createdDiscussions = append(createdDiscussions, request.Variables["input"].(map[string]interface{}))
+			w.Write([]byte(`{"data":{"createDiscussion":{"discussion":{"url":"` + discussionURL + `"}}}}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"repository":{"id":"R_1","discussionCategories":{"nodes":[{"id":"DC_1","name":"General"},{"id":"DC_2","name":"Ideas"}]}}}}`))
+	}))
+	defer graphqlServer.Close()
+
+	ghV4Client := githubv4.NewEnterpriseClient(graphqlServer.URL, graphqlServer.Client())
+
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: server.Client(), GithubV4Client: ghV4Client}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the discussion is created in the requested category with the contents of the issue
+	g.Expect(createdDiscussions).To(HaveLen(1))
+	g.Expect(createdDiscussions[0]["categoryId"]).To(Equal("DC_2"))
+	g.Expect(createdDiscussions[0]["title"]).To(Equal(githubIssue.Spec.Title))
+	g.Expect(createdDiscussions[0]["body"]).To(HavePrefix(githubIssue.Spec.Description))
+
+	// the issue is closed with a comment linking to the discussion
+	issue, _ := server.Issue(testOwnerName, testRepoName, 1)
+	g.Expect(issue.State).To(Equal("closed"))
+	g.Expect(issue.Comments).To(HaveLen(1))
+	g.Expect(issue.Comments[0].Body).To(ContainSubstring(discussionURL))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(githubIssueReconciled.Status.DiscussionURL).To(Equal(discussionURL))
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, issueRecreatedConditionType)).To(BeTrue())
+	g.Expect(apimeta.IsStatusConditionFalse(githubIssueReconciled.Status.Conditions, issueOpenConditionType)).To(BeTrue())
+
+	// the recreated issue is no longer synced with the object
+	requests := len(server.Requests())
+	githubIssueReconciled.Spec.Description = "updated-" + githubIssue.Spec.Description
+	g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(server.Requests()).To(HaveLen(requests))
+	g.Expect(createdDiscussions).To(HaveLen(1))
+}
+
+func TestCompletePendingIssueTransfer(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	// create githubissue object whose transfer was recorded in the status, but not in the spec
+	transferTo := "https://github.com/" + testOwnerName + "/otherRepo"
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.TransferTo = transferTo
+	githubIssue.Status.IssueNumber = 12
+	githubIssue.Status.Conditions = []metav1.Condition{{
+		Type:               issueTransferredConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             issueTransferredConditionReason,
+		LastTransitionTime: metav1.Now(),
+	}}
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// no github requests are expected, the transfer is completed in the spec only
+	ghClient := github.NewClient(ghmock.NewMockedHTTPClient())
+
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	res, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.Requeue).To(BeTrue())
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(githubIssueReconciled.Spec.Repo).To(Equal(transferTo))
+	g.Expect(githubIssueReconciled.Spec.TransferTo).To(BeEmpty())
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(Equal(12))
+}
+
+func TestSyncIssueProjects(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create githubissue object which is added to one project and was removed from another
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Projects = []trainingv1alpha1.GithubProject{
+		{
+			Number: 1,
+			Fields: []trainingv1alpha1.ProjectFieldValue{{Name: "Status", Value: "Done"}},
+		},
+	}
+	githubIssue.Status.IssueNumber = 7
+	githubIssue.Status.ProjectItems = []trainingv1alpha1.ProjectItemStatus{{ProjectID: "PVT_old", ItemID: "PVTI_old"}}
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with mock data
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number: github.Int(7),
+				NodeID: github.String("I_7"),
+				Title:  github.String(githubIssue.Spec.Title),
+				Body:   github.String(githubIssue.Spec.Description),
+				State:  github.String("open"),
+			},
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create graphql server with a project that has a status field and record the mutations
+	var mutations []string
+	var selectedOption string
+	graphqlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		g.Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+
+		switch {
+		case strings.Contains(request.Query, "addProjectV2ItemById"):
+			mutations = append(mutations, "add")
+			w.Write([]byte(`{"data":{"addProjectV2ItemById":{"item":{"id":"PVTI_1"}}}}`))
+		case strings.Contains(request.Query, "updateProjectV2ItemFieldValue"):
+			mutations = append(mutations, "update")
+			value := request.Variables["input"].(map[string]interface{})["value"].(map[string]interface{})
+			selectedOption = value["singleSelectOptionId"].(string)
+			w.Write([]byte(`{"data":{"updateProjectV2ItemFieldValue":{"projectV2Item":{"id":"PVTI_1"}}}}`))
+		case strings.Contains(request.Query, "deleteProjectV2Item"):
+			mutations = append(mutations, "delete")
+			w.Write([]byte(`{"data":{"deleteProjectV2Item":{"deletedItemId":"PVTI_old"}}}`))
+		case strings.Contains(request.Query, "organization("):
+			w.Write([]byte(`{"data":{"organization":{"projectV2":{"id":"PVT_1","fields":{"nodes":[` +
+				`{"id":"F_1","name":"Status","dataType":"SINGLE_SELECT","options":[{"id":"O_1","name":"Todo"},{"id":"O_2","name":"Done"}]}]}}}}}`))
+		default:
+			w.Write([]byte(`{"data":{"node":{"fieldValues":{"nodes":[` +
+				`{"__typename":"ProjectV2ItemFieldSingleSelectValue","field":{"name":"Status"},"name":"Todo"}]}}}}`))
+		}
+	}))
+	defer graphqlServer.Close()
+
+	ghV4Client := githubv4.NewEnterpriseClient(graphqlServer.URL, graphqlServer.Client())
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient, GithubV4Client: ghV4Client}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(mutations).To(Equal([]string{"add", "update", "delete"}))
+	g.Expect(selectedOption).To(Equal("O_2"))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(githubIssueReconciled.Status.ProjectItems).To(Equal([]trainingv1alpha1.ProjectItemStatus{{ProjectID: "PVT_1", ItemID: "PVTI_1"}}))
+}
+
+func TestAddIssueToMilestone(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create a milestone which was already created on github
+	// and a githubissue object which references it
+	githubMilestone := GenerateGithubMilestoneObject()
+	githubMilestone.Status.MilestoneNumber = 3
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.MilestoneRef = &corev1.LocalObjectReference{Name: githubMilestone.Name}
+	githubIssue.Status.IssueNumber = 7
+
+	obj := []client.Object{githubMilestone, githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client with mock data and record
+	// the milestone which is set on the issue
+	var milestone int
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepoByIssueNumber,
+			github.Issue{
+				Number: github.Int(7),
+				Title:  github.String(githubIssue.Spec.Title),
+				Body:   github.String(githubIssue.Spec.Description),
+				State:  github.String("open"),
+			},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PatchReposIssuesByOwnerByRepoByIssueNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				issueRequest := github.IssueRequest{}
+				g.Expect(json.NewDecoder(r.Body).Decode(&issueRequest)).To(Succeed())
+				milestone = issueRequest.GetMilestone()
+				w.Write(ghmock.MustMarshal(github.Issue{Number: github.Int(7)}))
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(milestone).To(Equal(3))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(githubIssueReconciled.Status.Milestone).To(Equal(3))
+
+	// an object referencing a milestone which was not created yet is not synced
+	githubMilestone.Status.MilestoneNumber = 0
+	g.Expect(cl.Status().Update(ctx, githubMilestone)).To(Succeed())
+	_, err = r.resolveMilestoneRef(ctx, &githubIssueReconciled, testOwnerName, testRepoName)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestRepoPolicyViolation(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create a policy which doesn't allow the repository of the object
+	githubIssue := GenerateGithubIssueObject()
+	githubRepoPolicy := &trainingv1alpha1.GithubRepoPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: GenerateRandomString()},
+		Spec: trainingv1alpha1.GithubRepoPolicySpec{
+			AllowedRepos: []string{"allowed-owner/*"},
+		},
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: githubIssue.Namespace}}
+
+	obj := []client.Object{githubIssue, githubRepoPolicy, namespace}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the mocked client has no handlers, so any request to github fails
+	ghClient := github.NewClient(ghmock.NewMockedHTTPClient())
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, policyViolationConditionType)).To(BeTrue())
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(BeZero())
+
+	// the object is mapped to a request when the policy changes
+	g.Expect(r.findGithubIssuesForRepoPolicy(githubRepoPolicy)).To(ConsistOf(req))
+}
+
+func TestRepoPolicyViolationOnDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create a policy which doesn't allow the repository of the object
+	githubIssue := GenerateGithubIssueObject()
+	githubRepoPolicy := &trainingv1alpha1.GithubRepoPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: GenerateRandomString()},
+		Spec: trainingv1alpha1.GithubRepoPolicySpec{
+			AllowedRepos: []string{"allowed-owner/*"},
+		},
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: githubIssue.Namespace}}
+
+	obj := []client.Object{githubIssue, githubRepoPolicy, namespace}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the mocked client has no handlers, so any request to github fails
+	ghClient := github.NewClient(ghmock.NewMockedHTTPClient())
+
+	// create a GithubIssueReconciler object with the scheme and fake client
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the deleted object is let go without a request to github
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(githubIssueReconciled.Finalizers).To(ContainElement(ghIssueFinalizer))
+	g.Expect(cl.Delete(ctx, &githubIssueReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestThrottleIssueCreation(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	// create context
+	ctx := context.Background()
+
+	// create an object which is linked to an open issue and a new
+	// object which targets the same repository
+	openGithubIssue := GenerateGithubIssueObject()
+	openGithubIssue.Status.IssueNumber = 1
+	apimeta.SetStatusCondition(&openGithubIssue.Status.Conditions, metav1.Condition{
+		Type:   issueOpenConditionType,
+		Status: metav1.ConditionTrue,
+		Reason: issueOpenConditionReason,
+	})
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Name = openGithubIssue.Name + "-new"
+
+	// an object with a malformed repository elsewhere in the cluster is skipped
+	malformedGithubIssue := GenerateGithubIssueObject()
+	malformedGithubIssue.Name = openGithubIssue.Name + "-malformed"
+	malformedGithubIssue.Spec.Repo = "https://github.com/"
+
+	obj := []client.Object{openGithubIssue, githubIssue, malformedGithubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// create mock githubissue client which fails the test if an issue is created
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepo,
+			[]github.Issue{},
+			[]github.Issue{},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PostReposIssuesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("an issue was created although the repository is at its limit")
+				w.WriteHeader(http.StatusInternalServerError)
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// create a GithubIssueReconciler object which allows one open issue per repository
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient, Throttle: &Throttle{MaxOpenIssuesPerRepo: 1}}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	result, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(throttleRetryInterval))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, throttledConditionType)).To(BeTrue())
+
+	// the throttled object has no issue and is let go when it is deleted
+	g.Expect(cl.Delete(ctx, &githubIssueReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestThrottleCreationRate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	now := time.Date(2022, time.October, 1, 12, 0, 0, 0, time.UTC)
+	throttle := &Throttle{MaxCreationsPerHourPerNamespace: 2, MaxCreationsPerHourPerRepo: 1, now: func() time.Time { return now }}
+
+	githubIssue := GenerateGithubIssueObject()
+	cl, _, err := SetupClient([]client.Object{githubIssue})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	limit, _, _, err := throttle.check(ctx, cl, githubIssue, testOwnerName, testRepoName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(BeEmpty())
+
+	// the repository reaches its limit before the namespace
+	now = now.Add(10 * time.Minute)
+	limit, wait, _, err := throttle.check(ctx, cl, githubIssue, testOwnerName, testRepoName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(Equal(creationsPerRepoLimit))
+	g.Expect(wait).To(Equal(50 * time.Minute))
+
+	limit, _, _, err = throttle.check(ctx, cl, githubIssue, testOwnerName, "other-repo")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(BeEmpty())
+	limit, _, _, err = throttle.check(ctx, cl, githubIssue, testOwnerName, "third-repo")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(Equal(creationsPerNamespaceLimit))
+
+	// the creations leave the window after an hour
+	now = now.Add(time.Hour)
+	limit, _, reservation, err := throttle.check(ctx, cl, githubIssue, testOwnerName, testRepoName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(BeEmpty())
+
+	// a released creation doesn't count against the limits
+	throttle.release(reservation)
+	limit, _, _, err = throttle.check(ctx, cl, githubIssue, testOwnerName, testRepoName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(BeEmpty())
+}
+
+func TestThrottleReservesCreations(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	githubIssue := GenerateGithubIssueObject()
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// concurrent reconciles don't create more issues than allowed
+	throttle := &Throttle{MaxCreationsPerHourPerRepo: 1}
+	var wg sync.WaitGroup
+	var allowed int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit, _, _, err := throttle.check(ctx, cl, githubIssue, testOwnerName, testRepoName)
+			if err == nil && limit == "" {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	g.Expect(allowed).To(Equal(int32(1)))
+
+	// an issue which is being created counts as open until its number reaches the cache
+	otherGithubIssue := GenerateGithubIssueObject()
+	otherGithubIssue.Name = githubIssue.Name + "-other"
+	g.Expect(cl.Create(ctx, otherGithubIssue)).To(Succeed())
+
+	throttle = &Throttle{MaxOpenIssuesPerRepo: 1}
+	limit, _, reservation, err := throttle.check(ctx, cl, githubIssue, testOwnerName, testRepoName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(BeEmpty())
+	limit, _, _, err = throttle.check(ctx, cl, otherGithubIssue, testOwnerName, testRepoName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(Equal(openIssuesPerRepoLimit))
+
+	throttle.release(reservation)
+	limit, _, _, err = throttle.check(ctx, cl, otherGithubIssue, testOwnerName, testRepoName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(BeEmpty())
+
+	// the pending creation is settled once the issue number of the object is cached
+	otherGithubIssue.Status.IssueNumber = 5
+	apimeta.SetStatusCondition(&otherGithubIssue.Status.Conditions, metav1.Condition{
+		Type:   issueOpenConditionType,
+		Status: metav1.ConditionFalse,
+		Reason: issueOpenConditionReason,
+	})
+	g.Expect(cl.Status().Update(ctx, otherGithubIssue)).To(Succeed())
+	limit, _, _, err = throttle.check(ctx, cl, githubIssue, testOwnerName, testRepoName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(BeEmpty())
+
+	// create mock githubissue client which fails to create the issue
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposIssuesByOwnerByRepo,
+			[]github.Issue{},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PostReposIssuesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ghmock.WriteError(w, http.StatusInternalServerError, "creating issue failed")
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	// the creation which failed is released
+	throttle = &Throttle{MaxCreationsPerHourPerRepo: 1}
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: ghClient, Throttle: throttle}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).To(HaveOccurred())
+
+	limit, _, _, err = throttle.check(ctx, cl, githubIssue, testOwnerName, testRepoName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limit).To(BeEmpty())
+}
+
+func TestReconcileAgainstFakeGithub(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Labels = []string{"bug"}
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+
+	// a failing github api fails the reconcile, which succeeds once github recovers
+	server.InjectFault(githubfake.Fault{Method: http.MethodPost, StatusCode: http.StatusBadGateway, Times: 1})
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(server.Issues(testOwnerName, testRepoName)).To(BeEmpty())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	issues := server.Issues(testOwnerName, testRepoName)
+	g.Expect(issues).To(HaveLen(1))
+	g.Expect(issues[0].Title).To(Equal(githubIssue.Spec.Title))
+	g.Expect(issues[0].Labels).To(Equal([]string{"bug"}))
+	g.Expect(getIssueMarkerOwner(issues[0].Body)).To(Equal(issueMarkerOwner(githubIssue)))
+
+	// the issue is found again instead of being created twice
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(server.Issues(testOwnerName, testRepoName)).To(HaveLen(1))
+
+	// a rate limited reconcile fails without changing the issue
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	githubIssueReconciled.Spec.Description = "updated-" + githubIssue.Spec.Description
+	g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+
+	server.SetRateLimit(100, 0, time.Now().Add(time.Hour))
+	_, err = r.Reconcile(ctx, req)
+	var rateLimitErr *github.RateLimitError
+	g.Expect(goerrors.As(err, &rateLimitErr)).To(BeTrue())
+
+	server.SetRateLimit(100, 100, time.Now().Add(time.Hour))
+	r.GithubClient = server.Client()
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	issue, _ := server.Issue(testOwnerName, testRepoName, 1)
+	g.Expect(stripIssueMarker(issue.Body)).To(Equal(githubIssueReconciled.Spec.Description))
+
+	// the issue is closed when the object is deleted
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(cl.Delete(ctx, &githubIssueReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	issue, _ = server.Issue(testOwnerName, testRepoName, 1)
+	g.Expect(issue.State).To(Equal("closed"))
+}
+
+func TestDryRunPlansChanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Labels = []string{"bug"}
+	githubIssue.Annotations = map[string]string{trainingv1alpha1.DryRunAnnotation: "true"}
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	recorder := record.NewFakeRecorder(10)
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: server.Client(), Recorder: recorder}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+
+	// only read requests are sent to github in dry-run mode
+	mutatingRequests := func() []string {
+		var mutating []string
+		for _, request := range server.Requests() {
+			if !strings.HasPrefix(request, http.MethodGet+" ") {
+				mutating = append(mutating, request)
+			}
+		}
+		return mutating
+	}
+
+	// the creation of a missing issue is planned
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(server.Issues(testOwnerName, testRepoName)).To(BeEmpty())
+	g.Expect(mutatingRequests()).To(BeEmpty())
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	planned := apimeta.FindStatusCondition(githubIssueReconciled.Status.Conditions, plannedConditionType)
+	g.Expect(planned).ToNot(BeNil())
+	g.Expect(planned.Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(planned.Message).To(ContainSubstring(fmt.Sprintf("create issue %q", githubIssue.Spec.Title)))
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(BeZero())
+	g.Expect(<-recorder.Events).To(ContainSubstring("create issue"))
+
+	// the changes to an existing issue are planned without changing it
+	server.AddIssue(testOwnerName, testRepoName, githubfake.Issue{
+		Title: githubIssue.Spec.Title,
+		Body:  githubIssue.Spec.Description,
+		State: "open",
+	})
+	githubIssueReconciled.Spec.State = "closed"
+	g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(mutatingRequests()).To(BeEmpty())
+	issue, _ := server.Issue(testOwnerName, testRepoName, 1)
+	g.Expect(issue.State).To(Equal("open"))
+	g.Expect(issue.Labels).To(BeEmpty())
+
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	planned = apimeta.FindStatusCondition(githubIssueReconciled.Status.Conditions, plannedConditionType)
+	g.Expect(planned.Message).To(ContainSubstring("set the labels of issue #1 to [bug]"))
+	g.Expect(planned.Message).To(ContainSubstring("close issue #1"))
+	g.Expect(recorder.Events).To(HaveLen(2))
+
+	// the plan is applied and the condition removed once dry-run mode is turned off
+	delete(githubIssueReconciled.Annotations, trainingv1alpha1.DryRunAnnotation)
+	g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	issue, _ = server.Issue(testOwnerName, testRepoName, 1)
+	g.Expect(issue.State).To(Equal("closed"))
+	g.Expect(issue.Labels).To(Equal([]string{"bug"}))
+
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(apimeta.FindStatusCondition(githubIssueReconciled.Status.Conditions, plannedConditionType)).To(BeNil())
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(Equal(1))
+
+	// a reconciler in dry-run mode plans the changes of every object
+	r.DryRun = true
+	githubIssueReconciled.Spec.State = "open"
+	g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	issue, _ = server.Issue(testOwnerName, testRepoName, 1)
+	g.Expect(issue.State).To(Equal("closed"))
+
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	planned = apimeta.FindStatusCondition(githubIssueReconciled.Status.Conditions, plannedConditionType)
+	g.Expect(planned.Message).To(Equal(plannedMessagePrefix + "reopen issue #1"))
+}
+
+func TestPausedIssueIsNotChanged(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Labels = []string{"bug"}
+	githubIssue.Annotations = map[string]string{trainingv1alpha1.PausedAnnotation: "true"}
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+
+	// the issue of a paused object is not created
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(server.Issues(testOwnerName, testRepoName)).To(BeEmpty())
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, pausedConditionType)).To(BeTrue())
+
+	// an existing issue is reported in the status without being changed
+	server.AddIssue(testOwnerName, testRepoName, githubfake.Issue{
+		Title: githubIssue.Spec.Title,
+		Body:  "changed during an incident",
+		State: "closed",
+	})
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, request := range server.Requests() {
+		g.Expect(request).To(HavePrefix(http.MethodGet + " "))
+	}
+	issue, _ := server.Issue(testOwnerName, testRepoName, 1)
+	g.Expect(issue.Body).To(Equal("changed during an incident"))
+	g.Expect(issue.Labels).To(BeEmpty())
+
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(Equal(1))
+	g.Expect(githubIssueReconciled.Status.ActiveDescription).To(Equal("changed during an incident"))
+	g.Expect(apimeta.IsStatusConditionFalse(githubIssueReconciled.Status.Conditions, issueOpenConditionType)).To(BeTrue())
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, pausedConditionType)).To(BeTrue())
+
+	// the issue is synced again once the object is resumed
+	delete(githubIssueReconciled.Annotations, trainingv1alpha1.PausedAnnotation)
+	g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	issue, _ = server.Issue(testOwnerName, testRepoName, 1)
+	g.Expect(stripIssueMarker(issue.Body)).To(Equal(githubIssue.Spec.Description))
+	g.Expect(issue.Labels).To(Equal([]string{"bug"}))
+
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(apimeta.FindStatusCondition(githubIssueReconciled.Status.Conditions, pausedConditionType)).To(BeNil())
+}
+
+func TestDeletePausedIssueClosesItOnResume(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	for _, annotation := range []string{trainingv1alpha1.PausedAnnotation, trainingv1alpha1.DryRunAnnotation} {
+		server := githubfake.NewServer()
+		defer server.Close()
+		server.AddRepo(testOwnerName, testRepoName)
+
+		githubIssue := GenerateGithubIssueObject()
+		githubIssue.Annotations = map[string]string{annotation: "true"}
+		githubIssue.Finalizers = []string{ghIssueFinalizer}
+		server.AddIssue(testOwnerName, testRepoName, githubfake.Issue{
+			Title: githubIssue.Spec.Title,
+			Body:  githubIssue.Spec.Description,
+			State: "open",
+		})
+
+		obj := []client.Object{githubIssue}
+		cl, s, err := SetupClient(obj)
+		g.Expect(err).ToNot(HaveOccurred())
+
+		r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+
+		req := reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      githubIssue.ObjectMeta.Name,
+				Namespace: githubIssue.ObjectMeta.Namespace,
+			},
+		}
+
+		// the deleted object is kept and reports the close of its issue
+		g.Expect(cl.Delete(ctx, githubIssue)).To(Succeed())
+		_, err = r.Reconcile(ctx, req)
+		g.Expect(err).ToNot(HaveOccurred())
+		for _, request := range server.Requests() {
+			g.Expect(request).To(HavePrefix(http.MethodGet + " "))
+		}
+		issue, _ := server.Issue(testOwnerName, testRepoName, 1)
+		g.Expect(issue.State).To(Equal("open"))
+
+		githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+		g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+		g.Expect(githubIssueReconciled.Finalizers).To(ContainElement(ghIssueFinalizer))
+		conditionType := pausedConditionType
+		if annotation == trainingv1alpha1.DryRunAnnotation {
+			conditionType = plannedConditionType
+		}
+		condition := apimeta.FindStatusCondition(githubIssueReconciled.Status.Conditions, conditionType)
+		g.Expect(condition).ToNot(BeNil())
+		g.Expect(condition.Message).To(ContainSubstring("close issue #1"))
+
+		// the issue is closed and the object let go once it is resumed
+		delete(githubIssueReconciled.Annotations, annotation)
+		g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+
+		_, err = r.Reconcile(ctx, req)
+		g.Expect(err).ToNot(HaveOccurred())
+		issue, _ = server.Issue(testOwnerName, testRepoName, 1)
+		g.Expect(issue.State).To(Equal("closed"))
+
+		err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+		g.Expect(errors.IsNotFound(err)).To(BeTrue())
+	}
+}
+
+func TestDeletePausedObjectOfClosedIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Annotations = map[string]string{trainingv1alpha1.PausedAnnotation: "true"}
+	githubIssue.Finalizers = []string{ghIssueFinalizer}
+	server.AddIssue(testOwnerName, testRepoName, githubfake.Issue{
+		Title: githubIssue.Spec.Title,
+		Body:  githubIssue.Spec.Description,
+		State: "closed",
+	})
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+
+	// the issue is already closed, so nothing is planned and the object is let go
+	g.Expect(cl.Delete(ctx, githubIssue)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, request := range server.Requests() {
+		g.Expect(request).To(HavePrefix(http.MethodGet + " "))
+	}
+
+	err = cl.Get(ctx, req.NamespacedName, &trainingv1alpha1.GithubIssue{})
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestDeleteIssueWithDryRunFlag(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Finalizers = []string{ghIssueFinalizer}
+	server.AddIssue(testOwnerName, testRepoName, githubfake.Issue{
+		Title: githubIssue.Spec.Title,
+		Body:  githubIssue.Spec.Description,
+		State: "open",
+	})
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	recorder := record.NewFakeRecorder(10)
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, GithubClient: server.Client(), Recorder: recorder, DryRun: true}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+
+	// the flag may stay set for good, so the deleted object is let go with the issue left open
+	g.Expect(cl.Delete(ctx, githubIssue)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, request := range server.Requests() {
+		g.Expect(request).To(HavePrefix(http.MethodGet + " "))
+	}
+	issue, _ := server.Issue(testOwnerName, testRepoName, 1)
+	g.Expect(issue.State).To(Equal("open"))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("close issue #1")))
+
+	err = cl.Get(ctx, req.NamespacedName, &trainingv1alpha1.GithubIssue{})
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestReconcileTriggerPredicate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.ResourceVersion = "1"
+	githubIssue.Generation = 1
+
+	updated := func(update func(*trainingv1alpha1.GithubIssue)) event.UpdateEvent {
+		newGithubIssue := githubIssue.DeepCopy()
+		newGithubIssue.ResourceVersion = "2"
+		update(newGithubIssue)
+		return event.UpdateEvent{ObjectOld: githubIssue, ObjectNew: newGithubIssue}
+	}
+
+	p := reconcileTriggerPredicate()
+
+	// the periodic resync and changes of the spec trigger a reconcile
+	g.Expect(p.Update(event.UpdateEvent{ObjectOld: githubIssue, ObjectNew: githubIssue.DeepCopy()})).To(BeTrue())
+	g.Expect(p.Update(updated(func(gi *trainingv1alpha1.GithubIssue) { gi.Generation = 2 }))).To(BeTrue())
+
+	// status updates and unrelated annotations don't
+	g.Expect(p.Update(updated(func(gi *trainingv1alpha1.GithubIssue) { gi.Status.IssueNumber = 1 }))).To(BeFalse())
+	g.Expect(p.Update(updated(func(gi *trainingv1alpha1.GithubIssue) {
+		gi.Annotations = map[string]string{"example.com/note": "x"}
+	}))).To(BeFalse())
+
+	// changes of the trigger annotations do
+	for _, annotation := range []string{trainingv1alpha1.ReconcileAtAnnotation, trainingv1alpha1.PausedAnnotation, trainingv1alpha1.DryRunAnnotation} {
+		g.Expect(p.Update(updated(func(gi *trainingv1alpha1.GithubIssue) {
+			gi.Annotations = map[string]string{annotation: "true"}
+		}))).To(BeTrue())
+	}
+
+	// and so does the deletion of the object
+	g.Expect(p.Update(updated(func(gi *trainingv1alpha1.GithubIssue) {
+		now := metav1.Now()
+		gi.DeletionTimestamp = &now
+	}))).To(BeTrue())
 }
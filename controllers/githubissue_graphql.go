@@ -0,0 +1,383 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shurcooL/githubv4"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// UpdateIssueIssueTypeInput is an autogenerated input type of UpdateIssueIssueType,
+// it is defined here since it is not part of the githubv4 package yet
+type UpdateIssueIssueTypeInput struct {
+	// The ID of the issue to update. (Required.)
+	IssueID githubv4.ID `json:"issueId"`
+	// The ID of the issue type to set on the issue. (Required.)
+	IssueTypeID githubv4.ID `json:"issueTypeId"`
+}
+
+// this function transfers the issue to the repository in the spec of the object. the new
+// number of the issue is recorded in the status before the repository in the spec is
+// changed, so a transfer whose spec update failed is completed by the next reconcile.
+// an issue which is already in the target repository, i.e. a transfer that succeeded
+// but could not be recorded, is not transferred again
+func (r *GithubIssueReconciler) handleIssueTransfer(ctx context.Context, issue *Issue, githubissue *trainingv1alpha1.GithubIssue, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	ghV4Client := r.GithubV4Client
+	if ghV4Client == nil {
+		return fmt.Errorf("github graphql client is not available")
+	}
+
+	transferTo := githubissue.Spec.TransferTo
+	targetOwner, targetRepo, err := parseOwnerRepo(transferTo)
+	if err != nil {
+		return err
+	}
+	issueNumber := issue.Number
+
+	alreadyTransferred := strings.HasSuffix(strings.ToLower(issue.RepositoryURL), strings.ToLower("/repos/"+targetOwner+"/"+targetRepo))
+	if !alreadyTransferred && (!strings.EqualFold(owner, targetOwner) || !strings.EqualFold(repo, targetRepo)) {
+		log.Info("Transferring issue", "issue", issueNumber, "owner", targetOwner, "repo", targetRepo)
+		transferredNumber, err := r.transferIssue(ctx, ghV4Client, issue.NodeID, targetOwner, targetRepo)
+		if err != nil {
+			return err
+		}
+		issueNumber = transferredNumber
+	}
+
+	githubissue.Status.IssueNumber = issueNumber
+	apimeta.SetStatusCondition(&githubissue.Status.Conditions, metav1.Condition{
+		Type:               issueTransferredConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             issueTransferredConditionReason,
+		Message:            fmt.Sprintf("The issue was transferred from %s to %s", githubissue.Spec.Repo, transferTo),
+		ObservedGeneration: githubissue.Generation,
+	})
+	if err := r.Status().Update(ctx, githubissue); err != nil {
+		log.Error(err, "unable to update githubissue status")
+		return err
+	}
+
+	return r.completeIssueTransfer(ctx, githubissue)
+}
+
+// this function checks whether the transfer of the issue of an object was recorded
+// in its status, while the repository in its spec was not changed yet
+func isIssueTransferPending(githubissue *trainingv1alpha1.GithubIssue) bool {
+	if githubissue.Spec.TransferTo == "" {
+		return false
+	}
+
+	transferred := apimeta.FindStatusCondition(githubissue.Status.Conditions, issueTransferredConditionType)
+	return transferred != nil && transferred.Status == metav1.ConditionTrue && transferred.ObservedGeneration == githubissue.Generation
+}
+
+// this function points the spec of an object at the repository its issue was transferred
+// to. the issue is tracked by its number in the status, so a number requested for adoption
+// is dropped since it refers to an issue of the repository the issue was transferred from
+func (r *GithubIssueReconciler) completeIssueTransfer(ctx context.Context, githubissue *trainingv1alpha1.GithubIssue) error {
+	log := log.FromContext(ctx)
+
+	githubissue.Spec.Repo = githubissue.Spec.TransferTo
+	githubissue.Spec.TransferTo = ""
+	githubissue.Spec.IssueNumber = 0
+	if err := r.Update(ctx, githubissue); err != nil {
+		log.Error(err, "failed to update githubissue")
+		return err
+	}
+
+	return nil
+}
+
+// this function pins or unpins the issue and sets its type according to the spec,
+// and records the state of the issue in the status of the object
+func (r *GithubIssueReconciler) syncIssueGraphQLFields(ctx context.Context, issue *Issue, githubissue *trainingv1alpha1.GithubIssue, owner, repo string) error {
+	ghV4Client := r.GithubV4Client
+	if ghV4Client == nil {
+		return fmt.Errorf("github graphql client is not available")
+	}
+
+	issueID := issue.NodeID
+	if pinned := githubissue.Spec.Pinned; pinned != nil {
+		isPinned, err := r.isIssuePinned(ctx, ghV4Client, issue.Number, owner, repo)
+		if err != nil {
+			return err
+		}
+
+		if *pinned != isPinned {
+			if err := r.setIssuePinned(ctx, ghV4Client, issueID, *pinned); err != nil {
+				return err
+			}
+		}
+		githubissue.Status.Pinned = *pinned
+	}
+
+	if issueType := githubissue.Spec.IssueType; issueType != "" {
+		if err := r.setIssueType(ctx, ghV4Client, issueID, issue.Number, issueType, owner, repo); err != nil {
+			return err
+		}
+		githubissue.Status.IssueType = issueType
+	}
+
+	return nil
+}
+
+// this function returns whether an issue is pinned in its repository
+func (r *GithubIssueReconciler) isIssuePinned(ctx context.Context, ghV4Client *githubv4.Client, issueNumber int, owner, repo string) (bool, error) {
+	var query struct {
+		Repository struct {
+			Issue struct {
+				IsPinned bool
+			} `graphql:"issue(number: $number)"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+
+	variables := map[string]interface{}{
+		"owner":  githubv4.String(owner),
+		"name":   githubv4.String(repo),
+		"number": githubv4.Int(issueNumber),
+	}
+
+	if err := ghV4Client.Query(ctx, &query, variables); err != nil {
+		return false, err
+	}
+
+	return query.Repository.Issue.IsPinned, nil
+}
+
+// this function pins or unpins an issue in its repository
+func (r *GithubIssueReconciler) setIssuePinned(ctx context.Context, ghV4Client *githubv4.Client, issueID string, pinned bool) error {
+	if pinned {
+		var mutation struct {
+			PinIssue struct {
+				Issue struct {
+					ID githubv4.ID
+				}
+			} `graphql:"pinIssue(input: $input)"`
+		}
+		return ghV4Client.Mutate(ctx, &mutation, githubv4.PinIssueInput{IssueID: issueID}, nil)
+	}
+
+	var mutation struct {
+		UnpinIssue struct {
+			Issue struct {
+				ID githubv4.ID
+			}
+		} `graphql:"unpinIssue(input: $input)"`
+	}
+	return ghV4Client.Mutate(ctx, &mutation, githubv4.UnpinIssueInput{IssueID: issueID}, nil)
+}
+
+// this function sets the type of an issue by the name of the issue type, the issue
+// is left untouched if it already has the requested type
+func (r *GithubIssueReconciler) setIssueType(ctx context.Context, ghV4Client *githubv4.Client, issueID string, issueNumber int, issueType, owner, repo string) error {
+	var query struct {
+		Repository struct {
+			Issue struct {
+				IssueType *struct {
+					Name string
+				}
+			} `graphql:"issue(number: $number)"`
+			IssueTypes struct {
+				Nodes []struct {
+					ID   githubv4.ID
+					Name string
+				}
+			} `graphql:"issueTypes(first: 50)"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+
+	variables := map[string]interface{}{
+		"owner":  githubv4.String(owner),
+		"name":   githubv4.String(repo),
+		"number": githubv4.Int(issueNumber),
+	}
+
+	if err := ghV4Client.Query(ctx, &query, variables); err != nil {
+		return err
+	}
+
+	if current := query.Repository.Issue.IssueType; current != nil && strings.EqualFold(current.Name, issueType) {
+		return nil
+	}
+
+	for _, node := range query.Repository.IssueTypes.Nodes {
+		if !strings.EqualFold(node.Name, issueType) {
+			continue
+		}
+
+		var mutation struct {
+			UpdateIssueIssueType struct {
+				Issue struct {
+					ID githubv4.ID
+				}
+			} `graphql:"updateIssueIssueType(input: $input)"`
+		}
+		return ghV4Client.Mutate(ctx, &mutation, UpdateIssueIssueTypeInput{IssueID: issueID, IssueTypeID: node.ID}, nil)
+	}
+
+	return fmt.Errorf("issue type %q does not exist in repository %s/%s", issueType, owner, repo)
+}
+
+// this function transfers an issue to another repository and returns
+// the number of the issue in the repository it was transferred to
+func (r *GithubIssueReconciler) transferIssue(ctx context.Context, ghV4Client *githubv4.Client, issueID, owner, repo string) (int, error) {
+	var query struct {
+		Repository struct {
+			ID githubv4.ID
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+
+	variables := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+	}
+
+	if err := ghV4Client.Query(ctx, &query, variables); err != nil {
+		return 0, err
+	}
+
+	var mutation struct {
+		TransferIssue struct {
+			Issue struct {
+				Number int
+			}
+		} `graphql:"transferIssue(input: $input)"`
+	}
+
+	input := githubv4.TransferIssueInput{
+		IssueID:      issueID,
+		RepositoryID: query.Repository.ID,
+	}
+
+	if err := ghV4Client.Mutate(ctx, &mutation, input, nil); err != nil {
+		return 0, err
+	}
+
+	return mutation.TransferIssue.Issue.Number, nil
+}
+
+// this function recreates the issue as a discussion in the category requested by the object. the
+// discussion is created with the title and description of the issue, which is then closed with a
+// comment linking to the discussion. the comments, reactions and history of the issue stay on the
+// issue. the URL of the discussion is recorded in the status as soon as it is created, so that a
+// recreation which failed afterwards doesn't create it twice
+func (r *GithubIssueReconciler) recreateIssueAsDiscussion(ctx context.Context, tracker IssueTracker, issue *Issue, githubissue *trainingv1alpha1.GithubIssue, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	ghV4Client := r.GithubV4Client
+	if ghV4Client == nil {
+		return fmt.Errorf("github graphql client is not available")
+	}
+
+	if githubissue.Status.DiscussionURL == "" {
+		category := githubissue.Spec.RecreateAsDiscussion.Category
+		log.Info("Recreating issue as a discussion", "issue", issue.Number, "category", category)
+		body := fmt.Sprintf("%s\n\n_Recreated from issue #%d_", stripIssueMarker(issue.Body), issue.Number)
+		discussionURL, err := r.createDiscussion(ctx, ghV4Client, issue.Title, body, category, owner, repo)
+		if err != nil {
+			return err
+		}
+
+		githubissue.Status.DiscussionURL = discussionURL
+		if err := r.Status().Update(ctx, githubissue); err != nil {
+			log.Error(err, "unable to update githubissue status")
+			return err
+		}
+	}
+
+	if issue.State != issueStateClosed {
+		comment := fmt.Sprintf("This issue was recreated as a discussion: %s", githubissue.Status.DiscussionURL)
+		if err := tracker.Comment(ctx, owner, repo, issue.Number, comment); err != nil {
+			log.Error(err, "failed to comment on issue", "owner", owner, "repo", repo, "issue", issue.Number)
+			return err
+		}
+		if err := r.closeIssue(ctx, tracker, issue.Number, owner, repo); err != nil {
+			return err
+		}
+		issue.State = issueStateClosed
+	}
+
+	apimeta.SetStatusCondition(&githubissue.Status.Conditions, metav1.Condition{
+		Type:    issueRecreatedConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  issueRecreatedConditionReason,
+		Message: fmt.Sprintf("The issue was recreated as the discussion %s", githubissue.Status.DiscussionURL),
+	})
+	return nil
+}
+
+// this function creates a discussion in a category of a repository and returns its URL
+func (r *GithubIssueReconciler) createDiscussion(ctx context.Context, ghV4Client *githubv4.Client, title, body, category, owner, repo string) (string, error) {
+	var query struct {
+		Repository struct {
+			ID                   githubv4.ID
+			DiscussionCategories struct {
+				Nodes []struct {
+					ID   githubv4.ID
+					Name string
+				}
+			} `graphql:"discussionCategories(first: 50)"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+
+	variables := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(repo),
+	}
+
+	if err := ghV4Client.Query(ctx, &query, variables); err != nil {
+		return "", err
+	}
+
+	for _, node := range query.Repository.DiscussionCategories.Nodes {
+		if !strings.EqualFold(node.Name, category) {
+			continue
+		}
+
+		var mutation struct {
+			CreateDiscussion struct {
+				Discussion struct {
+					URL string
+				}
+			} `graphql:"createDiscussion(input: $input)"`
+		}
+
+		input := githubv4.CreateDiscussionInput{
+			RepositoryID: query.Repository.ID,
+			CategoryID:   node.ID,
+			Title:        githubv4.String(title),
+			Body:         githubv4.String(body),
+		}
+
+		if err := ghV4Client.Mutate(ctx, &mutation, input, nil); err != nil {
+			return "", err
+		}
+		return mutation.CreateDiscussion.Discussion.URL, nil
+	}
+
+	return "", fmt.Errorf("discussion category %q does not exist in repository %s/%s", category, owner, repo)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// this function adds the issue to the milestone referenced by the object,
+// the issue is updated in place to reflect the milestone on github
+func (r *GithubIssueReconciler) syncIssueMilestone(ctx context.Context, tracker IssueTracker, issue *Issue, githubissue *trainingv1alpha1.GithubIssue, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	number, err := r.resolveMilestoneRef(ctx, githubissue, owner, repo)
+	if err != nil {
+		log.Error(err, "unable to resolve milestone of githubissue", "milestone", githubissue.Spec.MilestoneRef.Name)
+		return err
+	}
+
+	if issue.Milestone == number {
+		return nil
+	}
+
+	issueRequest := IssueRequest{
+		Milestone: &number,
+	}
+
+	updated, err := tracker.Update(ctx, owner, repo, issue.Number, &issueRequest)
+	if err != nil {
+		log.Error(err, "unable to update issue milestone")
+		return err
+	}
+	setIssueUpdatedAt(issue, updated)
+
+	issue.Milestone = number
+	return nil
+}
+
+// this function returns the number of the milestone referenced by the object, the
+// milestone has to be created already and belong to the repository of the issue
+func (r *GithubIssueReconciler) resolveMilestoneRef(ctx context.Context, githubissue *trainingv1alpha1.GithubIssue, owner, repo string) (int, error) {
+	if githubissue.Namespace == "" {
+		return 0, fmt.Errorf("milestoneRef is not supported for cluster-scoped objects")
+	}
+
+	var githubmilestone trainingv1alpha1.GithubMilestone
+	key := types.NamespacedName{Name: githubissue.Spec.MilestoneRef.Name, Namespace: githubissue.Namespace}
+	if err := r.Get(ctx, key, &githubmilestone); err != nil {
+		return 0, err
+	}
+
+	milestoneOwner, milestoneRepo, err := parseOwnerRepo(githubmilestone.Spec.Repo)
+	if err != nil {
+		return 0, err
+	}
+	if !strings.EqualFold(milestoneOwner, owner) || !strings.EqualFold(milestoneRepo, repo) {
+		return 0, fmt.Errorf("milestone %s belongs to repository %s/%s and not to %s/%s", key.Name, milestoneOwner, milestoneRepo, owner, repo)
+	}
+
+	if githubmilestone.Status.MilestoneNumber == 0 {
+		return 0, fmt.Errorf("milestone %s was not created yet", key.Name)
+	}
+
+	return githubmilestone.Status.MilestoneNumber, nil
+}
+
+// this function maps a GithubMilestone to the requests of the objects in
+// its namespace which reference it, so they are synced once it is created
+func (r *GithubIssueReconciler) findGithubIssuesForMilestone(obj client.Object) []reconcile.Request {
+	var githubissues trainingv1alpha1.GithubIssueList
+	if err := r.List(context.Background(), &githubissues, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, githubissue := range githubissues.Items {
+		if ref := githubissue.Spec.MilestoneRef; ref != nil && ref.Name == obj.GetName() && r.Scope.Contains(&githubissue) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: githubissue.Name, Namespace: githubissue.Namespace},
+			})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+const (
+	pausedConditionType   string = "Paused"
+	pausedConditionReason string = "ReconcilePaused"
+)
+
+// triggerAnnotations are the annotations which change how an object is
+// reconciled, so a change of any of them triggers a reconcile
+var triggerAnnotations = []string{
+	trainingv1alpha1.ReconcileAtAnnotation,
+	trainingv1alpha1.PausedAnnotation,
+	trainingv1alpha1.DryRunAnnotation,
+}
+
+// this function checks whether the reconciliation of an object is paused
+func isPaused(obj client.Object) bool {
+	return obj.GetAnnotations()[trainingv1alpha1.PausedAnnotation] == "true"
+}
+
+// this function reports the state of the issue of a paused object, the issue is
+// neither created nor changed and the object is not synced with it
+func (r *GithubIssueReconciler) updatePausedStatus(ctx context.Context, issue *Issue, githubissue *trainingv1alpha1.GithubIssue) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconcile of githubissue is paused, not changing the issue")
+
+	message := "The issue is not changed while the object is paused"
+	if issue != nil {
+		githubissue.Status.ActiveTitle = issue.Title
+		githubissue.Status.ActiveDescription = stripIssueMarker(issue.Body)
+		githubissue.Status.IssueNumber = issue.Number
+		githubissue.Status.Milestone = issue.Milestone
+		githubissue.Status.Locked = issue.Locked
+		githubissue.Status.LockReason = issue.LockReason
+		r.setIssueOpenCondition(issue, githubissue)
+		r.setIssueHasPRCondition(issue, githubissue)
+	} else {
+		message = "The issue is not created while the object is paused"
+	}
+	r.setPausedCondition(githubissue, metav1.ConditionTrue, pausedConditionReason, message)
+
+	if err := r.Status().Update(ctx, githubissue); err != nil {
+		log.Error(err, "unable to update githubissue status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// this function sets the condition of the object that indicates whether its reconciliation is paused
+func (r *GithubIssueReconciler) setPausedCondition(githubissue *trainingv1alpha1.GithubIssue, status metav1.ConditionStatus, reason, message string) {
+	condition := metav1.Condition{
+		Type:    pausedConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+
+	apimeta.SetStatusCondition(&githubissue.Status.Conditions, condition)
+}
+
+// reconcileTriggerPredicate filters out the updates of objects which don't need a reconcile,
+// e.g. the status updates of the reconciler. An object is reconciled when its spec or its labels
+// change, when it is deleted, when a trigger annotation changes and on the periodic resync
+func reconcileTriggerPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return true
+			}
+
+			// the periodic resync sends updates of objects which didn't change
+			if e.ObjectOld.GetResourceVersion() == e.ObjectNew.GetResourceVersion() {
+				return true
+			}
+
+			if e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() {
+				return true
+			}
+			if e.ObjectOld.GetDeletionTimestamp().IsZero() != e.ObjectNew.GetDeletionTimestamp().IsZero() {
+				return true
+			}
+			if !labels.Equals(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) {
+				return true
+			}
+
+			oldAnnotations, newAnnotations := e.ObjectOld.GetAnnotations(), e.ObjectNew.GetAnnotations()
+			for _, annotation := range triggerAnnotations {
+				if oldAnnotations[annotation] != newAnnotations[annotation] {
+					return true
+				}
+			}
+
+			return false
+		},
+	}
+}
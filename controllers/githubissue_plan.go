@@ -0,0 +1,254 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+const (
+	plannedConditionType            string = "Planned"
+	changesPlannedConditionReason   string = "ChangesPlanned"
+	noChangesPlannedConditionReason string = "NoChangesPlanned"
+
+	// plannedEventReason is the reason of the events listing the planned changes
+	plannedEventReason   string = "Planned"
+	plannedMessagePrefix string = "Dry run, the operator would "
+)
+
+// planningTracker is an issue tracker which reads issues from the tracker it wraps
+// and records the changes it is asked to make instead of making them, it lets the
+// reconciler compute what it would do to the issues of a repository in dry-run mode
+type planningTracker struct {
+	IssueTracker
+
+	// Actions are the changes the reconciler asked for, in the order it asked for them
+	Actions []string
+}
+
+// Create records the creation of an issue and returns the issue that would be created
+func (t *planningTracker) Create(ctx context.Context, owner, repo string, request *IssueRequest) (*Issue, error) {
+	issue := &Issue{State: issueStateOpen}
+	applyIssueRequest(issue, request)
+	t.record("create issue %q in %s/%s", issue.Title, owner, repo)
+	return issue, nil
+}
+
+// Update records the changes of the request to an issue
+func (t *planningTracker) Update(ctx context.Context, owner, repo string, number int, request *IssueRequest) (*Issue, error) {
+	issue := plannedIssueRef(number)
+	if request.Title != nil {
+		t.record("edit the title of %s to %q", issue, *request.Title)
+	}
+	if request.Body != nil {
+		t.record("edit the description of %s", issue)
+	}
+	if request.Labels != nil {
+		t.record("set the labels of %s to [%s]", issue, strings.Join(*request.Labels, ", "))
+	}
+	if request.Assignees != nil {
+		t.record("set the assignees of %s to [%s]", issue, strings.Join(*request.Assignees, ", "))
+	}
+	if request.Milestone != nil {
+		t.record("set the milestone of %s to %d", issue, *request.Milestone)
+	}
+	if request.State != nil {
+		if *request.State == issueStateClosed {
+			t.record("close %s", issue)
+		} else {
+			t.record("reopen %s", issue)
+		}
+	}
+
+	return &Issue{Number: number}, nil
+}
+
+// Close records the closing of an issue
+func (t *planningTracker) Close(ctx context.Context, owner, repo string, number int) error {
+	t.record("close %s", plannedIssueRef(number))
+	return nil
+}
+
+// Comment records a comment on an issue
+func (t *planningTracker) Comment(ctx context.Context, owner, repo string, number int, body string) error {
+	t.record("comment on %s", plannedIssueRef(number))
+	return nil
+}
+
+// Lock records the locking of the conversation of an issue
+func (t *planningTracker) Lock(ctx context.Context, owner, repo string, number int, reason string) error {
+	if reason != "" {
+		t.record("lock %s as %s", plannedIssueRef(number), reason)
+	} else {
+		t.record("lock %s", plannedIssueRef(number))
+	}
+	return nil
+}
+
+// Unlock records the unlocking of the conversation of an issue
+func (t *planningTracker) Unlock(ctx context.Context, owner, repo string, number int) error {
+	t.record("unlock %s", plannedIssueRef(number))
+	return nil
+}
+
+// this function appends an action to the planned actions
+func (t *planningTracker) record(format string, args ...interface{}) {
+	t.Actions = append(t.Actions, fmt.Sprintf(format, args...))
+}
+
+// this function returns how a planned action refers to an issue, the
+// issue which would be created has no number yet
+func plannedIssueRef(number int) string {
+	if number == 0 {
+		return "the new issue"
+	}
+	return fmt.Sprintf("issue #%d", number)
+}
+
+// this function sets the fields of a request on an issue
+func applyIssueRequest(issue *Issue, request *IssueRequest) {
+	if request.Title != nil {
+		issue.Title = *request.Title
+	}
+	if request.Body != nil {
+		issue.Body = *request.Body
+	}
+	if request.Labels != nil {
+		issue.Labels = *request.Labels
+	}
+	if request.Assignees != nil {
+		issue.Assignees = *request.Assignees
+	}
+	if request.Milestone != nil {
+		issue.Milestone = *request.Milestone
+	}
+	if request.State != nil {
+		issue.State = *request.State
+	}
+}
+
+// this function checks whether an object is reconciled in dry-run mode, either
+// because the reconciler runs in dry-run mode or the object requests it
+func (r *GithubIssueReconciler) isDryRun(githubissue *trainingv1alpha1.GithubIssue) bool {
+	return r.DryRun || githubissue.Annotations[trainingv1alpha1.DryRunAnnotation] == "true"
+}
+
+// this function reports the changes planned in dry-run mode, an event is emitted for
+// every action and the Planned condition of the object lists all of them
+func (r *GithubIssueReconciler) reportPlan(githubissue *trainingv1alpha1.GithubIssue, plan *planningTracker) {
+	r.emitPlanEvents(githubissue, plan)
+
+	if len(plan.Actions) == 0 {
+		r.setPlannedCondition(githubissue, metav1.ConditionFalse, noChangesPlannedConditionReason, "Dry run, the issue is in sync with the object")
+		return
+	}
+	message := plannedMessagePrefix + strings.Join(plan.Actions, "; ")
+	r.setPlannedCondition(githubissue, metav1.ConditionTrue, changesPlannedConditionReason, message)
+}
+
+// this function emits an event for every action planned in dry-run mode
+func (r *GithubIssueReconciler) emitPlanEvents(githubissue *trainingv1alpha1.GithubIssue, plan *planningTracker) {
+	if r.Recorder == nil {
+		return
+	}
+	for _, action := range plan.Actions {
+		r.Recorder.Event(githubissue, corev1.EventTypeNormal, plannedEventReason, plannedMessagePrefix+action)
+	}
+}
+
+// this function sets the condition which lists the changes planned in dry-run mode
+func (r *GithubIssueReconciler) setPlannedCondition(githubissue *trainingv1alpha1.GithubIssue, status metav1.ConditionStatus, reason, message string) {
+	condition := metav1.Condition{
+		Type:    plannedConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+
+	apimeta.SetStatusCondition(&githubissue.Status.Conditions, condition)
+}
+
+// this function reports the changes planned in dry-run mode in the status of an object
+func (r *GithubIssueReconciler) updatePlannedStatus(ctx context.Context, githubissue *trainingv1alpha1.GithubIssue, plan *planningTracker) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Dry run, not changing the issue", "actions", plan.Actions)
+
+	r.reportPlan(githubissue, plan)
+	if err := r.Status().Update(ctx, githubissue); err != nil {
+		log.Error(err, "unable to update githubissue status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// this function holds back the deletion of an object with the dry-run annotation or while it is
+// paused, the finalizer is kept until the issue can be closed and the planned close is reported in
+// the status. an object without an issue to close is let go right away, and so is an object which
+// is only in dry-run mode because of the --dry-run flag, which may stay set for good, after its
+// planned close is reported in events without closing the issue
+func (r *GithubIssueReconciler) holdObjectDeletion(ctx context.Context, githubissue *trainingv1alpha1.GithubIssue, plan *planningTracker, paused bool) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(githubissue, ghIssueFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if _, err := r.closeIssueOfDeletedObject(ctx, plan, githubissue); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	dryRunByFlag := !paused && githubissue.Annotations[trainingv1alpha1.DryRunAnnotation] != "true"
+	if dryRunByFlag && len(plan.Actions) > 0 {
+		log.Info("Dry run, letting deleted githubissue go without closing its issue", "actions", plan.Actions)
+		r.emitPlanEvents(githubissue, plan)
+	}
+
+	if len(plan.Actions) == 0 || dryRunByFlag {
+		controllerutil.RemoveFinalizer(githubissue, ghIssueFinalizer)
+		if err := r.Update(ctx, githubissue); err != nil {
+			log.Error(err, "failed to update githubissue")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !paused {
+		return r.updatePlannedStatus(ctx, githubissue, plan)
+	}
+
+	log.Info("Deleted githubissue is paused, closing its issue once it is resumed", "actions", plan.Actions)
+	r.setPausedCondition(githubissue, metav1.ConditionTrue, pausedConditionReason,
+		"The object was deleted while paused, the operator will "+strings.Join(plan.Actions, "; ")+" once it is resumed")
+	if err := r.Status().Update(ctx, githubissue); err != nil {
+		log.Error(err, "unable to update githubissue status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// this function checks the object against the repository policies of its namespace and
+// records the result in the PolicyViolation condition, it returns whether the object may be synced
+func (r *GithubIssueReconciler) enforceRepoPolicies(ctx context.Context, githubissue *trainingv1alpha1.GithubIssue) (bool, error) {
+	violations, err := trainingv1alpha1.CheckRepoPolicies(ctx, r.Client, githubissue)
+	if err != nil {
+		return false, err
+	}
+
+	if len(violations) > 0 {
+		apimeta.SetStatusCondition(&githubissue.Status.Conditions, metav1.Condition{
+			Type:               policyViolationConditionType,
+			Status:             metav1.ConditionTrue,
+			Reason:             policyViolatedConditionReason,
+			Message:            strings.Join(violations, "; "),
+			ObservedGeneration: githubissue.Generation,
+		})
+		return false, nil
+	}
+
+	// the condition is only reported on objects which violated a policy before
+	if apimeta.FindStatusCondition(githubissue.Status.Conditions, policyViolationConditionType) != nil {
+		apimeta.SetStatusCondition(&githubissue.Status.Conditions, metav1.Condition{
+			Type:               policyViolationConditionType,
+			Status:             metav1.ConditionFalse,
+			Reason:             policySatisfiedConditionReason,
+			Message:            "The object satisfies the repository policies of its namespace",
+			ObservedGeneration: githubissue.Generation,
+		})
+	}
+
+	return true, nil
+}
+
+// this function maps a GithubRepoPolicy to the requests of the objects
+// in the namespaces it selects, so they are checked against it again
+func (r *GithubIssueReconciler) findGithubIssuesForRepoPolicy(obj client.Object) []reconcile.Request {
+	ctx := context.Background()
+	log := log.FromContext(ctx)
+
+	policy, ok := obj.(*trainingv1alpha1.GithubRepoPolicy)
+	if !ok {
+		return nil
+	}
+
+	var githubissues trainingv1alpha1.GithubIssueList
+	if err := r.List(ctx, &githubissues); err != nil {
+		log.Error(err, "unable to list githubissues of githubrepopolicy", "githubrepopolicy", obj.GetName())
+		return nil
+	}
+
+	selectedNamespaces := map[string]bool{}
+	var requests []reconcile.Request
+	for _, githubissue := range githubissues.Items {
+		selected, checked := selectedNamespaces[githubissue.Namespace]
+		if !checked {
+			var ns corev1.Namespace
+			if err := r.Get(ctx, types.NamespacedName{Name: githubissue.Namespace}, &ns); err != nil {
+				log.Error(err, "unable to fetch namespace of githubissue", "namespace", githubissue.Namespace)
+				continue
+			}
+			var err error
+			if selected, err = policy.Selects(&ns); err != nil {
+				log.Error(err, "invalid namespace selector of githubrepopolicy", "githubrepopolicy", policy.Name)
+				return nil
+			}
+			selectedNamespaces[githubissue.Namespace] = selected
+		}
+
+		if selected && r.Scope.Contains(&githubissue) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: githubissue.Name, Namespace: githubissue.Namespace},
+			})
+		}
+	}
+	return requests
+}
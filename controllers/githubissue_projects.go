@@ -0,0 +1,400 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// projectV2 holds the ID and the fields of a GitHub project
+type projectV2 struct {
+	ID     githubv4.ID
+	Fields struct {
+		Nodes []projectV2Field
+	} `graphql:"fields(first: 100)"`
+}
+
+// projectV2Field holds a field of a GitHub project along with its
+// options for single select fields and its iterations for iteration fields
+type projectV2Field struct {
+	Common struct {
+		ID       githubv4.ID
+		Name     string
+		DataType string
+	} `graphql:"... on ProjectV2FieldCommon"`
+	SingleSelect struct {
+		Options []struct {
+			ID   string
+			Name string
+		}
+	} `graphql:"... on ProjectV2SingleSelectField"`
+	Iteration struct {
+		Configuration struct {
+			Iterations []struct {
+				ID    string
+				Title string
+			}
+		}
+	} `graphql:"... on ProjectV2IterationField"`
+}
+
+// projectV2FieldName holds the name of the field of a project item value
+type projectV2FieldName struct {
+	Common struct {
+		Name string
+	} `graphql:"... on ProjectV2FieldCommon"`
+}
+
+// this function adds the issue to the projects in the spec of the object, keeps the values
+// of the fields of its project items in sync and removes the items of projects which were
+// removed from the spec. the items are recorded in the status of the object
+func (r *GithubIssueReconciler) syncIssueProjects(ctx context.Context, issue *Issue, githubissue *trainingv1alpha1.GithubIssue, owner string) error {
+	log := log.FromContext(ctx)
+
+	ghV4Client := r.GithubV4Client
+	if ghV4Client == nil {
+		return fmt.Errorf("github graphql client is not available")
+	}
+
+	var projectItems []trainingv1alpha1.ProjectItemStatus
+	for _, githubProject := range githubissue.Spec.Projects {
+		project, err := r.getProject(ctx, ghV4Client, githubProject, owner)
+		if err != nil {
+			return err
+		}
+		projectID := fmt.Sprint(project.ID)
+
+		// adding an issue which is already in the project returns its existing item
+		itemID, err := r.addProjectItem(ctx, ghV4Client, projectID, issue.NodeID)
+		if err != nil {
+			return err
+		}
+
+		if err := r.syncProjectItemFields(ctx, ghV4Client, project, itemID, githubProject.Fields); err != nil {
+			return err
+		}
+
+		projectItems = append(projectItems, trainingv1alpha1.ProjectItemStatus{ProjectID: projectID, ItemID: itemID})
+	}
+
+	// remove the issue from projects which are no longer in the spec
+	for _, projectItem := range githubissue.Status.ProjectItems {
+		if containsProjectItem(projectItems, projectItem) {
+			continue
+		}
+
+		log.Info("Removing issue from project", "project", projectItem.ProjectID, "item", projectItem.ItemID)
+		if err := r.deleteProjectItem(ctx, ghV4Client, projectItem.ProjectID, projectItem.ItemID); err != nil {
+			return err
+		}
+	}
+
+	githubissue.Status.ProjectItems = projectItems
+	return nil
+}
+
+// this function returns a project by its node ID or by its number, a project referenced by its
+// number is looked up in the organization or user owning it, which defaults to the given owner
+func (r *GithubIssueReconciler) getProject(ctx context.Context, ghV4Client *githubv4.Client, githubProject trainingv1alpha1.GithubProject, owner string) (*projectV2, error) {
+	if githubProject.NodeID != "" {
+		var query struct {
+			Node struct {
+				ProjectV2 projectV2 `graphql:"... on ProjectV2"`
+			} `graphql:"node(id: $id)"`
+		}
+
+		variables := map[string]interface{}{
+			"id": githubv4.ID(githubProject.NodeID),
+		}
+
+		if err := ghV4Client.Query(ctx, &query, variables); err != nil {
+			return nil, err
+		}
+		return &query.Node.ProjectV2, nil
+	}
+
+	if githubProject.Owner != "" {
+		owner = githubProject.Owner
+	}
+
+	variables := map[string]interface{}{
+		"login":  githubv4.String(owner),
+		"number": githubv4.Int(githubProject.Number),
+	}
+
+	var organizationQuery struct {
+		Organization struct {
+			ProjectV2 projectV2 `graphql:"projectV2(number: $number)"`
+		} `graphql:"organization(login: $login)"`
+	}
+
+	organizationErr := ghV4Client.Query(ctx, &organizationQuery, variables)
+	if organizationErr == nil {
+		return &organizationQuery.Organization.ProjectV2, nil
+	}
+
+	// the owner of the project is not an organization, so look up the project of the user
+	var userQuery struct {
+		User struct {
+			ProjectV2 projectV2 `graphql:"projectV2(number: $number)"`
+		} `graphql:"user(login: $login)"`
+	}
+
+	if err := ghV4Client.Query(ctx, &userQuery, variables); err != nil {
+		return nil, fmt.Errorf("unable to find project %d of %s: %v, %v", githubProject.Number, owner, organizationErr, err)
+	}
+	return &userQuery.User.ProjectV2, nil
+}
+
+// this function adds an issue to a project and returns the ID of its project item
+func (r *GithubIssueReconciler) addProjectItem(ctx context.Context, ghV4Client *githubv4.Client, projectID, issueID string) (string, error) {
+	var mutation struct {
+		AddProjectV2ItemById struct {
+			Item struct {
+				ID githubv4.ID
+			}
+		} `graphql:"addProjectV2ItemById(input: $input)"`
+	}
+
+	input := githubv4.AddProjectV2ItemByIdInput{
+		ProjectID: projectID,
+		ContentID: issueID,
+	}
+
+	if err := ghV4Client.Mutate(ctx, &mutation, input, nil); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprint(mutation.AddProjectV2ItemById.Item.ID), nil
+}
+
+// this function removes an item from a project
+func (r *GithubIssueReconciler) deleteProjectItem(ctx context.Context, ghV4Client *githubv4.Client, projectID, itemID string) error {
+	var mutation struct {
+		DeleteProjectV2Item struct {
+			DeletedItemID githubv4.ID `graphql:"deletedItemId"`
+		} `graphql:"deleteProjectV2Item(input: $input)"`
+	}
+
+	input := githubv4.DeleteProjectV2ItemInput{
+		ProjectID: projectID,
+		ItemID:    itemID,
+	}
+
+	return ghV4Client.Mutate(ctx, &mutation, input, nil)
+}
+
+// this function updates the values of the fields of a project item
+// which differ from the values in the spec of the object
+func (r *GithubIssueReconciler) syncProjectItemFields(ctx context.Context, ghV4Client *githubv4.Client, project *projectV2, itemID string, fieldValues []trainingv1alpha1.ProjectFieldValue) error {
+	if len(fieldValues) == 0 {
+		return nil
+	}
+
+	currentValues, err := r.getProjectItemFieldValues(ctx, ghV4Client, itemID)
+	if err != nil {
+		return err
+	}
+
+	for _, fieldValue := range fieldValues {
+		field := getProjectField(project, fieldValue.Name)
+		if field == nil {
+			return fmt.Errorf("field %q does not exist in project %v", fieldValue.Name, project.ID)
+		}
+
+		if currentValue, ok := currentValues[strings.ToLower(fieldValue.Name)]; ok && currentValue == normalizeProjectFieldValue(field, fieldValue.Value) {
+			continue
+		}
+
+		value, err := getProjectV2FieldValue(field, fieldValue.Value)
+		if err != nil {
+			return err
+		}
+
+		var mutation struct {
+			UpdateProjectV2ItemFieldValue struct {
+				ProjectV2Item struct {
+					ID githubv4.ID
+				}
+			} `graphql:"updateProjectV2ItemFieldValue(input: $input)"`
+		}
+
+		input := githubv4.UpdateProjectV2ItemFieldValueInput{
+			ProjectID: project.ID,
+			ItemID:    itemID,
+			FieldID:   field.Common.ID,
+			Value:     *value,
+		}
+
+		if err := ghV4Client.Mutate(ctx, &mutation, input, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// this function returns the current values of the fields of a project item
+// keyed by the lower cased name of the field
+func (r *GithubIssueReconciler) getProjectItemFieldValues(ctx context.Context, ghV4Client *githubv4.Client, itemID string) (map[string]string, error) {
+	var query struct {
+		Node struct {
+			ProjectV2Item struct {
+				FieldValues struct {
+					Nodes []struct {
+						Typename string `graphql:"__typename"`
+						Common   struct {
+							Field projectV2FieldName
+						} `graphql:"... on ProjectV2ItemFieldValueCommon"`
+						SingleSelect struct {
+							Name string
+						} `graphql:"... on ProjectV2ItemFieldSingleSelectValue"`
+						Iteration struct {
+							Title string
+						} `graphql:"... on ProjectV2ItemFieldIterationValue"`
+						Text struct {
+							Text string
+						} `graphql:"... on ProjectV2ItemFieldTextValue"`
+						Number struct {
+							Number float64
+						} `graphql:"... on ProjectV2ItemFieldNumberValue"`
+						Date struct {
+							Date string
+						} `graphql:"... on ProjectV2ItemFieldDateValue"`
+					}
+				} `graphql:"fieldValues(first: 100)"`
+			} `graphql:"... on ProjectV2Item"`
+		} `graphql:"node(id: $id)"`
+	}
+
+	variables := map[string]interface{}{
+		"id": githubv4.ID(itemID),
+	}
+
+	if err := ghV4Client.Query(ctx, &query, variables); err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for _, node := range query.Node.ProjectV2Item.FieldValues.Nodes {
+		fieldName := strings.ToLower(node.Common.Field.Common.Name)
+		switch node.Typename {
+		case "ProjectV2ItemFieldSingleSelectValue":
+			values[fieldName] = node.SingleSelect.Name
+		case "ProjectV2ItemFieldIterationValue":
+			values[fieldName] = node.Iteration.Title
+		case "ProjectV2ItemFieldTextValue":
+			values[fieldName] = node.Text.Text
+		case "ProjectV2ItemFieldNumberValue":
+			values[fieldName] = strconv.FormatFloat(node.Number.Number, 'f', -1, 64)
+		case "ProjectV2ItemFieldDateValue":
+			values[fieldName] = strings.SplitN(node.Date.Date, "T", 2)[0]
+		}
+	}
+
+	return values, nil
+}
+
+// this function returns the field of a project by its name
+func getProjectField(project *projectV2, name string) *projectV2Field {
+	for i, field := range project.Fields.Nodes {
+		if strings.EqualFold(field.Common.Name, name) {
+			return &project.Fields.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// this function converts a value from the spec to the value of a project field,
+// options and iterations are resolved to their IDs by their names
+func getProjectV2FieldValue(field *projectV2Field, value string) (*githubv4.ProjectV2FieldValue, error) {
+	switch field.Common.DataType {
+	case "SINGLE_SELECT":
+		for _, option := range field.SingleSelect.Options {
+			if strings.EqualFold(option.Name, value) {
+				return &githubv4.ProjectV2FieldValue{SingleSelectOptionID: githubv4.NewString(githubv4.String(option.ID))}, nil
+			}
+		}
+		return nil, fmt.Errorf("option %q does not exist in field %q", value, field.Common.Name)
+	case "ITERATION":
+		for _, iteration := range field.Iteration.Configuration.Iterations {
+			if strings.EqualFold(iteration.Title, value) {
+				return &githubv4.ProjectV2FieldValue{IterationID: githubv4.NewString(githubv4.String(iteration.ID))}, nil
+			}
+		}
+		return nil, fmt.Errorf("iteration %q does not exist in field %q", value, field.Common.Name)
+	case "TEXT":
+		return &githubv4.ProjectV2FieldValue{Text: githubv4.NewString(githubv4.String(value))}, nil
+	case "NUMBER":
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q for field %q: %v", value, field.Common.Name, err)
+		}
+		return &githubv4.ProjectV2FieldValue{Number: githubv4.NewFloat(githubv4.Float(number))}, nil
+	case "DATE":
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q for field %q: %v", value, field.Common.Name, err)
+		}
+		return &githubv4.ProjectV2FieldValue{Date: githubv4.NewDate(githubv4.Date{Time: date})}, nil
+	}
+
+	return nil, fmt.Errorf("field %q of type %s is not supported", field.Common.Name, field.Common.DataType)
+}
+
+// this function returns a value from the spec in the form in which the
+// current value of a project field is reported, so that they can be compared
+func normalizeProjectFieldValue(field *projectV2Field, value string) string {
+	switch field.Common.DataType {
+	case "SINGLE_SELECT":
+		for _, option := range field.SingleSelect.Options {
+			if strings.EqualFold(option.Name, value) {
+				return option.Name
+			}
+		}
+	case "ITERATION":
+		for _, iteration := range field.Iteration.Configuration.Iterations {
+			if strings.EqualFold(iteration.Title, value) {
+				return iteration.Title
+			}
+		}
+	case "NUMBER":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return strconv.FormatFloat(number, 'f', -1, 64)
+		}
+	}
+	return value
+}
+
+// this function checks whether a project item is in a list of project items
+func containsProjectItem(projectItems []trainingv1alpha1.ProjectItemStatus, projectItem trainingv1alpha1.ProjectItemStatus) bool {
+	for _, item := range projectItems {
+		if item.ProjectID == projectItem.ProjectID && item.ItemID == projectItem.ItemID {
+			return true
+		}
+	}
+	return false
+}
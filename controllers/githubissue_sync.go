@@ -0,0 +1,199 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// syncedFields holds the fields which are synced between the spec of an object and its issue
+type syncedFields struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+	State       string   `json:"state"`
+}
+
+// this function decides in which direction the object and the issue are synced
+// and returns whether the spec should be pushed to the issue and whether the issue
+// should be pulled into the spec. in bidirectional mode the side which changed since
+// the last sync wins, and if both sides changed the conflict is recorded in a condition
+func (r *GithubIssueReconciler) resolveSyncDirection(issue *Issue, githubissue *trainingv1alpha1.GithubIssue) (bool, bool) {
+	switch githubissue.Spec.SyncDirection {
+	case trainingv1alpha1.SyncDirectionFromGithub:
+		return false, true
+	case trainingv1alpha1.SyncDirectionBidirectional:
+	default:
+		return true, false
+	}
+
+	// the first sync of an object applies the spec to the issue
+	lastAppliedHash := githubissue.Status.LastAppliedHash
+	if lastAppliedHash == "" {
+		r.setConflictCondition(githubissue, metav1.ConditionFalse, noConflictConditionReason, "The object and the issue are in sync")
+		return true, false
+	}
+
+	specHash := hashSyncedFields(getSpecSyncedFields(githubissue))
+	issueHash := hashSyncedFields(getManagedSyncedFields(getIssueSyncedFields(issue), githubissue))
+
+	// the spec changed if it differs from what was last applied, the issue changed if it
+	// was updated on github since the last sync and differs from what was last applied
+	specChanged := specHash != lastAppliedHash
+	issueChanged := issueHash != lastAppliedHash
+	if lastUpdatedAt := githubissue.Status.GithubUpdatedAt; lastUpdatedAt != nil && (issue.UpdatedAt == nil || !issue.UpdatedAt.After(lastUpdatedAt.Time)) {
+		issueChanged = false
+	}
+
+	if specChanged && issueChanged && specHash != issueHash {
+		r.setConflictCondition(githubissue, metav1.ConditionTrue, conflictConditionReason,
+			"Both the object and the issue changed since the last sync")
+		return false, false
+	}
+
+	r.setConflictCondition(githubissue, metav1.ConditionFalse, noConflictConditionReason, "The object and the issue are in sync")
+	if issueChanged {
+		return false, true
+	}
+	return true, false
+}
+
+// this function writes the title, description, labels and state of the issue
+// into the spec of the object and updates the object if the spec changed
+func (r *GithubIssueReconciler) pullIssueIntoSpec(ctx context.Context, issue *Issue, githubissue *trainingv1alpha1.GithubIssue) error {
+	log := log.FromContext(ctx)
+
+	issueFields := getIssueSyncedFields(issue)
+	if hashSyncedFields(issueFields) == hashSyncedFields(getSpecSyncedFields(githubissue)) {
+		return nil
+	}
+
+	log.Info("Pulling changes of issue into the spec", "issue", issue.Number)
+	githubissue.Spec.Title = issueFields.Title
+	githubissue.Spec.Description = issueFields.Description
+	githubissue.Spec.Labels = issueFields.Labels
+	githubissue.Spec.State = issueFields.State
+
+	return r.Update(ctx, githubissue)
+}
+
+// this function records the hash of the fields managed by the object as they are on the issue
+// after the sync, and the time the issue was last updated on github in the status of the object
+func (r *GithubIssueReconciler) recordSyncState(issue *Issue, githubissue *trainingv1alpha1.GithubIssue) {
+	githubissue.Status.LastAppliedHash = hashSyncedFields(getManagedSyncedFields(getIssueSyncedFields(issue), githubissue))
+	if issue.UpdatedAt != nil {
+		updatedAt := metav1.NewTime(*issue.UpdatedAt)
+		githubissue.Status.GithubUpdatedAt = &updatedAt
+	}
+}
+
+// this function sets the condition of the object that indicates whether
+// both the object and the issue changed since the last sync
+func (r *GithubIssueReconciler) setConflictCondition(githubissue *trainingv1alpha1.GithubIssue, status metav1.ConditionStatus, reason, message string) {
+	conflictCondition := metav1.Condition{
+		Type:    conflictConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+
+	apimeta.SetStatusCondition(&githubissue.Status.Conditions, conflictCondition)
+}
+
+// this function returns the synced fields of the spec of an object
+func getSpecSyncedFields(githubissue *trainingv1alpha1.GithubIssue) syncedFields {
+	return syncedFields{
+		Title:       githubissue.Spec.Title,
+		Description: githubissue.Spec.Description,
+		Labels:      githubissue.Spec.Labels,
+		State:       githubissue.Spec.State,
+	}
+}
+
+// this function returns the synced fields of an issue
+func getIssueSyncedFields(issue *Issue) syncedFields {
+	return syncedFields{
+		Title:       issue.Title,
+		Description: stripIssueMarker(issue.Body),
+		Labels:      issue.Labels,
+		State:       issue.State,
+	}
+}
+
+// this function returns the synced fields which are managed by an object, the fields which
+// are empty in the spec are left untouched on the issue so they are emptied as well
+func getManagedSyncedFields(fields syncedFields, githubissue *trainingv1alpha1.GithubIssue) syncedFields {
+	if githubissue.Spec.Title == "" {
+		fields.Title = ""
+	}
+	if len(githubissue.Spec.Labels) == 0 {
+		fields.Labels = nil
+	}
+	if githubissue.Spec.State == "" {
+		fields.State = ""
+	}
+	return fields
+}
+
+// this function records the time an issue was updated on github from the issue returned
+// by an update, so that the update made by the reconciler isn't mistaken for a change on github
+func setIssueUpdatedAt(issue, updated *Issue) {
+	if updated != nil && updated.UpdatedAt != nil {
+		issue.UpdatedAt = updated.UpdatedAt
+	}
+}
+
+// this function returns a hash of the synced fields, the
+// order of the labels does not affect the hash
+func hashSyncedFields(fields syncedFields) string {
+	labels := append([]string{}, fields.Labels...)
+	sort.Strings(labels)
+	fields.Labels = labels
+
+	data, _ := json.Marshal(fields)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// this function checks whether two lists contain the same values regardless of their order
+func equalUnordered(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
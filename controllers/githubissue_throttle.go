@@ -0,0 +1,277 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+const (
+	// throttleWindow is the window in which issue creations are counted
+	throttleWindow = time.Hour
+
+	// throttleRetryInterval is how long objects wait for the open issues of a repository to be closed
+	throttleRetryInterval = time.Minute
+
+	openIssuesPerRepoLimit       string = "open_issues_per_repo"
+	creationsPerNamespaceLimit   string = "creations_per_namespace"
+	creationsPerRepoLimit        string = "creations_per_repo"
+	namespaceCreationsKeyPrefix  string = "namespace/"
+	repositoryCreationsKeyPrefix string = "repo/"
+)
+
+var (
+	throttledReconciles = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "githubissues_throttled_reconciles_total",
+			Help: "Number of reconciles which didn't create an issue because a limit was reached",
+		},
+		[]string{"limit", "namespace"},
+	)
+
+	issueCreations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "githubissues_issue_creations_total",
+			Help: "Number of issues created on github",
+		},
+		[]string{"namespace"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(throttledReconciles, issueCreations)
+}
+
+// Throttle limits the issues created by the reconcilers, so that a runaway automation
+// creating objects can't flood a repository. A limit of 0 means no limit. The creations
+// are counted in memory, so the hourly limits start over when the manager restarts
+type Throttle struct {
+	// MaxOpenIssuesPerRepo is the maximum number of open managed issues in a repository
+	MaxOpenIssuesPerRepo int
+
+	// MaxCreationsPerHourPerNamespace is the maximum number of issues created for the objects of a namespace per hour
+	MaxCreationsPerHourPerNamespace int
+
+	// MaxCreationsPerHourPerRepo is the maximum number of issues created in a repository per hour
+	MaxCreationsPerHourPerRepo int
+
+	mu        sync.Mutex
+	creations map[string][]time.Time
+	now       func() time.Time
+
+	// pending are the objects of a repository for which an issue is created, they are counted
+	// as open issues until the issue number of the object shows up in the cache
+	pending map[string]map[types.NamespacedName]bool
+}
+
+// throttleReservation is a creation which was counted against the limits by
+// check before the issue is created, it is released if the issue isn't created
+type throttleReservation struct {
+	keys   []string
+	at     time.Time
+	repo   string
+	object types.NamespacedName
+}
+
+// this function returns the limit which prevents an issue from being created for an object and
+// how long to wait before trying again, an empty limit means that the issue can be created. the
+// creation is reserved while the limits are checked, so concurrent reconciles can't exceed them
+func (t *Throttle) check(ctx context.Context, c client.Reader, githubissue *trainingv1alpha1.GithubIssue, owner, repo string) (string, time.Duration, *throttleReservation, error) {
+	if t == nil {
+		return "", 0, nil, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	namespaceKey := namespaceCreationsKeyPrefix + githubissue.Namespace
+	repositoryKey := repositoryCreationsKeyPrefix + owner + "/" + repo
+	if wait := t.waitForCreation(namespaceKey, t.MaxCreationsPerHourPerNamespace); wait > 0 {
+		return creationsPerNamespaceLimit, wait, nil, nil
+	}
+	if wait := t.waitForCreation(repositoryKey, t.MaxCreationsPerHourPerRepo); wait > 0 {
+		return creationsPerRepoLimit, wait, nil, nil
+	}
+
+	if t.MaxOpenIssuesPerRepo > 0 {
+		openIssues, err := t.countOpenIssues(ctx, c, owner, repo)
+		if err != nil {
+			return "", 0, nil, err
+		}
+		if openIssues >= t.MaxOpenIssuesPerRepo {
+			return openIssuesPerRepoLimit, throttleRetryInterval, nil, nil
+		}
+	}
+
+	if t.MaxOpenIssuesPerRepo <= 0 && t.MaxCreationsPerHourPerNamespace <= 0 && t.MaxCreationsPerHourPerRepo <= 0 {
+		return "", 0, nil, nil
+	}
+
+	if t.creations == nil {
+		t.creations = map[string][]time.Time{}
+	}
+	if t.pending == nil {
+		t.pending = map[string]map[types.NamespacedName]bool{}
+	}
+
+	reservation := &throttleReservation{
+		keys:   []string{namespaceKey, repositoryKey},
+		at:     t.clock(),
+		repo:   owner + "/" + repo,
+		object: types.NamespacedName{Namespace: githubissue.Namespace, Name: githubissue.Name},
+	}
+	for _, key := range reservation.keys {
+		t.creations[key] = append(t.creations[key], reservation.at)
+	}
+	if t.pending[reservation.repo] == nil {
+		t.pending[reservation.repo] = map[types.NamespacedName]bool{}
+	}
+	t.pending[reservation.repo][reservation.object] = true
+
+	return "", 0, reservation, nil
+}
+
+// this function counts the open issues of a repository, the issues created for objects whose
+// status didn't reach the cache yet are counted through the pending creations of the repository
+func (t *Throttle) countOpenIssues(ctx context.Context, c client.Reader, owner, repo string) (int, error) {
+	openIssues, objects, err := countOpenManagedIssues(ctx, c, owner, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := t.pending[owner+"/"+repo]
+	for object := range pending {
+		// the creation is settled once the object is gone or its issue number is cached
+		if linked, ok := objects[object]; !ok || linked {
+			delete(pending, object)
+			continue
+		}
+		openIssues++
+	}
+
+	return openIssues, nil
+}
+
+// this function releases a creation reserved by check, so an issue which
+// wasn't created doesn't count against the limits
+func (t *Throttle) release(reservation *throttleReservation) {
+	if t == nil || reservation == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.pending[reservation.repo], reservation.object)
+	for _, key := range reservation.keys {
+		creations := t.creations[key]
+		for i := len(creations) - 1; i >= 0; i-- {
+			if creations[i].Equal(reservation.at) {
+				t.creations[key] = append(creations[:i:i], creations[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// this function records that an issue was created for an object
+func (t *Throttle) recordCreation(namespace string) {
+	issueCreations.WithLabelValues(namespace).Inc()
+}
+
+// this function drops the creations of a key which left the window and returns how long to wait
+// until another creation is allowed, 0 is returned if a creation is allowed right away
+func (t *Throttle) waitForCreation(key string, limit int) time.Duration {
+	now := t.clock()
+
+	creations := t.creations[key]
+	for len(creations) > 0 && now.Sub(creations[0]) >= throttleWindow {
+		creations = creations[1:]
+	}
+	if t.creations != nil {
+		t.creations[key] = creations
+	}
+
+	if limit <= 0 || len(creations) < limit {
+		return 0
+	}
+
+	return creations[len(creations)-limit].Add(throttleWindow).Sub(now)
+}
+
+// this function returns the current time
+func (t *Throttle) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// this function counts the objects of a repository which are linked to an open issue, it also
+// returns the objects of the repository and whether they are linked to an issue
+func countOpenManagedIssues(ctx context.Context, c client.Reader, owner, repo string) (int, map[types.NamespacedName]bool, error) {
+	var githubissues trainingv1alpha1.GithubIssueList
+	if err := c.List(ctx, &githubissues); err != nil {
+		return 0, nil, err
+	}
+
+	var clusterGithubIssues trainingv1alpha1.ClusterGithubIssueList
+	if err := c.List(ctx, &clusterGithubIssues); err != nil {
+		return 0, nil, err
+	}
+
+	count := 0
+	objects := map[types.NamespacedName]bool{}
+	countOpenIssue := func(object types.NamespacedName, spec *trainingv1alpha1.GithubIssueSpec, status *trainingv1alpha1.GithubIssueStatus) {
+		// objects whose repository can't be parsed don't belong to any repository
+		specOwner, specRepo, err := parseOwnerRepo(spec.Repo)
+		if err != nil || specOwner != owner || specRepo != repo {
+			return
+		}
+		objects[object] = status.IssueNumber != 0
+		if status.IssueNumber != 0 && apimeta.IsStatusConditionTrue(status.Conditions, issueOpenConditionType) {
+			count++
+		}
+	}
+
+	for i := range githubissues.Items {
+		githubissue := &githubissues.Items[i]
+		countOpenIssue(types.NamespacedName{Namespace: githubissue.Namespace, Name: githubissue.Name}, &githubissue.Spec, &githubissue.Status)
+	}
+	for i := range clusterGithubIssues.Items {
+		clusterGithubIssue := &clusterGithubIssues.Items[i]
+		countOpenIssue(types.NamespacedName{Name: clusterGithubIssue.Name}, &clusterGithubIssue.Spec, &clusterGithubIssue.Status)
+	}
+
+	return count, objects, nil
+}
+
+// this function returns the message of the Throttled condition of a limit
+func throttledMessage(limit string, wait time.Duration) string {
+	return fmt.Sprintf("The %s limit was reached, the issue is created in %s at the earliest", limit, wait.Round(time.Second))
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/google/go-github/v45/github"
 	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	"github.com/shurcooL/githubv4"
 	"golang.org/x/oauth2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -45,7 +46,14 @@ const (
 )
 
 func GetGithubClient(ctx context.Context) *github.Client {
-	ghPersonalAccessToken := os.Getenv("GH_PERSONAL_TOKEN")
+	return NewGithubClient(ctx, os.Getenv("GH_PERSONAL_TOKEN"))
+}
+
+func GetGithubV4Client(ctx context.Context) *githubv4.Client {
+	return NewGithubV4Client(ctx, os.Getenv("GH_PERSONAL_TOKEN"))
+}
+
+func NewGithubClient(ctx context.Context, ghPersonalAccessToken string) *github.Client {
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: ghPersonalAccessToken},
 	)
@@ -56,6 +64,17 @@ func GetGithubClient(ctx context.Context) *github.Client {
 	return ghClient
 }
 
+func NewGithubV4Client(ctx context.Context, ghPersonalAccessToken string) *githubv4.Client {
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: ghPersonalAccessToken},
+	)
+
+	tc := oauth2.NewClient(ctx, ts)
+	ghV4Client := githubv4.NewClient(tc)
+
+	return ghV4Client
+}
+
 func SetupClient(obj []client.Object) (client.Client, *runtime.Scheme, error) {
 
 	s := scheme.Scheme
@@ -100,3 +119,44 @@ func GenerateGithubIssueObject() *trainingv1alpha1.GithubIssue {
 
 	return githubIssue
 }
+
+func GenerateGithubLabelObject() *trainingv1alpha1.GithubLabel {
+	name := GenerateRandomString()
+	labelName := GenerateRandomString()
+
+	githubLabel := &trainingv1alpha1.GithubLabel{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: trainingv1alpha1.GithubLabelSpec{
+			Repo:        testRepo,
+			Name:        labelName,
+			Color:       "d73a4a",
+			Description: GenerateRandomString(),
+		},
+	}
+
+	return githubLabel
+}
+
+func GenerateGithubMilestoneObject() *trainingv1alpha1.GithubMilestone {
+	name := GenerateRandomString()
+	title := GenerateRandomString()
+	description := GenerateRandomString()
+
+	githubMilestone := &trainingv1alpha1.GithubMilestone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: trainingv1alpha1.GithubMilestoneSpec{
+			Repo:        testRepo,
+			Title:       title,
+			Description: description,
+			State:       "open",
+		},
+	}
+
+	return githubMilestone
+}
@@ -0,0 +1,438 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// GithubIssueBackupReconciler reconciles a GithubIssueBackup object
+type GithubIssueBackupReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	GithubClient *github.Client
+
+	// Trackers are the issue trackers of repository hosts other than github
+	Trackers map[string]IssueTracker
+
+	// BackupDir is the directory under which file destinations are written,
+	// backups to files are rejected if it is not set
+	BackupDir string
+
+	// ClusterIssues builds the github clients used for the issues of ClusterGithubIssue
+	// objects, the github client is used for them if it is not set
+	ClusterIssues *ClusterGithubIssueReconciler
+
+	// Scope restricts the objects handled by the reconciler
+	Scope Scope
+}
+
+const (
+	backupSucceededConditionType      string = "BackupSucceeded"
+	backupCompletedConditionReason    string = "BackupCompleted"
+	backupFailedConditionReason       string = "BackupFailed"
+	backupIncompleteConditionReason   string = "BackupIncomplete"
+	invalidDestinationConditionReason string = "InvalidDestination"
+
+	// issueBackupLabel is set on the ConfigMaps written by a backup to the name of the backup
+	issueBackupLabel string = "training.redhat.com/issue-backup"
+
+	// maxConfigMapBackupSize is the largest backup kept in a ConfigMap, which is limited to 1MiB with its metadata
+	maxConfigMapBackupSize int = 1000 * 1024
+
+	// backupFileTimeLayout is the layout of the time in the names of backup files
+	backupFileTimeLayout string = "20060102T150405Z"
+)
+
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubissuebackups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubissuebackups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubissuebackups/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=training.redhat.com,resources=clustergithubissues,verbs=get;list;watch
+
+// Reconcile takes a backup of the issues managed by the GithubIssue objects selected by a
+// GithubIssueBackup object, and the ClusterGithubIssue objects if it includes them, with
+// their comments, and takes it again every interval.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *GithubIssueBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Processing GithubIssueBackupReconciler")
+
+	// fetch githubissuebackup object
+	var backup trainingv1alpha1.GithubIssueBackup
+	if err := r.Get(ctx, req.NamespacedName, &backup); err != nil {
+		if errors.IsNotFound(err) {
+			// request object not found, could have been deleted after reconcile request
+			// return and don't requeue
+			log.Info("GithubIssueBackup resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// error reading the object - request
+		log.Error(err, "unable to fetch githubissuebackup")
+		return ctrl.Result{}, err
+	}
+
+	// an invalid destination is reported and not retried until the spec is fixed
+	if err := r.validateDestination(&backup); err != nil {
+		r.setBackupSucceededCondition(&backup, metav1.ConditionFalse, invalidDestinationConditionReason, err.Error())
+		if err := r.Status().Update(ctx, &backup); err != nil {
+			log.Error(err, "unable to update githubissuebackup status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// wait for the next backup to be due
+	if wait, due := r.nextBackupIn(&backup); !due {
+		if wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+		return ctrl.Result{}, nil
+	}
+
+	now := time.Now()
+	issueBackup, err := r.takeBackup(ctx, &backup, now)
+	if err != nil {
+		log.Error(err, "unable to back up issues")
+		r.setBackupSucceededCondition(&backup, metav1.ConditionFalse, backupFailedConditionReason, err.Error())
+		if err := r.Status().Update(ctx, &backup); err != nil {
+			log.Error(err, "unable to update githubissuebackup status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	location, err := r.writeBackup(ctx, &backup, issueBackup)
+	if err != nil {
+		log.Error(err, "unable to write backup")
+		r.setBackupSucceededCondition(&backup, metav1.ConditionFalse, backupFailedConditionReason, err.Error())
+		if err := r.Status().Update(ctx, &backup); err != nil {
+			log.Error(err, "unable to update githubissuebackup status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	backup.Status.LastBackupTime = &metav1.Time{Time: now}
+	backup.Status.LastBackupLocation = location
+	backup.Status.BackedUpIssues = len(issueBackup.Issues)
+	backup.Status.FailedIssues = len(issueBackup.Failures)
+	if len(issueBackup.Failures) > 0 {
+		failure := issueBackup.Failures[0]
+		r.setBackupSucceededCondition(&backup, metav1.ConditionFalse, backupIncompleteConditionReason,
+			fmt.Sprintf("Backed up %d issues to %s, %d issues could not be backed up, the issue of %s: %s",
+				len(issueBackup.Issues), location, len(issueBackup.Failures), failure.Object, failure.Error))
+	} else {
+		r.setBackupSucceededCondition(&backup, metav1.ConditionTrue, backupCompletedConditionReason,
+			fmt.Sprintf("Backed up %d issues to %s", len(issueBackup.Issues), location))
+	}
+
+	log.Info("Updating githubissuebackup status")
+	if err := r.Status().Update(ctx, &backup); err != nil {
+		log.Error(err, "unable to update githubissuebackup status")
+		return ctrl.Result{}, err
+	}
+
+	if backup.Spec.Interval != nil {
+		return ctrl.Result{RequeueAfter: backup.Spec.Interval.Duration}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// this function checks that exactly one destination is set and
+// that a file destination stays within the backup directory
+func (r *GithubIssueBackupReconciler) validateDestination(backup *trainingv1alpha1.GithubIssueBackup) error {
+	destination := backup.Spec.Destination
+	if (destination.ConfigMap == "") == (destination.File == "") {
+		return fmt.Errorf("exactly one of destination.configMap and destination.file has to be set")
+	}
+	if destination.Retain != 0 && destination.File == "" {
+		return fmt.Errorf("destination.retain only applies to a destination.file")
+	}
+
+	if destination.File != "" {
+		if r.BackupDir == "" {
+			return fmt.Errorf("backups to files are disabled, the operator has no --backup-dir")
+		}
+		if _, err := r.backupFilePath(destination.File); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// this function returns how long to wait for the next backup and whether a backup is due now,
+// a backup without an interval is taken once
+func (r *GithubIssueBackupReconciler) nextBackupIn(backup *trainingv1alpha1.GithubIssueBackup) (time.Duration, bool) {
+	if backup.Status.LastBackupTime == nil {
+		return 0, true
+	}
+	if backup.Spec.Interval == nil {
+		return 0, false
+	}
+
+	wait := time.Until(backup.Status.LastBackupTime.Add(backup.Spec.Interval.Duration))
+	return wait, wait <= 0
+}
+
+// this function fetches the issues of the selected objects which have an issue, with their comments.
+// an issue which can't be fetched, such as a deleted one, is recorded as a failure of the backup
+// and the other issues are backed up anyway
+func (r *GithubIssueBackupReconciler) takeBackup(ctx context.Context, backup *trainingv1alpha1.GithubIssueBackup, now time.Time) (*IssueBackup, error) {
+	selector := labels.Everything()
+	if backup.Spec.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(backup.Spec.Selector); err != nil {
+			return nil, err
+		}
+	}
+
+	var githubissues trainingv1alpha1.GithubIssueList
+	if err := r.List(ctx, &githubissues, client.InNamespace(backup.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	sort.Slice(githubissues.Items, func(i, j int) bool {
+		return githubissues.Items[i].Name < githubissues.Items[j].Name
+	})
+
+	issueReconciler := &GithubIssueReconciler{GithubClient: r.GithubClient, Trackers: r.Trackers}
+	issueReconcilers := make([]*GithubIssueReconciler, len(githubissues.Items))
+	for i := range issueReconcilers {
+		issueReconcilers[i] = issueReconciler
+	}
+
+	// the cluster-scoped objects are translated to GithubIssue objects, as they
+	// are by the ClusterGithubIssueReconciler, and come after the namespaced ones
+	if backup.Spec.ClusterIssues {
+		clusterGithubIssues, clusterIssueReconciler, err := r.listClusterGithubIssues(ctx, selector)
+		if err != nil {
+			return nil, err
+		}
+		for i := range clusterGithubIssues.Items {
+			githubissue := trainingv1alpha1.GithubIssue{}
+			clusterToGithubIssue(&clusterGithubIssues.Items[i], &githubissue)
+			githubissues.Items = append(githubissues.Items, githubissue)
+			issueReconcilers = append(issueReconcilers, clusterIssueReconciler)
+		}
+	}
+
+	issueBackup := &IssueBackup{TakenAt: now.UTC(), Issues: []BackedUpIssue{}}
+	for i := range githubissues.Items {
+		githubissue := &githubissues.Items[i]
+		if githubissue.Status.IssueNumber == 0 {
+			continue
+		}
+
+		backedUp, err := r.backUpIssueOf(ctx, issueReconcilers[i], githubissue)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "unable to back up issue", "githubissue", githubissue.Name, "number", githubissue.Status.IssueNumber)
+			issueBackup.Failures = append(issueBackup.Failures, BackupFailure{
+				Object: issueMarkerOwner(githubissue),
+				Repo:   githubissue.Spec.Repo,
+				Number: githubissue.Status.IssueNumber,
+				Error:  err.Error(),
+			})
+			continue
+		}
+		issueBackup.Issues = append(issueBackup.Issues, *backedUp)
+	}
+
+	return issueBackup, nil
+}
+
+// this function lists the selected ClusterGithubIssue objects, sorted by name, and
+// returns them with a reconciler using the github clients of cluster-scoped objects
+func (r *GithubIssueBackupReconciler) listClusterGithubIssues(ctx context.Context, selector labels.Selector) (*trainingv1alpha1.ClusterGithubIssueList, *GithubIssueReconciler, error) {
+	var clusterGithubIssues trainingv1alpha1.ClusterGithubIssueList
+	if err := r.List(ctx, &clusterGithubIssues, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(clusterGithubIssues.Items, func(i, j int) bool {
+		return clusterGithubIssues.Items[i].Name < clusterGithubIssues.Items[j].Name
+	})
+
+	ghClient := r.GithubClient
+	if r.ClusterIssues != nil && len(clusterGithubIssues.Items) > 0 {
+		var err error
+		if ghClient, _, err = r.ClusterIssues.getGithubClients(ctx); err != nil {
+			return nil, nil, fmt.Errorf("unable to create github clients for cluster-scoped objects: %w", err)
+		}
+	}
+
+	return &clusterGithubIssues, &GithubIssueReconciler{GithubClient: ghClient, Trackers: r.Trackers}, nil
+}
+
+// this function returns the issue of an object with its comments as it is kept in a backup
+func (r *GithubIssueBackupReconciler) backUpIssueOf(ctx context.Context, issueReconciler *GithubIssueReconciler, githubissue *trainingv1alpha1.GithubIssue) (*BackedUpIssue, error) {
+	tracker, err := issueReconciler.getIssueTracker(githubissue)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, repo, err := issueReconciler.extractOwnerRepoInfo(githubissue)
+	if err != nil {
+		return nil, err
+	}
+
+	return backUpIssue(ctx, tracker, githubissue, owner, repo)
+}
+
+// this function writes the backup to its destination and returns where it was written
+func (r *GithubIssueBackupReconciler) writeBackup(ctx context.Context, backup *trainingv1alpha1.GithubIssueBackup, issueBackup *IssueBackup) (string, error) {
+	data, key, err := encodeIssueBackup(issueBackup, backup.Spec.Format)
+	if err != nil {
+		return "", err
+	}
+
+	if backup.Spec.Destination.ConfigMap != "" {
+		if len(data) > maxConfigMapBackupSize {
+			return "", fmt.Errorf("the backup is %d bytes, which is too large for a ConfigMap, use a file destination", len(data))
+		}
+
+		// the ConfigMap is not owned by the backup, so it is kept when the backup is deleted.
+		// an existing ConfigMap which wasn't written by this backup is never overwritten
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: backup.Spec.Destination.ConfigMap, Namespace: backup.Namespace}}
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+			if configMap.ResourceVersion != "" && configMap.Labels[issueBackupLabel] != backup.Name {
+				return fmt.Errorf("configmap %s already exists and is not labeled %s=%s, it is not overwritten", configMap.Name, issueBackupLabel, backup.Name)
+			}
+			if configMap.Labels == nil {
+				configMap.Labels = map[string]string{}
+			}
+			configMap.Labels[issueBackupLabel] = backup.Name
+			configMap.Data = map[string]string{key: string(data)}
+			return nil
+		}); err != nil {
+			return "", err
+		}
+
+		return "configmap/" + configMap.Name, nil
+	}
+
+	dir, err := r.backupFilePath(backup.Spec.Destination.File)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s-%s", backup.Namespace, backup.Name, issueBackup.TakenAt.Format(backupFileTimeLayout))+filepath.Ext(key))
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		return "", err
+	}
+
+	// the backup was written, so a file which can't be deleted is only logged
+	if err := r.pruneBackupFiles(dir, backup); err != nil {
+		log.FromContext(ctx).Error(err, "unable to delete old backup files", "dir", dir)
+	}
+
+	return path, nil
+}
+
+// this function deletes the oldest files of a backup from its directory,
+// so that only the latest files set by destination.retain are kept
+func (r *GithubIssueBackupReconciler) pruneBackupFiles(dir string, backup *trainingv1alpha1.GithubIssueBackup) error {
+	retain := backup.Spec.Destination.Retain
+	if retain <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	prefix := fmt.Sprintf("%s-%s-", backup.Namespace, backup.Name)
+	files := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		// the files of another backup whose name starts with the name of this one are kept
+		takenAt := strings.TrimSuffix(strings.TrimPrefix(name, prefix), filepath.Ext(name))
+		if _, err := time.Parse(backupFileTimeLayout, takenAt); err != nil {
+			continue
+		}
+		files = append(files, name)
+	}
+	if len(files) <= retain {
+		return nil
+	}
+
+	// the time in the names has a fixed width, so they sort from the oldest to the latest
+	sort.Strings(files)
+	for _, name := range files[:len(files)-retain] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// this function returns the directory of a file destination, which has to be within the backup directory
+func (r *GithubIssueBackupReconciler) backupFilePath(file string) (string, error) {
+	dir := filepath.Join(r.BackupDir, filepath.Clean("/"+file))
+	if dir != filepath.Clean(r.BackupDir) && !strings.HasPrefix(dir, filepath.Clean(r.BackupDir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("destination.file %q is outside of the backup directory", file)
+	}
+	return dir, nil
+}
+
+// this function sets the condition of the object that
+// indicates whether the last backup was taken
+func (r *GithubIssueBackupReconciler) setBackupSucceededCondition(backup *trainingv1alpha1.GithubIssueBackup, status metav1.ConditionStatus, reason, message string) {
+	backupCondition := metav1.Condition{
+		Type:    backupSucceededConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+
+	apimeta.SetStatusCondition(&backup.Status.Conditions, backupCondition)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GithubIssueBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&trainingv1alpha1.GithubIssueBackup{}, builder.WithPredicates(r.Scope.Predicate())).
+		Complete(r)
+}
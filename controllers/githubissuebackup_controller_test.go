@@ -0,0 +1,436 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	"github.com/mzeevi/githubissues-operator/internal/githubfake"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// this function returns a fake github server holding an incident issue with comments and
+// another issue, and the GithubIssue objects managing them of which only the first is an incident
+func setupBackupIssues() (*githubfake.Server, []client.Object) {
+	server := githubfake.NewServer()
+
+	createdAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	closedAt := createdAt.Add(6 * time.Hour)
+	server.AddIssue(testOwnerName, testRepoName, githubfake.Issue{
+		Title:     "Database outage",
+		Body:      "The primary database was down.\n\n<!-- githubissues-operator: default/incident -->",
+		State:     "closed",
+		Labels:    []string{"incident"},
+		Assignees: []string{"oncall"},
+		CreatedAt: createdAt,
+		ClosedAt:  &closedAt,
+		Comments: []githubfake.Comment{
+			{ID: 1, Author: "oncall", Body: "Failing over to the replica.", CreatedAt: createdAt.Add(time.Hour)},
+			{ID: 2, Author: "dba", Body: "Root cause: full disk.", CreatedAt: createdAt.Add(2 * time.Hour)},
+		},
+	})
+	server.AddIssue(testOwnerName, testRepoName, githubfake.Issue{Title: "Add dark mode"})
+
+	incident := GenerateGithubIssueObject()
+	incident.Name = "incident"
+	incident.Labels = map[string]string{"incident": "true"}
+	incident.Spec.Title = "Database outage"
+	incident.Status.IssueNumber = 1
+
+	feature := GenerateGithubIssueObject()
+	feature.Spec.Title = "Add dark mode"
+	feature.Status.IssueNumber = 2
+
+	// an object without an issue yet is not backed up
+	pending := GenerateGithubIssueObject()
+	pending.Labels = map[string]string{"incident": "true"}
+
+	return server, []client.Object{incident, feature, pending}
+}
+
+func TestBackupIssuesToConfigMap(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server, obj := setupBackupIssues()
+	defer server.Close()
+
+	backup := &trainingv1alpha1.GithubIssueBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "incidents", Namespace: testNamespace},
+		Spec: trainingv1alpha1.GithubIssueBackupSpec{
+			Selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"incident": "true"}},
+			Interval:    &metav1.Duration{Duration: time.Hour},
+			Format:      trainingv1alpha1.BackupFormatJSON,
+			Destination: trainingv1alpha1.BackupDestination{ConfigMap: "incidents-backup"},
+		},
+	}
+
+	cl, s, err := SetupClient(append(obj, backup))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueBackupReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: backup.Name, Namespace: backup.Namespace}}
+
+	result, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(time.Hour))
+
+	backupReconciled := trainingv1alpha1.GithubIssueBackup{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &backupReconciled)).To(Succeed())
+	g.Expect(backupReconciled.Status.BackedUpIssues).To(Equal(1))
+	g.Expect(backupReconciled.Status.LastBackupLocation).To(Equal("configmap/incidents-backup"))
+	g.Expect(apimeta.IsStatusConditionTrue(backupReconciled.Status.Conditions, backupSucceededConditionType)).To(BeTrue())
+
+	configMap := corev1.ConfigMap{}
+	g.Expect(cl.Get(ctx, types.NamespacedName{Name: "incidents-backup", Namespace: testNamespace}, &configMap)).To(Succeed())
+	g.Expect(configMap.Labels).To(HaveKeyWithValue(issueBackupLabel, "incidents"))
+
+	issueBackup, err := ParseIssueBackup([]byte(configMap.Data[BackupJSONKey]))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issueBackup.Issues).To(HaveLen(1))
+
+	issue := issueBackup.Issues[0]
+	g.Expect(issue.Object).To(Equal("default/incident"))
+	g.Expect(issue.Repo).To(Equal(testRepo))
+	g.Expect(issue.Number).To(Equal(1))
+	g.Expect(issue.Title).To(Equal("Database outage"))
+	g.Expect(issue.Body).To(Equal("The primary database was down."))
+	g.Expect(issue.State).To(Equal("closed"))
+	g.Expect(issue.Labels).To(Equal([]string{"incident"}))
+	g.Expect(issue.Assignees).To(Equal([]string{"oncall"}))
+	g.Expect(issue.CreatedAt.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC))).To(BeTrue())
+	g.Expect(issue.ClosedAt).ToNot(BeNil())
+	g.Expect(issue.Comments).To(HaveLen(2))
+	g.Expect(issue.Comments[1].Author).To(Equal("dba"))
+	g.Expect(issue.Comments[1].Body).To(Equal("Root cause: full disk."))
+
+	// the next backup waits for the interval
+	result, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+	g.Expect(result.RequeueAfter).To(BeNumerically("<=", time.Hour))
+
+	// the backup is restored into another repository, and restoring it again creates nothing
+	inspector := &IssueInspector{GithubClient: server.Client()}
+	server.AddRepo(testOwnerName, "archive")
+	archiveRepo := "https://github.com/" + testOwnerName + "/archive"
+
+	restored, err := inspector.RestoreIssues(ctx, issueBackup, archiveRepo)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(restored).To(Equal([]RestoredIssue{{Source: "https://github.com/testOrg/testRepo/issues/1", Number: 1}}))
+
+	archived, _ := server.Issue(testOwnerName, "archive", 1)
+	g.Expect(archived.Title).To(Equal("Database outage"))
+	g.Expect(archived.Body).To(HavePrefix("The primary database was down."))
+	g.Expect(archived.Body).To(ContainSubstring("Restored from https://github.com/testOrg/testRepo/issues/1"))
+	g.Expect(archived.State).To(Equal("closed"))
+	g.Expect(archived.Labels).To(Equal([]string{"incident"}))
+	g.Expect(archived.Comments).To(HaveLen(2))
+	g.Expect(archived.Comments[0].Body).To(ContainSubstring("Failing over to the replica."))
+	g.Expect(archived.Comments[0].Body).To(HavePrefix("_oncall commented at 2026-03-01T11:00:00Z:_"))
+
+	restored, err = inspector.RestoreIssues(ctx, issueBackup, archiveRepo)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(restored).To(Equal([]RestoredIssue{{Source: "https://github.com/testOrg/testRepo/issues/1", Number: 1, Existing: true}}))
+	g.Expect(server.Issues(testOwnerName, "archive")).To(HaveLen(1))
+}
+
+func TestBackupRecordsFailedIssues(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server, obj := setupBackupIssues()
+	defer server.Close()
+
+	// the issue of this object was deleted from the repository
+	deleted := GenerateGithubIssueObject()
+	deleted.Name = "deleted"
+	deleted.Status.IssueNumber = 9
+
+	backup := &trainingv1alpha1.GithubIssueBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "all-issues", Namespace: testNamespace},
+		Spec: trainingv1alpha1.GithubIssueBackupSpec{
+			Destination: trainingv1alpha1.BackupDestination{ConfigMap: "all-issues-backup"},
+		},
+	}
+
+	cl, s, err := SetupClient(append(obj, deleted, backup))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueBackupReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: backup.Name, Namespace: backup.Namespace}}
+
+	// the other issues are backed up anyway
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	backupReconciled := trainingv1alpha1.GithubIssueBackup{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &backupReconciled)).To(Succeed())
+	g.Expect(backupReconciled.Status.BackedUpIssues).To(Equal(2))
+	g.Expect(backupReconciled.Status.FailedIssues).To(Equal(1))
+	condition := apimeta.FindStatusCondition(backupReconciled.Status.Conditions, backupSucceededConditionType)
+	g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(condition.Reason).To(Equal(backupIncompleteConditionReason))
+	g.Expect(condition.Message).To(ContainSubstring("default/deleted"))
+
+	configMap := corev1.ConfigMap{}
+	g.Expect(cl.Get(ctx, types.NamespacedName{Name: "all-issues-backup", Namespace: testNamespace}, &configMap)).To(Succeed())
+	issueBackup, err := ParseIssueBackup([]byte(configMap.Data[BackupJSONKey]))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issueBackup.Issues).To(HaveLen(2))
+	g.Expect(issueBackup.Failures).To(HaveLen(1))
+	g.Expect(issueBackup.Failures[0].Object).To(Equal("default/deleted"))
+	g.Expect(issueBackup.Failures[0].Number).To(Equal(9))
+	g.Expect(issueBackup.Failures[0].Error).ToNot(BeEmpty())
+	g.Expect(issueBackup.Markdown()).To(ContainSubstring("## Failed issues\n\n- default/deleted, issue 9 of " + testRepo))
+}
+
+func TestBackupKeepsForeignConfigMap(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server, obj := setupBackupIssues()
+	defer server.Close()
+
+	// the destination of the backup is a ConfigMap of something else
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: testNamespace},
+		Data:       map[string]string{"config.yaml": "replicas: 3"},
+	}
+	backup := &trainingv1alpha1.GithubIssueBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "incidents", Namespace: testNamespace},
+		Spec: trainingv1alpha1.GithubIssueBackupSpec{
+			Destination: trainingv1alpha1.BackupDestination{ConfigMap: configMap.Name},
+		},
+	}
+
+	cl, s, err := SetupClient(append(obj, configMap, backup))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueBackupReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: backup.Name, Namespace: backup.Namespace}}
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).To(MatchError(ContainSubstring("is not overwritten")))
+
+	backupReconciled := trainingv1alpha1.GithubIssueBackup{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &backupReconciled)).To(Succeed())
+	condition := apimeta.FindStatusCondition(backupReconciled.Status.Conditions, backupSucceededConditionType)
+	g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(condition.Reason).To(Equal(backupFailedConditionReason))
+
+	configMapReconciled := corev1.ConfigMap{}
+	g.Expect(cl.Get(ctx, types.NamespacedName{Name: configMap.Name, Namespace: testNamespace}, &configMapReconciled)).To(Succeed())
+	g.Expect(configMapReconciled.Data).To(Equal(map[string]string{"config.yaml": "replicas: 3"}))
+	g.Expect(configMapReconciled.Labels).ToNot(HaveKey(issueBackupLabel))
+}
+
+func TestBackupIssuesToFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server, obj := setupBackupIssues()
+	defer server.Close()
+
+	backup := &trainingv1alpha1.GithubIssueBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "all-issues", Namespace: testNamespace},
+		Spec: trainingv1alpha1.GithubIssueBackupSpec{
+			Format:      trainingv1alpha1.BackupFormatMarkdown,
+			Destination: trainingv1alpha1.BackupDestination{File: "../team-a"},
+		},
+	}
+
+	cl, s, err := SetupClient(append(obj, backup))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: backup.Name, Namespace: backup.Namespace}}
+	backupReconciled := trainingv1alpha1.GithubIssueBackup{}
+
+	// file backups need a backup directory
+	r := &GithubIssueBackupReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cl.Get(ctx, req.NamespacedName, &backupReconciled)).To(Succeed())
+	condition := apimeta.FindStatusCondition(backupReconciled.Status.Conditions, backupSucceededConditionType)
+	g.Expect(condition.Reason).To(Equal(invalidDestinationConditionReason))
+
+	// a backup without an interval is taken once, within the backup directory
+	r.BackupDir = t.TempDir()
+	result, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeZero())
+
+	g.Expect(cl.Get(ctx, req.NamespacedName, &backupReconciled)).To(Succeed())
+	g.Expect(backupReconciled.Status.BackedUpIssues).To(Equal(2))
+	location := backupReconciled.Status.LastBackupLocation
+	g.Expect(filepath.Dir(location)).To(Equal(filepath.Join(r.BackupDir, "team-a")))
+	g.Expect(filepath.Base(location)).To(HavePrefix("default-all-issues-"))
+	g.Expect(location).To(HaveSuffix(".md"))
+
+	data, err := os.ReadFile(location)
+	g.Expect(err).ToNot(HaveOccurred())
+	markdown := string(data)
+	g.Expect(markdown).To(ContainSubstring("## testOrg/testRepo#1: Database outage"))
+	g.Expect(markdown).To(ContainSubstring("- Closed: 2026-03-01T16:00:00Z"))
+	g.Expect(markdown).To(ContainSubstring("**dba** at 2026-03-01T12:00:00Z:\n\nRoot cause: full disk."))
+	g.Expect(markdown).To(ContainSubstring("## testOrg/testRepo#2: Add dark mode"))
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	files, err := os.ReadDir(filepath.Dir(location))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(files).To(HaveLen(1))
+}
+
+func TestBackupRetainsLatestFiles(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server, obj := setupBackupIssues()
+	defer server.Close()
+
+	backup := &trainingv1alpha1.GithubIssueBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "all-issues", Namespace: testNamespace},
+		Spec: trainingv1alpha1.GithubIssueBackupSpec{
+			Destination: trainingv1alpha1.BackupDestination{File: "team-a", Retain: 2},
+		},
+	}
+
+	cl, s, err := SetupClient(append(obj, backup))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueBackupReconciler{Client: cl, Scheme: s, GithubClient: server.Client(), BackupDir: t.TempDir()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: backup.Name, Namespace: backup.Namespace}}
+
+	// older files of the backup, and a file of another backup whose name starts with the name of this one
+	dir := filepath.Join(r.BackupDir, "team-a")
+	g.Expect(os.MkdirAll(dir, 0o750)).To(Succeed())
+	for _, name := range []string{
+		"default-all-issues-20260101T000000Z.json",
+		"default-all-issues-20260102T000000Z.json",
+		"default-all-issues-extra-20260101T000000Z.json",
+	} {
+		g.Expect(os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o640)).To(Succeed())
+	}
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	backupReconciled := trainingv1alpha1.GithubIssueBackup{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &backupReconciled)).To(Succeed())
+
+	files, err := os.ReadDir(dir)
+	g.Expect(err).ToNot(HaveOccurred())
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	g.Expect(names).To(ConsistOf(
+		"default-all-issues-20260102T000000Z.json",
+		"default-all-issues-extra-20260101T000000Z.json",
+		filepath.Base(backupReconciled.Status.LastBackupLocation),
+	))
+
+	// retain only applies to files
+	backupReconciled.Spec.Destination = trainingv1alpha1.BackupDestination{ConfigMap: "all-issues-backup", Retain: 2}
+	g.Expect(cl.Update(ctx, &backupReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cl.Get(ctx, req.NamespacedName, &backupReconciled)).To(Succeed())
+	condition := apimeta.FindStatusCondition(backupReconciled.Status.Conditions, backupSucceededConditionType)
+	g.Expect(condition.Reason).To(Equal(invalidDestinationConditionReason))
+}
+
+func TestBackupClusterIssues(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server, obj := setupBackupIssues()
+	defer server.Close()
+
+	// the feature issue is managed by a cluster-scoped object as well
+	clusterFeature := generateClusterGithubIssueObject()
+	clusterFeature.Name = "cluster-feature"
+	clusterFeature.Labels = map[string]string{"incident": "true"}
+	clusterFeature.Status.IssueNumber = 2
+
+	backup := &trainingv1alpha1.GithubIssueBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "incidents", Namespace: testNamespace},
+		Spec: trainingv1alpha1.GithubIssueBackupSpec{
+			Selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"incident": "true"}},
+			Destination: trainingv1alpha1.BackupDestination{ConfigMap: "incidents-backup"},
+		},
+	}
+
+	cl, s, err := SetupClient(append(obj, clusterFeature, backup))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueBackupReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: backup.Name, Namespace: backup.Namespace}}
+
+	// the cluster-scoped objects are only backed up when the backup includes them
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	backupReconciled := trainingv1alpha1.GithubIssueBackup{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &backupReconciled)).To(Succeed())
+	g.Expect(backupReconciled.Status.BackedUpIssues).To(Equal(1))
+
+	backupReconciled.Spec.ClusterIssues = true
+	backupReconciled.Status.LastBackupTime = nil
+	g.Expect(cl.Update(ctx, &backupReconciled)).To(Succeed())
+	g.Expect(cl.Status().Update(ctx, &backupReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cl.Get(ctx, req.NamespacedName, &backupReconciled)).To(Succeed())
+	g.Expect(backupReconciled.Status.BackedUpIssues).To(Equal(2))
+
+	configMap := corev1.ConfigMap{}
+	g.Expect(cl.Get(ctx, types.NamespacedName{Name: "incidents-backup", Namespace: testNamespace}, &configMap)).To(Succeed())
+	issueBackup, err := ParseIssueBackup([]byte(configMap.Data[BackupJSONKey]))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issueBackup.Issues).To(HaveLen(2))
+	g.Expect(issueBackup.Issues[0].Object).To(Equal("default/incident"))
+	g.Expect(issueBackup.Issues[1].Object).To(Equal("cluster-feature"))
+	g.Expect(issueBackup.Issues[1].Title).To(Equal("Add dark mode"))
+}
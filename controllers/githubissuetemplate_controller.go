@@ -0,0 +1,557 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v45/github"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// GithubIssueTemplateReconciler reconciles a GithubIssueTemplate object
+type GithubIssueTemplateReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	GithubClient *github.Client
+
+	// Scope restricts the objects handled by the reconciler
+	Scope Scope
+}
+
+const (
+	// issueTemplateLabel is set on the GithubIssue objects created from
+	// a template and holds the name of the template
+	issueTemplateLabel string = "training.redhat.com/issue-template"
+
+	defaultMaxInFlight int = 5
+
+	rolloutCompleteConditionType     string = "RolloutComplete"
+	rolloutCompleteConditionReason   string = "AllIssuesUpdated"
+	rolloutInProgressConditionReason string = "RolloutInProgress"
+	invalidTemplateConditionReason   string = "InvalidTemplate"
+)
+
+// issueTemplateData holds the fields the title and description
+// templates of a GithubIssueTemplate are rendered with
+type issueTemplateData struct {
+	Repo  string
+	Owner string
+	Name  string
+}
+
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubissuetemplates,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubissuetemplates/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubissuetemplates/finalizers,verbs=update
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubissues,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile fans a GithubIssueTemplate out into one owned GithubIssue object per target
+// repository and rolls changes of the template out to them, at most MaxInFlight at a time.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *GithubIssueTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Processing GithubIssueTemplateReconciler")
+
+	// fetch githubissuetemplate object
+	var issueTemplate trainingv1alpha1.GithubIssueTemplate
+	if err := r.Get(ctx, req.NamespacedName, &issueTemplate); err != nil {
+		if errors.IsNotFound(err) {
+			// request object not found, could have been deleted after reconcile request
+			// return and don't requeue. the owned objects are garbage collected
+			log.Info("GithubIssueTemplate resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// error reading the object - request
+		log.Error(err, "unable to fetch githubissuetemplate")
+		return ctrl.Result{}, err
+	}
+
+	// changes of the objects of a template outside of the scope of this instance are ignored
+	if !r.Scope.Contains(&issueTemplate) {
+		return ctrl.Result{}, nil
+	}
+
+	repos, err := r.getTargetRepos(ctx, &issueTemplate)
+	if err != nil {
+		log.Error(err, "unable to list target repositories of githubissuetemplate")
+		return ctrl.Result{}, err
+	}
+
+	// render the desired spec of the object of every repository, a template
+	// which can't be rendered is reported and not retried until it changes
+	desired := make(map[string]trainingv1alpha1.GithubIssueSpec, len(repos))
+	for _, repo := range repos {
+		spec, err := r.renderIssueSpec(&issueTemplate, repo)
+		if err != nil {
+			log.Error(err, "unable to render githubissuetemplate", "repo", repo)
+			r.setRolloutCompleteCondition(&issueTemplate, metav1.ConditionFalse, invalidTemplateConditionReason, err.Error())
+			if err := r.Status().Update(ctx, &issueTemplate); err != nil {
+				log.Error(err, "unable to update githubissuetemplate status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		desired[repo] = spec
+	}
+
+	children, err := r.getChildren(ctx, &issueTemplate)
+	if err != nil {
+		log.Error(err, "unable to list githubissues of githubissuetemplate")
+		return ctrl.Result{}, err
+	}
+
+	// objects which match the template are either failed, updated once they are synced
+	// with their issue or in flight while they are not synced yet, every object is
+	// counted in exactly one of them
+	maxInFlight := issueTemplate.Spec.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxInFlight
+	}
+
+	updated, inFlight := 0, 0
+	failed := map[string]bool{}
+	var pending []string
+	for _, repo := range repos {
+		child, ok := children[repo]
+		switch {
+		case !ok || !r.isChildUpToDate(child, desired[repo]):
+			pending = append(pending, repo)
+		case isGithubIssueFailed(child):
+			failed[repo] = true
+		case isGithubIssueSynced(child):
+			updated++
+		default:
+			inFlight++
+		}
+	}
+
+	// create or update the pending objects without exceeding the max in flight
+	var rolloutErr error
+	for _, repo := range pending {
+		if inFlight >= maxInFlight {
+			break
+		}
+		if err := r.applyChild(ctx, &issueTemplate, children[repo], repo, desired[repo]); err != nil {
+			log.Error(err, "failed to apply githubissue of githubissuetemplate", "repo", repo)
+			rolloutErr = err
+			failed[repo] = true
+			continue
+		}
+		inFlight++
+	}
+
+	// delete the objects of repositories which are no longer targeted,
+	// which closes their issues through the finalizer of the objects
+	for repo, child := range children {
+		if _, ok := desired[repo]; ok {
+			continue
+		}
+		log.Info("Deleting githubissue of repository which is no longer targeted", "repo", repo, "githubissue", child.Name)
+		if err := r.Delete(ctx, child); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "failed to delete githubissue")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// aggregate the state of the issues into the status of the template
+	issueTemplate.Status.Repos = len(repos)
+	issueTemplate.Status.UpdatedRepos = updated
+	issueTemplate.Status.OpenIssues, issueTemplate.Status.ClosedIssues = 0, 0
+	issueTemplate.Status.FailedIssues = len(failed)
+	for repo, child := range children {
+		if _, ok := desired[repo]; !ok || failed[repo] {
+			continue
+		}
+		switch {
+		case apimeta.IsStatusConditionTrue(child.Status.Conditions, issueOpenConditionType):
+			issueTemplate.Status.OpenIssues++
+		case apimeta.IsStatusConditionFalse(child.Status.Conditions, issueOpenConditionType):
+			issueTemplate.Status.ClosedIssues++
+		}
+	}
+
+	if updated == len(repos) {
+		r.setRolloutCompleteCondition(&issueTemplate, metav1.ConditionTrue, rolloutCompleteConditionReason, "All issues match the template")
+	} else {
+		r.setRolloutCompleteCondition(&issueTemplate, metav1.ConditionFalse, rolloutInProgressConditionReason,
+			fmt.Sprintf("%d of %d issues match the template", updated, len(repos)))
+	}
+
+	log.Info("Updating githubissuetemplate status")
+	if err := r.Status().Update(ctx, &issueTemplate); err != nil {
+		log.Error(err, "unable to update githubissuetemplate status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, rolloutErr
+}
+
+// this function sets the condition of the template that indicates
+// whether all of its objects match the template
+func (r *GithubIssueTemplateReconciler) setRolloutCompleteCondition(issueTemplate *trainingv1alpha1.GithubIssueTemplate, status metav1.ConditionStatus, reason, message string) {
+	rolloutCondition := metav1.Condition{
+		Type:    rolloutCompleteConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+
+	apimeta.SetStatusCondition(&issueTemplate.Status.Conditions, rolloutCondition)
+}
+
+// this function returns the URLs of the repositories targeted by the template, the
+// repositories in the spec come first followed by the ones matching the selector
+func (r *GithubIssueTemplateReconciler) getTargetRepos(ctx context.Context, issueTemplate *trainingv1alpha1.GithubIssueTemplate) ([]string, error) {
+	repos := append([]string{}, issueTemplate.Spec.Repos...)
+
+	if selector := issueTemplate.Spec.RepoSelector; selector != nil {
+		ghClient := r.GithubClient
+		if ghClient == nil {
+			err := fmt.Errorf("github client is not available")
+			return nil, err
+		}
+
+		selected, err := r.getSelectedRepos(ctx, ghClient, selector)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, selected...)
+	}
+
+	// the same repository may be listed more than once
+	var targetRepos []string
+	seen := map[string]bool{}
+	for _, repo := range repos {
+		key := repoKey(repo)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		targetRepos = append(targetRepos, repo)
+	}
+
+	return targetRepos, nil
+}
+
+// this function returns the URLs of the repositories of an organization or
+// user which match a selector, archived repositories are never selected
+func (r *GithubIssueTemplateReconciler) getSelectedRepos(ctx context.Context, ghClient *github.Client, selector *trainingv1alpha1.RepoSelector) ([]string, error) {
+	var nameRegexp *regexp.Regexp
+	if selector.NamePattern != "" {
+		var err error
+		if nameRegexp, err = regexp.Compile(selector.NamePattern); err != nil {
+			return nil, err
+		}
+	}
+
+	repositories, err := r.getReposOfOwner(ctx, ghClient, selector.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []string
+	for _, repository := range repositories {
+		if repository.GetArchived() {
+			continue
+		}
+		if nameRegexp != nil && !nameRegexp.MatchString(repository.GetName()) {
+			continue
+		}
+		if !hasTopics(repository.Topics, selector.Topics) {
+			continue
+		}
+		repos = append(repos, repository.GetHTMLURL())
+	}
+
+	return repos, nil
+}
+
+// this function returns all repositories of an organization,
+// or of a user if there is no organization with that login
+func (r *GithubIssueTemplateReconciler) getReposOfOwner(ctx context.Context, ghClient *github.Client, owner string) ([]*github.Repository, error) {
+	log := log.FromContext(ctx)
+
+	var repositories []*github.Repository
+	orgOptions := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, response, err := ghClient.Repositories.ListByOrg(ctx, owner, orgOptions)
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			break
+		}
+		if err != nil {
+			log.Error(err, "unable to fetch repositories of organization", "owner", owner)
+			return nil, err
+		}
+
+		repositories = append(repositories, page...)
+		if response.NextPage == 0 {
+			return repositories, nil
+		}
+		orgOptions.Page = response.NextPage
+	}
+
+	userOptions := &github.RepositoryListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, response, err := ghClient.Repositories.List(ctx, owner, userOptions)
+		if err != nil {
+			log.Error(err, "unable to fetch repositories of user", "owner", owner)
+			return nil, err
+		}
+
+		repositories = append(repositories, page...)
+		if response.NextPage == 0 {
+			return repositories, nil
+		}
+		userOptions.Page = response.NextPage
+	}
+}
+
+// this function renders the spec of the object of a repository from the template
+func (r *GithubIssueTemplateReconciler) renderIssueSpec(issueTemplate *trainingv1alpha1.GithubIssueTemplate, repo string) (trainingv1alpha1.GithubIssueSpec, error) {
+	// the repository is validated the same way as by the webhook of the objects
+	if _, _, err := trainingv1alpha1.NormalizeRepo(repo); err != nil {
+		return trainingv1alpha1.GithubIssueSpec{}, err
+	}
+	owner, name, err := parseOwnerRepo(repo)
+	if err != nil {
+		return trainingv1alpha1.GithubIssueSpec{}, err
+	}
+	data := issueTemplateData{Repo: repo, Owner: owner, Name: name}
+
+	title, err := renderTemplate("title", issueTemplate.Spec.Title, data)
+	if err != nil {
+		return trainingv1alpha1.GithubIssueSpec{}, err
+	}
+
+	description, err := renderTemplate("description", issueTemplate.Spec.Description, data)
+	if err != nil {
+		return trainingv1alpha1.GithubIssueSpec{}, err
+	}
+
+	return trainingv1alpha1.GithubIssueSpec{
+		Repo:        repo,
+		Title:       title,
+		Description: description,
+		Labels:      issueTemplate.Spec.Labels,
+	}, nil
+}
+
+// this function returns the objects owned by the template keyed by their repository
+func (r *GithubIssueTemplateReconciler) getChildren(ctx context.Context, issueTemplate *trainingv1alpha1.GithubIssueTemplate) (map[string]*trainingv1alpha1.GithubIssue, error) {
+	var githubissues trainingv1alpha1.GithubIssueList
+	if err := r.List(ctx, &githubissues, client.InNamespace(issueTemplate.Namespace),
+		client.MatchingLabels{issueTemplateLabel: templateLabelValue(issueTemplate)}); err != nil {
+		return nil, err
+	}
+
+	children := map[string]*trainingv1alpha1.GithubIssue{}
+	for i := range githubissues.Items {
+		child := &githubissues.Items[i]
+		if !metav1.IsControlledBy(child, issueTemplate) {
+			continue
+		}
+		children[r.findTargetRepo(issueTemplate, child)] = child
+	}
+
+	return children, nil
+}
+
+// this function returns the repository of an object as it is written
+// in the template, so objects are matched regardless of the URL format
+func (r *GithubIssueTemplateReconciler) findTargetRepo(issueTemplate *trainingv1alpha1.GithubIssueTemplate, child *trainingv1alpha1.GithubIssue) string {
+	key := repoKey(child.Spec.Repo)
+	for _, repo := range issueTemplate.Spec.Repos {
+		if repoKey(repo) == key {
+			return repo
+		}
+	}
+	return child.Spec.Repo
+}
+
+// this function creates the object of a repository, or updates
+// it if it exists, to match the spec rendered from the template
+func (r *GithubIssueTemplateReconciler) applyChild(ctx context.Context, issueTemplate *trainingv1alpha1.GithubIssueTemplate, child *trainingv1alpha1.GithubIssue, repo string, spec trainingv1alpha1.GithubIssueSpec) error {
+	log := log.FromContext(ctx)
+
+	if child == nil {
+		// the labels of the template are copied to its objects, so that
+		// they are handled by the same instance of the operator
+		labels := map[string]string{}
+		for key, value := range issueTemplate.Labels {
+			labels[key] = value
+		}
+		labels[issueTemplateLabel] = templateLabelValue(issueTemplate)
+
+		child = &trainingv1alpha1.GithubIssue{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      childName(issueTemplate, repo),
+				Namespace: issueTemplate.Namespace,
+				Labels:    labels,
+			},
+			Spec: spec,
+		}
+		if err := controllerutil.SetControllerReference(issueTemplate, child, r.Scheme); err != nil {
+			return err
+		}
+
+		log.Info("Creating githubissue of githubissuetemplate", "repo", repo, "githubissue", child.Name)
+		return r.Create(ctx, child)
+	}
+
+	child.Spec.Repo = spec.Repo
+	child.Spec.Title = spec.Title
+	child.Spec.Description = spec.Description
+	child.Spec.Labels = spec.Labels
+
+	log.Info("Updating githubissue of githubissuetemplate", "repo", repo, "githubissue", child.Name)
+	return r.Update(ctx, child)
+}
+
+// this function checks whether the spec of an object matches the spec rendered from the template
+func (r *GithubIssueTemplateReconciler) isChildUpToDate(child *trainingv1alpha1.GithubIssue, spec trainingv1alpha1.GithubIssueSpec) bool {
+	return repoKey(child.Spec.Repo) == repoKey(spec.Repo) &&
+		child.Spec.Title == spec.Title &&
+		child.Spec.Description == spec.Description &&
+		equalUnordered(child.Spec.Labels, spec.Labels)
+}
+
+// this function checks whether an object was synced with its issue since its spec last changed
+func isGithubIssueSynced(githubissue *trainingv1alpha1.GithubIssue) bool {
+	return githubissue.Status.IssueNumber != 0 &&
+		githubissue.Status.ActiveTitle == githubissue.Spec.Title &&
+		githubissue.Status.ActiveDescription == githubissue.Spec.Description
+}
+
+// this function checks whether an object can't be synced with its issue, because its issue is
+// claimed, it violates a policy, its creation is throttled or its reconciliation failed. conditions
+// which were reported for an earlier generation of the object are not counted
+func isGithubIssueFailed(githubissue *trainingv1alpha1.GithubIssue) bool {
+	for _, condition := range githubissue.Status.Conditions {
+		if condition.ObservedGeneration != githubissue.Generation {
+			continue
+		}
+		switch {
+		case condition.Type == issueAdoptedConditionType && condition.Status == metav1.ConditionFalse,
+			condition.Type == policyViolationConditionType && condition.Status == metav1.ConditionTrue,
+			condition.Type == throttledConditionType && condition.Status == metav1.ConditionTrue,
+			condition.Type == syncFailedConditionType && condition.Status == metav1.ConditionTrue:
+			return true
+		}
+	}
+	return false
+}
+
+// this function returns the name of the object of a repository, it is derived
+// from the name of the template and a hash of the repository
+func childName(issueTemplate *trainingv1alpha1.GithubIssueTemplate, repo string) string {
+	hash := sha256.Sum256([]byte(repoKey(repo)))
+	return shortenName(issueTemplate.Name, validation.DNS1123SubdomainMaxLength-9) + "-" + hex.EncodeToString(hash[:])[:8]
+}
+
+// this function returns the value of the label of the objects of a template, the name of a
+// template which is too long for a label value is shortened and suffixed with its hash
+func templateLabelValue(issueTemplate *trainingv1alpha1.GithubIssueTemplate) string {
+	if len(issueTemplate.Name) <= validation.LabelValueMaxLength {
+		return issueTemplate.Name
+	}
+
+	hash := sha256.Sum256([]byte(issueTemplate.Name))
+	return shortenName(issueTemplate.Name, validation.LabelValueMaxLength-9) + "-" + hex.EncodeToString(hash[:])[:8]
+}
+
+// this function cuts a name to the given length, without leaving a
+// separator at its end which is followed by another separator
+func shortenName(name string, length int) string {
+	if len(name) <= length {
+		return name
+	}
+	return strings.TrimRight(name[:length], "-.")
+}
+
+// this function returns a key identifying a repository regardless of the URL format,
+// the repository is normalized the same way as by the webhook of the objects
+func repoKey(repo string) string {
+	if normalized, _, err := trainingv1alpha1.NormalizeRepo(repo); err == nil {
+		repo = normalized
+	}
+	owner, name, err := parseOwnerRepo(repo)
+	if err != nil {
+		return strings.ToLower(repo)
+	}
+	return strings.ToLower(owner + "/" + name)
+}
+
+// this function renders a go template with the given data,
+// referencing a field which does not exist is an error
+func renderTemplate(name, text string, data issueTemplateData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// this function checks whether all of the wanted topics are in the topics of a repository
+func hasTopics(topics, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, topic := range topics {
+			if strings.EqualFold(topic, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GithubIssueTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&trainingv1alpha1.GithubIssueTemplate{}, builder.WithPredicates(r.Scope.Predicate())).
+		Owns(&trainingv1alpha1.GithubIssue{}).
+		Complete(r)
+}
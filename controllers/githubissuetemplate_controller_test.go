@@ -0,0 +1,476 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	ghmock "github.com/migueleliasweb/go-github-mock/src/mock"
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// this function creates a template object filing an issue in the given repositories
+func generateGithubIssueTemplateObject(repos ...string) *trainingv1alpha1.GithubIssueTemplate {
+	return &trainingv1alpha1.GithubIssueTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GenerateRandomString(),
+			Namespace: testNamespace,
+			UID:       types.UID(GenerateRandomString()),
+		},
+		Spec: trainingv1alpha1.GithubIssueTemplateSpec{
+			Title:       "Upgrade dependencies of {{ .Name }}",
+			Description: "Dependencies of {{ .Owner }}/{{ .Name }} are outdated",
+			Labels:      []string{"dependencies"},
+			Repos:       repos,
+			MaxInFlight: 2,
+		},
+	}
+}
+
+// this function marks the objects of a template as synced with an open issue,
+// as if they were reconciled by the GithubIssueReconciler
+func markTemplateChildrenSynced(ctx context.Context, g *WithT, cl client.Client, issueTemplate *trainingv1alpha1.GithubIssueTemplate) int {
+	var githubissues trainingv1alpha1.GithubIssueList
+	g.Expect(cl.List(ctx, &githubissues, client.MatchingLabels{issueTemplateLabel: templateLabelValue(issueTemplate)})).To(Succeed())
+
+	for i := range githubissues.Items {
+		child := &githubissues.Items[i]
+		child.Status.IssueNumber = i + 1
+		child.Status.ActiveTitle = child.Spec.Title
+		child.Status.ActiveDescription = child.Spec.Description
+		apimeta.SetStatusCondition(&child.Status.Conditions, metav1.Condition{
+			Type:   issueOpenConditionType,
+			Status: metav1.ConditionTrue,
+			Reason: issueOpenConditionReason,
+		})
+		g.Expect(cl.Status().Update(ctx, child)).To(Succeed())
+	}
+	return len(githubissues.Items)
+}
+
+func TestFanOutIssueTemplate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	issueTemplate := generateGithubIssueTemplateObject(
+		"https://github.com/testOrg/repo-a",
+		"https://github.com/testOrg/repo-b",
+		"https://github.com/testOrg/repo-c",
+	)
+
+	obj := []client.Object{issueTemplate}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueTemplateReconciler{Client: cl, Scheme: s}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      issueTemplate.ObjectMeta.Name,
+			Namespace: issueTemplate.ObjectMeta.Namespace,
+		},
+	}
+
+	// only as many objects as allowed in flight are created at once
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(markTemplateChildrenSynced(ctx, g, cl, issueTemplate)).To(Equal(2))
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(markTemplateChildrenSynced(ctx, g, cl, issueTemplate)).To(Equal(3))
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the titles and descriptions are rendered for every repository
+	child := trainingv1alpha1.GithubIssue{}
+	childKey := types.NamespacedName{Name: childName(issueTemplate, "https://github.com/testOrg/repo-b"), Namespace: testNamespace}
+	g.Expect(cl.Get(ctx, childKey, &child)).To(Succeed())
+	g.Expect(child.Spec.Title).To(Equal("Upgrade dependencies of repo-b"))
+	g.Expect(child.Spec.Description).To(Equal("Dependencies of testOrg/repo-b are outdated"))
+	g.Expect(child.Spec.Labels).To(Equal([]string{"dependencies"}))
+	g.Expect(metav1.IsControlledBy(&child, issueTemplate)).To(BeTrue())
+
+	issueTemplateReconciled := trainingv1alpha1.GithubIssueTemplate{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &issueTemplateReconciled)).To(Succeed())
+	g.Expect(issueTemplateReconciled.Status.Repos).To(Equal(3))
+	g.Expect(issueTemplateReconciled.Status.UpdatedRepos).To(Equal(3))
+	g.Expect(issueTemplateReconciled.Status.OpenIssues).To(Equal(3))
+	g.Expect(apimeta.IsStatusConditionTrue(issueTemplateReconciled.Status.Conditions, rolloutCompleteConditionType)).To(BeTrue())
+}
+
+func TestRolloutIssueTemplateChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	issueTemplate := generateGithubIssueTemplateObject(
+		"https://github.com/testOrg/repo-a",
+		"https://github.com/testOrg/repo-b",
+		"https://github.com/testOrg/repo-c",
+	)
+	issueTemplate.Spec.MaxInFlight = 3
+
+	obj := []client.Object{issueTemplate}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueTemplateReconciler{Client: cl, Scheme: s}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      issueTemplate.ObjectMeta.Name,
+			Namespace: issueTemplate.ObjectMeta.Namespace,
+		},
+	}
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(markTemplateChildrenSynced(ctx, g, cl, issueTemplate)).To(Equal(3))
+
+	// change the title and stop targeting one of the repositories
+	g.Expect(cl.Get(ctx, req.NamespacedName, issueTemplate)).To(Succeed())
+	issueTemplate.Spec.Title = "Bump dependencies of {{ .Name }}"
+	issueTemplate.Spec.Repos = issueTemplate.Spec.Repos[:2]
+	issueTemplate.Spec.MaxInFlight = 1
+	g.Expect(cl.Update(ctx, issueTemplate)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the object of the repository which is no longer targeted is deleted
+	// and only one object is updated at a time
+	var githubissues trainingv1alpha1.GithubIssueList
+	g.Expect(cl.List(ctx, &githubissues, client.MatchingLabels{issueTemplateLabel: templateLabelValue(issueTemplate)})).To(Succeed())
+	g.Expect(githubissues.Items).To(HaveLen(2))
+
+	renamed := 0
+	for _, child := range githubissues.Items {
+		if child.Spec.Title == "Bump dependencies of "+child.Spec.Repo[len("https://github.com/testOrg/"):] {
+			renamed++
+		}
+	}
+	g.Expect(renamed).To(Equal(1))
+
+	issueTemplateReconciled := trainingv1alpha1.GithubIssueTemplate{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &issueTemplateReconciled)).To(Succeed())
+	g.Expect(issueTemplateReconciled.Status.Repos).To(Equal(2))
+	g.Expect(issueTemplateReconciled.Status.UpdatedRepos).To(Equal(0))
+	g.Expect(apimeta.IsStatusConditionFalse(issueTemplateReconciled.Status.Conditions, rolloutCompleteConditionType)).To(BeTrue())
+}
+
+func TestCountFailedIssuesOfTemplate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	issueTemplate := generateGithubIssueTemplateObject(
+		"https://github.com/testOrg/repo-a",
+		"https://github.com/testOrg/repo-b",
+		"https://github.com/testOrg/repo-c",
+	)
+	issueTemplate.Spec.MaxInFlight = 3
+
+	obj := []client.Object{issueTemplate}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueTemplateReconciler{Client: cl, Scheme: s}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      issueTemplate.ObjectMeta.Name,
+			Namespace: issueTemplate.ObjectMeta.Namespace,
+		},
+	}
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(markTemplateChildrenSynced(ctx, g, cl, issueTemplate)).To(Equal(3))
+
+	// the reconciliation of the object of repo-a failed, the object of repo-b violated a
+	// policy in an earlier generation and the issue of repo-c is claimed by another object
+	setChildCondition := func(repo string, synced bool, condition metav1.Condition) {
+		child := trainingv1alpha1.GithubIssue{}
+		childKey := types.NamespacedName{Name: childName(issueTemplate, repo), Namespace: testNamespace}
+		g.Expect(cl.Get(ctx, childKey, &child)).To(Succeed())
+		if !synced {
+			child.Status.ActiveTitle = ""
+		}
+		apimeta.SetStatusCondition(&child.Status.Conditions, condition)
+		g.Expect(cl.Status().Update(ctx, &child)).To(Succeed())
+	}
+	setChildCondition("https://github.com/testOrg/repo-a", true, metav1.Condition{
+		Type:   syncFailedConditionType,
+		Status: metav1.ConditionTrue,
+		Reason: syncFailedConditionReason,
+	})
+	setChildCondition("https://github.com/testOrg/repo-b", true, metav1.Condition{
+		Type:               policyViolationConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             policyViolatedConditionReason,
+		ObservedGeneration: 7,
+	})
+	setChildCondition("https://github.com/testOrg/repo-c", false, metav1.Condition{
+		Type:   issueAdoptedConditionType,
+		Status: metav1.ConditionFalse,
+		Reason: issueClaimedConditionReason,
+	})
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// every object is counted once, failed objects are neither updated nor open
+	issueTemplateReconciled := trainingv1alpha1.GithubIssueTemplate{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &issueTemplateReconciled)).To(Succeed())
+	g.Expect(issueTemplateReconciled.Status.Repos).To(Equal(3))
+	g.Expect(issueTemplateReconciled.Status.UpdatedRepos).To(Equal(1))
+	g.Expect(issueTemplateReconciled.Status.FailedIssues).To(Equal(2))
+	g.Expect(issueTemplateReconciled.Status.OpenIssues).To(Equal(1))
+	g.Expect(apimeta.IsStatusConditionFalse(issueTemplateReconciled.Status.Conditions, rolloutCompleteConditionType)).To(BeTrue())
+}
+
+func TestIssueTemplateWithNonCanonicalRepos(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	issueTemplate := generateGithubIssueTemplateObject(
+		"testOrg/repo-a",
+		"http://github.com/testOrg/repo-b/",
+		"https://github.com/TestOrg/Repo-C.git",
+	)
+	issueTemplate.Spec.MaxInFlight = 3
+
+	obj := []client.Object{issueTemplate}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueTemplateReconciler{Client: cl, Scheme: s}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      issueTemplate.ObjectMeta.Name,
+			Namespace: issueTemplate.ObjectMeta.Namespace,
+		},
+	}
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the repositories of the objects are normalized, as if they were admitted by the webhook
+	var githubissues trainingv1alpha1.GithubIssueList
+	g.Expect(cl.List(ctx, &githubissues, client.MatchingLabels{issueTemplateLabel: templateLabelValue(issueTemplate)})).To(Succeed())
+	g.Expect(githubissues.Items).To(HaveLen(3))
+	for i := range githubissues.Items {
+		child := &githubissues.Items[i]
+		child.Spec.Repo, _, err = trainingv1alpha1.NormalizeRepo(child.Spec.Repo)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(cl.Update(ctx, child)).To(Succeed())
+	}
+	g.Expect(markTemplateChildrenSynced(ctx, g, cl, issueTemplate)).To(Equal(3))
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the normalized objects match the template and are left untouched
+	child := trainingv1alpha1.GithubIssue{}
+	childKey := types.NamespacedName{Name: childName(issueTemplate, "https://github.com/TestOrg/Repo-C.git"), Namespace: testNamespace}
+	g.Expect(cl.Get(ctx, childKey, &child)).To(Succeed())
+	g.Expect(child.Spec.Repo).To(Equal("https://github.com/TestOrg/Repo-C"))
+
+	issueTemplateReconciled := trainingv1alpha1.GithubIssueTemplate{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &issueTemplateReconciled)).To(Succeed())
+	g.Expect(issueTemplateReconciled.Status.UpdatedRepos).To(Equal(3))
+	g.Expect(apimeta.IsStatusConditionTrue(issueTemplateReconciled.Status.Conditions, rolloutCompleteConditionType)).To(BeTrue())
+}
+
+func TestInvalidIssueTemplate(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	issueTemplate := generateGithubIssueTemplateObject("https://github.com/testOrg/repo-a")
+	issueTemplate.Spec.Title = "Upgrade dependencies of {{ .Missing }}"
+
+	obj := []client.Object{issueTemplate}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueTemplateReconciler{Client: cl, Scheme: s}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      issueTemplate.ObjectMeta.Name,
+			Namespace: issueTemplate.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	issueTemplateReconciled := trainingv1alpha1.GithubIssueTemplate{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &issueTemplateReconciled)).To(Succeed())
+	condition := apimeta.FindStatusCondition(issueTemplateReconciled.Status.Conditions, rolloutCompleteConditionType)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Reason).To(Equal(invalidTemplateConditionReason))
+}
+
+func TestIssueTemplateWithInvalidRepo(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	// a repository without an owner can't be targeted
+	issueTemplate := generateGithubIssueTemplateObject("https://github.com/testOrg/repo-a", "foo")
+
+	obj := []client.Object{issueTemplate}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueTemplateReconciler{Client: cl, Scheme: s}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      issueTemplate.ObjectMeta.Name,
+			Namespace: issueTemplate.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	issueTemplateReconciled := trainingv1alpha1.GithubIssueTemplate{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &issueTemplateReconciled)).To(Succeed())
+	condition := apimeta.FindStatusCondition(issueTemplateReconciled.Status.Conditions, rolloutCompleteConditionType)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Reason).To(Equal(invalidTemplateConditionReason))
+	g.Expect(condition.Message).To(ContainSubstring(`"foo"`))
+}
+
+func TestIssueTemplateWithLongName(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	// the name of the template is longer than a label value may be
+	issueTemplate := generateGithubIssueTemplateObject("https://github.com/testOrg/repo-a")
+	issueTemplate.Name = strings.Repeat("long-template-name-", 10) + "x"
+
+	obj := []client.Object{issueTemplate}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueTemplateReconciler{Client: cl, Scheme: s}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      issueTemplate.ObjectMeta.Name,
+			Namespace: issueTemplate.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(markTemplateChildrenSynced(ctx, g, cl, issueTemplate)).To(Equal(1))
+
+	var githubissues trainingv1alpha1.GithubIssueList
+	g.Expect(cl.List(ctx, &githubissues)).To(Succeed())
+	g.Expect(githubissues.Items).To(HaveLen(1))
+	g.Expect(validation.IsValidLabelValue(githubissues.Items[0].Labels[issueTemplateLabel])).To(BeEmpty())
+	g.Expect(validation.IsDNS1123Subdomain(githubissues.Items[0].Name)).To(BeEmpty())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	issueTemplateReconciled := trainingv1alpha1.GithubIssueTemplate{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &issueTemplateReconciled)).To(Succeed())
+	g.Expect(issueTemplateReconciled.Status.UpdatedRepos).To(Equal(1))
+}
+
+func TestSelectIssueTemplateRepos(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	issueTemplate := generateGithubIssueTemplateObject("https://github.com/testOrg/repo-a")
+	issueTemplate.Spec.RepoSelector = &trainingv1alpha1.RepoSelector{
+		Owner:       testOwnerName,
+		NamePattern: "^repo-",
+		Topics:      []string{"operator"},
+	}
+
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetOrgsReposByOrg,
+			[]github.Repository{
+				{
+					Name:    github.String("repo-a"),
+					HTMLURL: github.String("https://github.com/testOrg/repo-a"),
+					Topics:  []string{"operator"},
+				},
+				{
+					Name:    github.String("repo-b"),
+					HTMLURL: github.String("https://github.com/testOrg/repo-b"),
+					Topics:  []string{"operator", "go"},
+				},
+				{
+					Name:     github.String("repo-c"),
+					HTMLURL:  github.String("https://github.com/testOrg/repo-c"),
+					Topics:   []string{"operator"},
+					Archived: github.Bool(true),
+				},
+				{
+					Name:    github.String("repo-d"),
+					HTMLURL: github.String("https://github.com/testOrg/repo-d"),
+				},
+				{
+					Name:    github.String("website"),
+					HTMLURL: github.String("https://github.com/testOrg/website"),
+					Topics:  []string{"operator"},
+				},
+			},
+		),
+	)
+
+	r := &GithubIssueTemplateReconciler{GithubClient: github.NewClient(mockedHTTPClient)}
+
+	repos, err := r.getTargetRepos(ctx, issueTemplate)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(repos).To(Equal([]string{
+		"https://github.com/testOrg/repo-a",
+		"https://github.com/testOrg/repo-b",
+	}))
+}
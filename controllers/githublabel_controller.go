@@ -0,0 +1,351 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// GithubLabelReconciler reconciles a GithubLabel object
+type GithubLabelReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	GithubClient *github.Client
+
+	// Scope restricts the objects handled by the reconciler
+	Scope Scope
+}
+
+const (
+	ghLabelFinalizer string = "redhat.com/githublabel-finalizer"
+
+	labelSyncedConditionType   string = "LabelSynced"
+	labelSyncedConditionReason string = "LabelInSync"
+)
+
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githublabels,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githublabels/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githublabels/finalizers,verbs=update
+
+// Reconcile creates the label described by a GithubLabel object in its repository,
+// keeps its name, color and description in sync and deletes it with the object.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *GithubLabelReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Processing GithubLabelReconciler")
+
+	// fetch githublabel object
+	var githublabel trainingv1alpha1.GithubLabel
+	if err := r.Get(ctx, req.NamespacedName, &githublabel); err != nil {
+		if errors.IsNotFound(err) {
+			// request object not found, could have been deleted after reconcile request
+			// return and don't requeue
+			log.Info("GithubLabel resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// error reading the object - request
+		log.Error(err, "unable to fetch githublabel")
+		return ctrl.Result{}, err
+	}
+
+	ghClient := r.GithubClient
+	if ghClient == nil {
+		err := fmt.Errorf("github client is not available")
+		return ctrl.Result{}, err
+	}
+
+	// examine DeletionTimestamp to determine if object is under deletion
+	if !githublabel.ObjectMeta.DeletionTimestamp.IsZero() {
+		if err := r.deleteFinalizer(ctx, &githublabel, ghClient); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// the object is not being deleted, so if it does not have a finalizer,
+	// then lets add the finalizer and update the object
+	if err := r.addFinalizer(ctx, &githublabel); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	owner, repo, err := parseOwnerRepo(githublabel.Spec.Repo)
+	if err != nil {
+		log.Error(err, "unable to parse repository of githublabel", "repo", githublabel.Spec.Repo)
+		return ctrl.Result{}, err
+	}
+
+	// the label is looked up by the name it was last synced with,
+	// so that a renamed label is edited instead of created again
+	label, err := r.findLabel(ctx, ghClient, &githublabel, owner, repo)
+	if err != nil {
+		log.Error(err, "unable to fetch label from github repository", "owner", owner, "repo", repo)
+		return ctrl.Result{}, err
+	}
+
+	// a label which already exists in the repository is adopted, it is synced
+	// with the object but it is not deleted with it
+	if label == nil {
+		label, err = r.createLabel(ctx, ghClient, &githublabel, owner, repo)
+		if err != nil {
+			log.Error(err, "failed to create label on github repository", "owner", owner, "repo", repo)
+			return ctrl.Result{}, err
+		}
+
+		// the created label is recorded before anything else is done, so that
+		// it is still deleted with the object if a later step fails
+		githublabel.Status.Created = true
+		githublabel.Status.ActiveName = label.GetName()
+		if err := r.Status().Update(ctx, &githublabel); err != nil {
+			log.Error(err, "unable to update githublabel status")
+			return ctrl.Result{}, err
+		}
+	} else if r.isLabelOutOfSync(label, &githublabel) {
+		label, err = r.editLabel(ctx, ghClient, label.GetName(), &githublabel, owner, repo)
+		if err != nil {
+			log.Error(err, "failed to update label on github repository", "owner", owner, "repo", repo)
+			return ctrl.Result{}, err
+		}
+	}
+
+	githublabel.Status.ActiveName = label.GetName()
+	githublabel.Status.ActiveColor = label.GetColor()
+	githublabel.Status.ActiveDescription = label.GetDescription()
+	r.setLabelSyncedCondition(&githublabel)
+
+	log.Info("Updating githublabel status")
+	if err := r.Status().Update(ctx, &githublabel); err != nil {
+		log.Error(err, "unable to update githublabel status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// this function sets the condition of the object that indicates
+// whether the label on github matches the spec
+func (r *GithubLabelReconciler) setLabelSyncedCondition(githublabel *trainingv1alpha1.GithubLabel) {
+	labelCondition := metav1.Condition{
+		Type:    labelSyncedConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  labelSyncedConditionReason,
+		Message: "The label matches the spec",
+	}
+
+	apimeta.SetStatusCondition(&githublabel.Status.Conditions, labelCondition)
+}
+
+// this function handles the deletion of a finalizer to an object, a label which was created
+// by the operator is deleted from the repository before the finalizer is removed, while an
+// adopted label is left in the repository along with its uses on issues and pull requests
+func (r *GithubLabelReconciler) deleteFinalizer(ctx context.Context, githublabel *trainingv1alpha1.GithubLabel, ghClient *github.Client) error {
+	log := log.FromContext(ctx)
+	log.Info("Handling finalizer deletion")
+
+	if controllerutil.ContainsFinalizer(githublabel, ghLabelFinalizer) {
+		owner, repo, err := parseOwnerRepo(githublabel.Spec.Repo)
+		name := githublabel.Status.ActiveName
+		if name == "" {
+			name = githublabel.Spec.Name
+		}
+
+		if err != nil {
+			log.Info("Repository of githublabel can't be parsed, not deleting a label", "repo", githublabel.Spec.Repo)
+		} else if githublabel.Status.Created {
+			if err := r.deleteLabel(ctx, ghClient, name, owner, repo); err != nil {
+				log.Error(err, "failed to delete label", "owner", owner, "repo", repo, "label", name)
+				return err
+			}
+		} else {
+			log.Info("Label was not created by the operator, keeping it in the repository", "owner", owner, "repo", repo, "label", name)
+		}
+
+		controllerutil.RemoveFinalizer(githublabel, ghLabelFinalizer)
+		if err := r.Update(ctx, githublabel); err != nil {
+			log.Error(err, "failed to update githublabel")
+			return err
+		}
+	}
+	return nil
+}
+
+// this function handles the addition of a finalizer to an object
+func (r *GithubLabelReconciler) addFinalizer(ctx context.Context, githublabel *trainingv1alpha1.GithubLabel) error {
+	log := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(githublabel, ghLabelFinalizer) {
+		controllerutil.AddFinalizer(githublabel, ghLabelFinalizer)
+		if err := r.Update(ctx, githublabel); err != nil {
+			log.Error(err, "failed to update githublabel")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// this function returns the label linked to the object, it is looked up by the
+// name it was last synced with and then by the name in the spec.
+// nil is returned if no such label exists
+func (r *GithubLabelReconciler) findLabel(ctx context.Context, ghClient *github.Client, githublabel *trainingv1alpha1.GithubLabel, owner, repo string) (*github.Label, error) {
+	if activeName := githublabel.Status.ActiveName; activeName != "" && activeName != githublabel.Spec.Name {
+		label, err := r.getLabel(ctx, ghClient, activeName, owner, repo)
+		if err != nil || label != nil {
+			return label, err
+		}
+	}
+
+	return r.getLabel(ctx, ghClient, githublabel.Spec.Name, owner, repo)
+}
+
+// this function returns a single label in a repository by its name,
+// nil is returned if the label does not exist
+func (r *GithubLabelReconciler) getLabel(ctx context.Context, ghClient *github.Client, name, owner, repo string) (*github.Label, error) {
+	log := log.FromContext(ctx)
+
+	label, response, err := ghClient.Issues.GetLabel(ctx, owner, repo, url.PathEscape(name))
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Error(err, "unable to fetch label from github", "label", name)
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return nil, err
+	}
+
+	return label, nil
+}
+
+// this function creates a new label from the spec of the object
+func (r *GithubLabelReconciler) createLabel(ctx context.Context, ghClient *github.Client, githublabel *trainingv1alpha1.GithubLabel, owner, repo string) (*github.Label, error) {
+	log := log.FromContext(ctx)
+
+	label, response, err := ghClient.Issues.CreateLabel(ctx, owner, repo, r.labelFromSpec(githublabel))
+
+	if err != nil {
+		log.Error(err, "unable to create label")
+		return label, err
+	}
+
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return label, err
+	}
+
+	return label, nil
+}
+
+// this function updates the label with the given name to match the spec of the
+// object, which renames the label if the name in the spec is different
+func (r *GithubLabelReconciler) editLabel(ctx context.Context, ghClient *github.Client, name string, githublabel *trainingv1alpha1.GithubLabel, owner, repo string) (*github.Label, error) {
+	log := log.FromContext(ctx)
+
+	label, response, err := ghClient.Issues.EditLabel(ctx, owner, repo, url.PathEscape(name), r.labelFromSpec(githublabel))
+
+	if err != nil {
+		log.Error(err, "unable to update label")
+		return label, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return label, err
+	}
+
+	return label, nil
+}
+
+// this function deletes a label from a repository,
+// a label which does not exist anymore is ignored
+func (r *GithubLabelReconciler) deleteLabel(ctx context.Context, ghClient *github.Client, name, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	response, err := ghClient.Issues.DeleteLabel(ctx, owner, repo, url.PathEscape(name))
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if err != nil {
+		log.Error(err, "unable to delete label")
+		return err
+	}
+
+	if response.StatusCode != http.StatusNoContent {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return err
+	}
+
+	return nil
+}
+
+// this function checks whether the name, color or description
+// of a label differ from the spec of the object
+func (r *GithubLabelReconciler) isLabelOutOfSync(label *github.Label, githublabel *trainingv1alpha1.GithubLabel) bool {
+	spec := githublabel.Spec
+	if label.GetName() != spec.Name {
+		return true
+	}
+	if spec.Color != "" && !strings.EqualFold(label.GetColor(), spec.Color) {
+		return true
+	}
+	return spec.Description != "" && label.GetDescription() != spec.Description
+}
+
+// this function builds the label request from the spec of the object,
+// an empty color or description leaves the current value untouched
+func (r *GithubLabelReconciler) labelFromSpec(githublabel *trainingv1alpha1.GithubLabel) *github.Label {
+	label := &github.Label{
+		Name: github.String(githublabel.Spec.Name),
+	}
+	if githublabel.Spec.Color != "" {
+		label.Color = github.String(githublabel.Spec.Color)
+	}
+	if githublabel.Spec.Description != "" {
+		label.Description = github.String(githublabel.Spec.Description)
+	}
+	return label
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GithubLabelReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&trainingv1alpha1.GithubLabel{}, builder.WithPredicates(r.Scope.Predicate())).
+		Complete(r)
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	ghmock "github.com/migueleliasweb/go-github-mock/src/mock"
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	"github.com/mzeevi/githubissues-operator/internal/githubfake"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCreateLabelIfDoesntExist(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	// create githublabel object
+	githubLabel := GenerateGithubLabelObject()
+
+	obj := []client.Object{githubLabel}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the label does not exist yet, so it has to be created
+	var createdLabel github.Label
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatchHandler(
+			ghmock.GetReposLabelsByOwnerByRepoByName,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ghmock.WriteError(w, http.StatusNotFound, "Not Found")
+			}),
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PostReposLabelsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(json.NewDecoder(r.Body).Decode(&createdLabel)).To(Succeed())
+				w.WriteHeader(http.StatusCreated)
+				w.Write(ghmock.MustMarshal(createdLabel))
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	r := &GithubLabelReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubLabel.ObjectMeta.Name,
+			Namespace: githubLabel.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(createdLabel.GetName()).To(Equal(githubLabel.Spec.Name))
+	g.Expect(createdLabel.GetColor()).To(Equal(githubLabel.Spec.Color))
+	g.Expect(createdLabel.GetDescription()).To(Equal(githubLabel.Spec.Description))
+
+	githubLabelReconciled := trainingv1alpha1.GithubLabel{}
+	err = cl.Get(ctx, req.NamespacedName, &githubLabelReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(githubLabelReconciled.Status.ActiveName).To(Equal(githubLabel.Spec.Name))
+	g.Expect(apimeta.IsStatusConditionTrue(githubLabelReconciled.Status.Conditions, labelSyncedConditionType)).To(BeTrue())
+}
+
+func TestRenameLabelOnNameChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	// the object was synced before under a different name
+	githubLabel := GenerateGithubLabelObject()
+	oldName := "old-" + githubLabel.Spec.Name
+	githubLabel.Status.ActiveName = oldName
+
+	obj := []client.Object{githubLabel}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var editedName string
+	var editedLabel github.Label
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposLabelsByOwnerByRepoByName,
+			github.Label{
+				Name:        github.String(oldName),
+				Color:       github.String("ffffff"),
+				Description: github.String(githubLabel.Spec.Description),
+			},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PatchReposLabelsByOwnerByRepoByName,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				editedName = r.URL.Path
+				g.Expect(json.NewDecoder(r.Body).Decode(&editedLabel)).To(Succeed())
+				w.Write(ghmock.MustMarshal(editedLabel))
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	r := &GithubLabelReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubLabel.ObjectMeta.Name,
+			Namespace: githubLabel.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the label is edited under its old name instead of being created again
+	g.Expect(editedName).To(HaveSuffix("/labels/" + oldName))
+	g.Expect(editedLabel.GetName()).To(Equal(githubLabel.Spec.Name))
+	g.Expect(editedLabel.GetColor()).To(Equal(githubLabel.Spec.Color))
+
+	githubLabelReconciled := trainingv1alpha1.GithubLabel{}
+	err = cl.Get(ctx, req.NamespacedName, &githubLabelReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(githubLabelReconciled.Status.ActiveName).To(Equal(githubLabel.Spec.Name))
+}
+
+func TestDeleteLabelOnDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	githubLabel := GenerateGithubLabelObject()
+
+	obj := []client.Object{githubLabel}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubLabelReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubLabel.ObjectMeta.Name,
+			Namespace: githubLabel.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(server.Labels(testOwnerName, testRepoName)).To(HaveLen(1))
+
+	// delete the object and call reconcile again
+	githubLabelReconciled := trainingv1alpha1.GithubLabel{}
+	err = cl.Get(ctx, req.NamespacedName, &githubLabelReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(githubLabelReconciled.Status.Created).To(BeTrue())
+	g.Expect(cl.Delete(ctx, &githubLabelReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the label was created by the operator, so it is deleted with the object
+	g.Expect(server.Labels(testOwnerName, testRepoName)).To(BeEmpty())
+
+	// the finalizer was removed, so the object is gone
+	err = cl.Get(ctx, req.NamespacedName, &githubLabelReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestSyncLabelWithSlashInName(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	githubLabel := GenerateGithubLabelObject()
+	githubLabel.Spec.Name = "kind/bug"
+
+	obj := []client.Object{githubLabel}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubLabelReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubLabel.ObjectMeta.Name,
+			Namespace: githubLabel.ObjectMeta.Namespace,
+		},
+	}
+
+	// the label is found by its escaped name, so it isn't created again
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	labels := server.Labels(testOwnerName, testRepoName)
+	g.Expect(labels).To(HaveLen(1))
+	g.Expect(labels[0].Name).To(Equal("kind/bug"))
+
+	githubLabelReconciled := trainingv1alpha1.GithubLabel{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubLabelReconciled)).To(Succeed())
+	g.Expect(cl.Delete(ctx, &githubLabelReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(server.Labels(testOwnerName, testRepoName)).To(BeEmpty())
+}
+
+func TestKeepAdoptedLabelOnDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	// the label already exists in the repository before the object is created
+	githubLabel := GenerateGithubLabelObject()
+	_, _, err := server.Client().Issues.CreateLabel(ctx, testOwnerName, testRepoName, &github.Label{Name: github.String(githubLabel.Spec.Name)})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	obj := []client.Object{githubLabel}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubLabelReconciler{Client: cl, Scheme: s, GithubClient: server.Client()}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubLabel.ObjectMeta.Name,
+			Namespace: githubLabel.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	githubLabelReconciled := trainingv1alpha1.GithubLabel{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubLabelReconciled)).To(Succeed())
+	g.Expect(githubLabelReconciled.Status.Created).To(BeFalse())
+	g.Expect(cl.Delete(ctx, &githubLabelReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the adopted label is left in the repository, while the object is gone
+	labels := server.Labels(testOwnerName, testRepoName)
+	g.Expect(labels).To(HaveLen(1))
+	g.Expect(labels[0].Name).To(Equal(githubLabel.Spec.Name))
+	for _, request := range server.Requests() {
+		g.Expect(request).ToNot(HavePrefix(http.MethodDelete))
+	}
+
+	err = cl.Get(ctx, req.NamespacedName, &githubLabelReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
@@ -0,0 +1,429 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// GithubMilestoneReconciler reconciles a GithubMilestone object
+type GithubMilestoneReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	GithubClient *github.Client
+
+	// Scope restricts the objects handled by the reconciler
+	Scope Scope
+}
+
+const (
+	ghMilestoneFinalizer string = "redhat.com/githubmilestone-finalizer"
+
+	milestoneSyncedConditionType   string = "MilestoneSynced"
+	milestoneSyncedConditionReason string = "MilestoneInSync"
+
+	// dueOnFormat is the precision due dates of milestones are compared
+	// with, github only keeps the date of a due date
+	dueOnFormat string = "2006-01-02"
+)
+
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubmilestones,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubmilestones/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=training.redhat.com,resources=githubmilestones/finalizers,verbs=update
+
+// Reconcile creates the milestone described by a GithubMilestone object in its repository,
+// keeps it in sync with the spec, reports its progress and deletes it with the object.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.12.1/pkg/reconcile
+func (r *GithubMilestoneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Processing GithubMilestoneReconciler")
+
+	// fetch githubmilestone object
+	var githubmilestone trainingv1alpha1.GithubMilestone
+	if err := r.Get(ctx, req.NamespacedName, &githubmilestone); err != nil {
+		if errors.IsNotFound(err) {
+			// request object not found, could have been deleted after reconcile request
+			// return and don't requeue
+			log.Info("GithubMilestone resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// error reading the object - request
+		log.Error(err, "unable to fetch githubmilestone")
+		return ctrl.Result{}, err
+	}
+
+	ghClient := r.GithubClient
+	if ghClient == nil {
+		err := fmt.Errorf("github client is not available")
+		return ctrl.Result{}, err
+	}
+
+	// examine DeletionTimestamp to determine if object is under deletion
+	if !githubmilestone.ObjectMeta.DeletionTimestamp.IsZero() {
+		if err := r.deleteFinalizer(ctx, &githubmilestone, ghClient); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// the object is not being deleted, so if it does not have a finalizer,
+	// then lets add the finalizer and update the object
+	if err := r.addFinalizer(ctx, &githubmilestone); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	owner, repo, err := parseOwnerRepo(githubmilestone.Spec.Repo)
+	if err != nil {
+		log.Error(err, "unable to parse repository of githubmilestone", "repo", githubmilestone.Spec.Repo)
+		return ctrl.Result{}, err
+	}
+
+	milestone, err := r.findMilestone(ctx, ghClient, &githubmilestone, owner, repo)
+	if err != nil {
+		log.Error(err, "unable to fetch milestones from github repository", "owner", owner, "repo", repo)
+		return ctrl.Result{}, err
+	}
+
+	// a milestone which already exists in the repository is adopted, it is synced
+	// with the object but it is not deleted with it
+	if milestone == nil {
+		milestone, err = r.createMilestone(ctx, ghClient, &githubmilestone, owner, repo)
+		if err != nil {
+			log.Error(err, "failed to create milestone on github repository", "owner", owner, "repo", repo)
+			return ctrl.Result{}, err
+		}
+
+		// the created milestone is recorded before anything else is done, so
+		// that it is still deleted with the object if a later step fails
+		githubmilestone.Status.Created = true
+		githubmilestone.Status.MilestoneNumber = milestone.GetNumber()
+		if err := r.Status().Update(ctx, &githubmilestone); err != nil {
+			log.Error(err, "unable to update githubmilestone status")
+			return ctrl.Result{}, err
+		}
+	} else if r.isMilestoneOutOfSync(milestone, &githubmilestone) {
+		milestone, err = r.editMilestone(ctx, ghClient, milestone.GetNumber(), &githubmilestone, owner, repo)
+		if err != nil {
+			log.Error(err, "failed to update milestone on github repository", "owner", owner, "repo", repo)
+			return ctrl.Result{}, err
+		}
+	}
+
+	githubmilestone.Status.MilestoneNumber = milestone.GetNumber()
+	githubmilestone.Status.ActiveTitle = milestone.GetTitle()
+	githubmilestone.Status.State = milestone.GetState()
+	githubmilestone.Status.OpenIssues = milestone.GetOpenIssues()
+	githubmilestone.Status.ClosedIssues = milestone.GetClosedIssues()
+	r.setMilestoneSyncedCondition(&githubmilestone)
+
+	log.Info("Updating githubmilestone status")
+	if err := r.Status().Update(ctx, &githubmilestone); err != nil {
+		log.Error(err, "unable to update githubmilestone status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// this function sets the condition of the object that indicates
+// whether the milestone on github matches the spec
+func (r *GithubMilestoneReconciler) setMilestoneSyncedCondition(githubmilestone *trainingv1alpha1.GithubMilestone) {
+	milestoneCondition := metav1.Condition{
+		Type:    milestoneSyncedConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  milestoneSyncedConditionReason,
+		Message: "The milestone matches the spec",
+	}
+
+	apimeta.SetStatusCondition(&githubmilestone.Status.Conditions, milestoneCondition)
+}
+
+// this function handles the deletion of a finalizer to an object, a milestone which was
+// created by the operator is deleted from the repository before the finalizer is removed,
+// while an adopted milestone is left in the repository
+func (r *GithubMilestoneReconciler) deleteFinalizer(ctx context.Context, githubmilestone *trainingv1alpha1.GithubMilestone, ghClient *github.Client) error {
+	log := log.FromContext(ctx)
+	log.Info("Handling finalizer deletion")
+
+	if controllerutil.ContainsFinalizer(githubmilestone, ghMilestoneFinalizer) {
+		owner, repo, err := parseOwnerRepo(githubmilestone.Spec.Repo)
+		if err != nil {
+			log.Info("Repository of githubmilestone can't be parsed, not deleting a milestone", "repo", githubmilestone.Spec.Repo)
+		} else if githubmilestone.Status.Created {
+			milestone, err := r.findMilestone(ctx, ghClient, githubmilestone, owner, repo)
+			if err != nil {
+				log.Error(err, "unable to fetch milestones from github repository", "owner", owner, "repo", repo)
+				return err
+			}
+
+			if milestone != nil {
+				if err := r.deleteMilestone(ctx, ghClient, milestone.GetNumber(), owner, repo); err != nil {
+					log.Error(err, "failed to delete milestone", "owner", owner, "repo", repo, "milestone", milestone.GetNumber())
+					return err
+				}
+			}
+		} else {
+			log.Info("Milestone was not created by the operator, keeping it in the repository", "owner", owner, "repo", repo, "milestone", githubmilestone.Status.MilestoneNumber)
+		}
+
+		controllerutil.RemoveFinalizer(githubmilestone, ghMilestoneFinalizer)
+		if err := r.Update(ctx, githubmilestone); err != nil {
+			log.Error(err, "failed to update githubmilestone")
+			return err
+		}
+	}
+	return nil
+}
+
+// this function handles the addition of a finalizer to an object
+func (r *GithubMilestoneReconciler) addFinalizer(ctx context.Context, githubmilestone *trainingv1alpha1.GithubMilestone) error {
+	log := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(githubmilestone, ghMilestoneFinalizer) {
+		controllerutil.AddFinalizer(githubmilestone, ghMilestoneFinalizer)
+		if err := r.Update(ctx, githubmilestone); err != nil {
+			log.Error(err, "failed to update githubmilestone")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// this function returns the milestone linked to the object, a milestone which was
+// already linked is fetched by its number, otherwise the milestone is looked up
+// by its title. nil is returned if no such milestone exists
+func (r *GithubMilestoneReconciler) findMilestone(ctx context.Context, ghClient *github.Client, githubmilestone *trainingv1alpha1.GithubMilestone, owner, repo string) (*github.Milestone, error) {
+	if number := githubmilestone.Status.MilestoneNumber; number != 0 {
+		return r.getMilestone(ctx, ghClient, number, owner, repo)
+	}
+
+	milestones, err := r.getMilestonesInRepo(ctx, ghClient, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, milestone := range milestones {
+		if milestone.GetTitle() == githubmilestone.Spec.Title {
+			return milestone, nil
+		}
+	}
+	return nil, nil
+}
+
+// this function returns a single milestone in a repository by its number,
+// nil is returned if the milestone does not exist
+func (r *GithubMilestoneReconciler) getMilestone(ctx context.Context, ghClient *github.Client, number int, owner, repo string) (*github.Milestone, error) {
+	log := log.FromContext(ctx)
+
+	milestone, response, err := ghClient.Issues.GetMilestone(ctx, owner, repo, number)
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Error(err, "unable to fetch milestone from github", "milestone", number)
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return nil, err
+	}
+
+	return milestone, nil
+}
+
+// this function returns the open and closed milestones in a repository, every page of the
+// milestones is fetched so that milestones beyond the first page are found by their title
+func (r *GithubMilestoneReconciler) getMilestonesInRepo(ctx context.Context, ghClient *github.Client, owner, repo string) ([]*github.Milestone, error) {
+	log := log.FromContext(ctx)
+
+	options := &github.MilestoneListOptions{
+		State:       "all",
+		ListOptions: github.ListOptions{PerPage: githubPerPage},
+	}
+
+	var milestones []*github.Milestone
+	for {
+		page, response, err := ghClient.Issues.ListMilestones(ctx, owner, repo, options)
+		if err != nil {
+			log.Error(err, "unable to fetch milestones from github")
+			return nil, err
+		}
+
+		if response.StatusCode != http.StatusOK {
+			err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+			return nil, err
+		}
+
+		milestones = append(milestones, page...)
+		if response.NextPage == 0 {
+			return milestones, nil
+		}
+		options.Page = response.NextPage
+	}
+}
+
+// this function creates a new milestone from the spec of the object
+func (r *GithubMilestoneReconciler) createMilestone(ctx context.Context, ghClient *github.Client, githubmilestone *trainingv1alpha1.GithubMilestone, owner, repo string) (*github.Milestone, error) {
+	log := log.FromContext(ctx)
+
+	milestone, response, err := ghClient.Issues.CreateMilestone(ctx, owner, repo, r.milestoneFromSpec(githubmilestone))
+
+	if err != nil {
+		log.Error(err, "unable to create milestone")
+		return milestone, err
+	}
+
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return milestone, err
+	}
+
+	return milestone, nil
+}
+
+// this function updates a milestone to match the spec of the object
+func (r *GithubMilestoneReconciler) editMilestone(ctx context.Context, ghClient *github.Client, number int, githubmilestone *trainingv1alpha1.GithubMilestone, owner, repo string) (*github.Milestone, error) {
+	log := log.FromContext(ctx)
+
+	// the request is built by hand because github.Milestone omits an empty
+	// due date, while a due date removed from the spec has to be cleared
+	spec := r.milestoneFromSpec(githubmilestone)
+	u := fmt.Sprintf("repos/%v/%v/milestones/%d", owner, repo, number)
+	request, err := ghClient.NewRequest(http.MethodPatch, u, &milestoneEditRequest{
+		Title:       spec.GetTitle(),
+		Description: spec.GetDescription(),
+		State:       spec.GetState(),
+		DueOn:       spec.DueOn,
+	})
+	if err != nil {
+		log.Error(err, "unable to build milestone update request")
+		return nil, err
+	}
+
+	milestone := new(github.Milestone)
+	response, err := ghClient.Do(ctx, request, milestone)
+
+	if err != nil {
+		log.Error(err, "unable to update milestone")
+		return milestone, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return milestone, err
+	}
+
+	return milestone, nil
+}
+
+// this function deletes a milestone from a repository,
+// a milestone which does not exist anymore is ignored
+func (r *GithubMilestoneReconciler) deleteMilestone(ctx context.Context, ghClient *github.Client, number int, owner, repo string) error {
+	log := log.FromContext(ctx)
+
+	response, err := ghClient.Issues.DeleteMilestone(ctx, owner, repo, number)
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if err != nil {
+		log.Error(err, "unable to delete milestone")
+		return err
+	}
+
+	if response.StatusCode != http.StatusNoContent {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return err
+	}
+
+	return nil
+}
+
+// this function checks whether the title, description, due date
+// or state of a milestone differ from the spec of the object
+func (r *GithubMilestoneReconciler) isMilestoneOutOfSync(milestone *github.Milestone, githubmilestone *trainingv1alpha1.GithubMilestone) bool {
+	spec := githubmilestone.Spec
+	if milestone.GetTitle() != spec.Title || milestone.GetDescription() != spec.Description {
+		return true
+	}
+	if spec.State != "" && milestone.GetState() != spec.State {
+		return true
+	}
+
+	specDueOn := ""
+	if spec.DueOn != nil {
+		specDueOn = spec.DueOn.UTC().Format(dueOnFormat)
+	}
+	milestoneDueOn := ""
+	if milestone.DueOn != nil {
+		milestoneDueOn = milestone.GetDueOn().UTC().Format(dueOnFormat)
+	}
+	return specDueOn != milestoneDueOn
+}
+
+// this type is the body of a milestone update request, the due date is always
+// sent so that a null due date removes it from the milestone
+type milestoneEditRequest struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state,omitempty"`
+	DueOn       *time.Time `json:"due_on"`
+}
+
+// this function builds the milestone request from the spec of the object
+func (r *GithubMilestoneReconciler) milestoneFromSpec(githubmilestone *trainingv1alpha1.GithubMilestone) *github.Milestone {
+	milestone := &github.Milestone{
+		Title:       github.String(githubmilestone.Spec.Title),
+		Description: github.String(githubmilestone.Spec.Description),
+	}
+	if githubmilestone.Spec.State != "" {
+		milestone.State = github.String(githubmilestone.Spec.State)
+	}
+	if githubmilestone.Spec.DueOn != nil {
+		dueOn := githubmilestone.Spec.DueOn.UTC()
+		milestone.DueOn = &dueOn
+	}
+	return milestone
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GithubMilestoneReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&trainingv1alpha1.GithubMilestone{}, builder.WithPredicates(r.Scope.Predicate())).
+		Complete(r)
+}
@@ -0,0 +1,417 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	ghmock "github.com/migueleliasweb/go-github-mock/src/mock"
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCreateMilestoneIfDoesntExist(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	// create githubmilestone object with a due date
+	githubMilestone := GenerateGithubMilestoneObject()
+	dueOn := metav1.NewTime(time.Date(2022, time.December, 31, 0, 0, 0, 0, time.UTC))
+	githubMilestone.Spec.DueOn = &dueOn
+
+	obj := []client.Object{githubMilestone}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// only another milestone exists, so the milestone has to be created
+	var createdMilestone github.Milestone
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposMilestonesByOwnerByRepo,
+			[]github.Milestone{
+				{
+					Number: github.Int(1),
+					Title:  github.String("Milestone 1"),
+					State:  github.String("open"),
+				},
+			},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PostReposMilestonesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(json.NewDecoder(r.Body).Decode(&createdMilestone)).To(Succeed())
+				createdMilestone.Number = github.Int(2)
+				createdMilestone.OpenIssues = github.Int(0)
+				createdMilestone.ClosedIssues = github.Int(0)
+				w.WriteHeader(http.StatusCreated)
+				w.Write(ghmock.MustMarshal(createdMilestone))
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	r := &GithubMilestoneReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubMilestone.ObjectMeta.Name,
+			Namespace: githubMilestone.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(createdMilestone.GetTitle()).To(Equal(githubMilestone.Spec.Title))
+	g.Expect(createdMilestone.GetDescription()).To(Equal(githubMilestone.Spec.Description))
+	g.Expect(createdMilestone.GetDueOn().Equal(dueOn.Time)).To(BeTrue())
+
+	githubMilestoneReconciled := trainingv1alpha1.GithubMilestone{}
+	err = cl.Get(ctx, req.NamespacedName, &githubMilestoneReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(githubMilestoneReconciled.Status.MilestoneNumber).To(Equal(2))
+	g.Expect(githubMilestoneReconciled.Status.Created).To(BeTrue())
+	g.Expect(apimeta.IsStatusConditionTrue(githubMilestoneReconciled.Status.Conditions, milestoneSyncedConditionType)).To(BeTrue())
+}
+
+func TestFindMilestoneOnLaterPage(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	githubMilestone := GenerateGithubMilestoneObject()
+
+	obj := []client.Object{githubMilestone}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the milestone with the title of the object is on the second page of
+	// the milestones, so it is found instead of being created again
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatchPages(
+			ghmock.GetReposMilestonesByOwnerByRepo,
+			[]github.Milestone{
+				{
+					Number: github.Int(1),
+					Title:  github.String("Milestone 1"),
+					State:  github.String("open"),
+				},
+			},
+			[]github.Milestone{
+				{
+					Number:      github.Int(31),
+					Title:       github.String(githubMilestone.Spec.Title),
+					Description: github.String(githubMilestone.Spec.Description),
+					State:       github.String("open"),
+				},
+			},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PostReposMilestonesByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("milestone was created although it exists")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	r := &GithubMilestoneReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubMilestone.ObjectMeta.Name,
+			Namespace: githubMilestone.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	githubMilestoneReconciled := trainingv1alpha1.GithubMilestone{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubMilestoneReconciled)).To(Succeed())
+	g.Expect(githubMilestoneReconciled.Status.MilestoneNumber).To(Equal(31))
+}
+
+func TestClearRemovedMilestoneDueOn(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	// the due date was removed from the spec of a milestone that has one
+	githubMilestone := GenerateGithubMilestoneObject()
+	githubMilestone.Spec.DueOn = nil
+	githubMilestone.Status.MilestoneNumber = 2
+
+	obj := []client.Object{githubMilestone}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	dueOn := time.Date(2022, time.December, 31, 0, 0, 0, 0, time.UTC)
+	var edits []map[string]interface{}
+
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposMilestonesByOwnerByRepoByMilestoneNumber,
+			github.Milestone{
+				Number:      github.Int(2),
+				Title:       github.String(githubMilestone.Spec.Title),
+				Description: github.String(githubMilestone.Spec.Description),
+				State:       github.String("open"),
+				DueOn:       &dueOn,
+			},
+			github.Milestone{
+				Number:      github.Int(2),
+				Title:       github.String(githubMilestone.Spec.Title),
+				Description: github.String(githubMilestone.Spec.Description),
+				State:       github.String("open"),
+			},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.PatchReposMilestonesByOwnerByRepoByMilestoneNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				edit := map[string]interface{}{}
+				g.Expect(json.NewDecoder(r.Body).Decode(&edit)).To(Succeed())
+				edits = append(edits, edit)
+
+				w.Write(ghmock.MustMarshal(github.Milestone{
+					Number:      github.Int(2),
+					Title:       github.String(githubMilestone.Spec.Title),
+					Description: github.String(githubMilestone.Spec.Description),
+					State:       github.String("open"),
+				}))
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	r := &GithubMilestoneReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubMilestone.ObjectMeta.Name,
+			Namespace: githubMilestone.ObjectMeta.Namespace,
+		},
+	}
+
+	// the due date is cleared once, the milestone is in sync afterwards
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(edits).To(HaveLen(1))
+	g.Expect(edits[0]).To(HaveKeyWithValue("due_on", BeNil()))
+}
+
+func TestReportMilestoneProgress(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	// the milestone was already created and is in sync with the spec
+	githubMilestone := GenerateGithubMilestoneObject()
+	githubMilestone.Status.MilestoneNumber = 2
+
+	obj := []client.Object{githubMilestone}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposMilestonesByOwnerByRepoByMilestoneNumber,
+			github.Milestone{
+				Number:       github.Int(2),
+				Title:        github.String(githubMilestone.Spec.Title),
+				Description:  github.String(githubMilestone.Spec.Description),
+				State:        github.String("open"),
+				OpenIssues:   github.Int(4),
+				ClosedIssues: github.Int(6),
+			},
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	r := &GithubMilestoneReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubMilestone.ObjectMeta.Name,
+			Namespace: githubMilestone.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	githubMilestoneReconciled := trainingv1alpha1.GithubMilestone{}
+	err = cl.Get(ctx, req.NamespacedName, &githubMilestoneReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(githubMilestoneReconciled.Status.OpenIssues).To(Equal(4))
+	g.Expect(githubMilestoneReconciled.Status.ClosedIssues).To(Equal(6))
+}
+
+func TestDeleteMilestoneOnDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	// the milestone was created by the operator
+	githubMilestone := GenerateGithubMilestoneObject()
+	githubMilestone.Status.MilestoneNumber = 2
+	githubMilestone.Status.Created = true
+
+	obj := []client.Object{githubMilestone}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var deletedPath string
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposMilestonesByOwnerByRepoByMilestoneNumber,
+			github.Milestone{
+				Number:      github.Int(2),
+				Title:       github.String(githubMilestone.Spec.Title),
+				Description: github.String(githubMilestone.Spec.Description),
+				State:       github.String("open"),
+			},
+			github.Milestone{
+				Number:      github.Int(2),
+				Title:       github.String(githubMilestone.Spec.Title),
+				Description: github.String(githubMilestone.Spec.Description),
+				State:       github.String("open"),
+			},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.DeleteReposMilestonesByOwnerByRepoByMilestoneNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deletedPath = r.URL.Path
+				w.WriteHeader(http.StatusNoContent)
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	r := &GithubMilestoneReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubMilestone.ObjectMeta.Name,
+			Namespace: githubMilestone.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// delete the object and call reconcile again
+	githubMilestoneReconciled := trainingv1alpha1.GithubMilestone{}
+	err = cl.Get(ctx, req.NamespacedName, &githubMilestoneReconciled)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cl.Delete(ctx, &githubMilestoneReconciled)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(deletedPath).To(HaveSuffix("/milestones/2"))
+
+	// the finalizer was removed, so the object is gone
+	err = cl.Get(ctx, req.NamespacedName, &githubMilestoneReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestKeepAdoptedMilestoneOnDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	githubMilestone := GenerateGithubMilestoneObject()
+
+	obj := []client.Object{githubMilestone}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// the milestone already exists in the repository, so it is adopted by its title
+	mockedHTTPClient := ghmock.NewMockedHTTPClient(
+		ghmock.WithRequestMatch(
+			ghmock.GetReposMilestonesByOwnerByRepo,
+			[]github.Milestone{
+				{
+					Number:      github.Int(2),
+					Title:       github.String(githubMilestone.Spec.Title),
+					Description: github.String(githubMilestone.Spec.Description),
+					State:       github.String("open"),
+				},
+			},
+		),
+		ghmock.WithRequestMatchHandler(
+			ghmock.DeleteReposMilestonesByOwnerByRepoByMilestoneNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("adopted milestone was deleted")
+				w.WriteHeader(http.StatusNoContent)
+			}),
+		),
+	)
+
+	ghClient := github.NewClient(mockedHTTPClient)
+
+	r := &GithubMilestoneReconciler{Client: cl, Scheme: s, GithubClient: ghClient}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubMilestone.ObjectMeta.Name,
+			Namespace: githubMilestone.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	githubMilestoneReconciled := trainingv1alpha1.GithubMilestone{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubMilestoneReconciled)).To(Succeed())
+	g.Expect(githubMilestoneReconciled.Status.MilestoneNumber).To(Equal(2))
+	g.Expect(githubMilestoneReconciled.Status.Created).To(BeFalse())
+
+	// the object is deleted while the adopted milestone is left in the repository
+	g.Expect(cl.Delete(ctx, &githubMilestoneReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	err = cl.Get(ctx, req.NamespacedName, &githubMilestoneReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
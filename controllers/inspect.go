@@ -0,0 +1,252 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+// IssueInspector works with the issues of GithubIssue objects outside of the reconciler,
+// finding them the same way the reconciler does. it is used by kubectl githubissue
+type IssueInspector struct {
+	GithubClient *github.Client
+	Trackers     map[string]IssueTracker
+}
+
+// FieldDiff is a field whose value in the spec of an object differs from its issue
+type FieldDiff struct {
+	Field string
+	Spec  string
+	Issue string
+}
+
+func (i *IssueInspector) reconciler() *GithubIssueReconciler {
+	return &GithubIssueReconciler{GithubClient: i.GithubClient, Trackers: i.Trackers}
+}
+
+// this function returns the issue tracker of a repository
+func (i *IssueInspector) repoTracker(repo string) (IssueTracker, error) {
+	return i.reconciler().getIssueTracker(&trainingv1alpha1.GithubIssue{Spec: trainingv1alpha1.GithubIssueSpec{Repo: repo}})
+}
+
+// this function returns the issue managed by the object and nil
+// if the reconciler would create a new issue for the object
+func (i *IssueInspector) LiveIssue(ctx context.Context, githubissue *trainingv1alpha1.GithubIssue) (*Issue, error) {
+	r := i.reconciler()
+
+	tracker, err := r.getIssueTracker(githubissue)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, repo, err := r.extractOwnerRepoInfo(githubissue)
+	if err != nil {
+		return nil, err
+	}
+	issue, err := r.findIssue(ctx, tracker, githubissue, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// an issue found by its title which is claimed by another object is not adopted
+	if issue != nil && githubissue.Status.IssueNumber == 0 && r.getRequestedIssueNumber(githubissue) == 0 && r.isIssueClaimedByAnother(issue, githubissue) {
+		return nil, nil
+	}
+
+	return issue, nil
+}
+
+// this function returns a GithubIssue object which adopts the issue with the given URL,
+// the spec of the object is filled from the issue so adopting it changes nothing
+func (i *IssueInspector) AdoptIssue(ctx context.Context, issueURL, namespace, name string) (*trainingv1alpha1.GithubIssue, error) {
+	repo, issueNumber, err := trainingv1alpha1.NormalizeRepo(issueURL)
+	if err != nil {
+		return nil, err
+	}
+	if issueNumber == 0 {
+		return nil, fmt.Errorf("%q is not the URL of an issue", issueURL)
+	}
+
+	tracker, err := i.repoTracker(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, repoName, err := parseOwnerRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+	issue, err := tracker.Get(ctx, owner, repoName, issueNumber)
+	if err != nil {
+		return nil, err
+	}
+	if issue == nil {
+		return nil, fmt.Errorf("issue %d was not found in %s", issueNumber, repo)
+	}
+	if claimedBy := getIssueMarkerOwner(issue.Body); claimedBy != "" {
+		return nil, fmt.Errorf("issue %d is already managed by %s", issueNumber, claimedBy)
+	}
+
+	return adoptingGithubIssue(repo, repoName, issue, namespace, name), nil
+}
+
+// this function returns GithubIssue objects which adopt the issues of a repository selected by
+// the filter. issues already managed by an object and pull requests on github are skipped
+func (i *IssueInspector) ImportIssues(ctx context.Context, repoURL string, filter IssueFilter, namespace string) ([]*trainingv1alpha1.GithubIssue, error) {
+	repo, _, err := trainingv1alpha1.NormalizeRepo(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker, err := i.repoTracker(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, repoName, err := parseOwnerRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+	issues, err := listIssues(ctx, tracker, owner, repoName, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	githubissues := []*trainingv1alpha1.GithubIssue{}
+	for _, issue := range issues {
+		if getIssueMarkerOwner(issue.Body) != "" || (isGithubTracker(tracker) && issue.HasPullRequest) {
+			continue
+		}
+		githubissues = append(githubissues, adoptingGithubIssue(repo, repoName, issue, namespace, ""))
+	}
+
+	return githubissues, nil
+}
+
+// this function returns the number of a milestone of a repository given by its number or, on github, by its title
+func (i *IssueInspector) ResolveMilestone(ctx context.Context, repoURL, milestone string) (int, error) {
+	if number, err := strconv.Atoi(milestone); err == nil {
+		return number, nil
+	}
+
+	repo, _, err := trainingv1alpha1.NormalizeRepo(repoURL)
+	if err != nil {
+		return 0, err
+	}
+
+	tracker, err := i.repoTracker(repo)
+	if err != nil {
+		return 0, err
+	}
+	if !isGithubTracker(tracker) {
+		return 0, fmt.Errorf("milestones are only found by their title on github, use the number of the milestone")
+	}
+
+	owner, repoName, err := parseOwnerRepo(repo)
+	if err != nil {
+		return 0, err
+	}
+	milestones, err := (&GithubMilestoneReconciler{}).getMilestonesInRepo(ctx, i.GithubClient, owner, repoName)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range milestones {
+		if m.GetTitle() == milestone {
+			return m.GetNumber(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("milestone %q was not found in %s", milestone, repo)
+}
+
+// this function returns a GithubIssue object whose spec matches the issue, so adopting it changes nothing
+func adoptingGithubIssue(repo, repoName string, issue *Issue, namespace, name string) *trainingv1alpha1.GithubIssue {
+	if name == "" {
+		name = adoptedObjectName(repoName, issue.Number)
+	}
+
+	githubissue := &trainingv1alpha1.GithubIssue{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: trainingv1alpha1.GroupVersion.String(),
+			Kind:       "GithubIssue",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: trainingv1alpha1.GithubIssueSpec{
+			Repo:        repo,
+			IssueNumber: issue.Number,
+			Title:       issue.Title,
+			Description: stripIssueMarker(issue.Body),
+			Labels:      issue.Labels,
+			Assignees:   issue.Assignees,
+		},
+	}
+	if issue.State == issueStateClosed {
+		githubissue.Spec.State = issueStateClosed
+	}
+
+	return githubissue
+}
+
+// this function returns the fields which the reconciler would change on the issue
+// to match the spec of the object, fields left empty in the spec are not managed
+func DiffIssue(githubissue *trainingv1alpha1.GithubIssue, issue *Issue) []FieldDiff {
+	diffs := []FieldDiff{}
+
+	if title := githubissue.Spec.Title; title != "" && title != issue.Title {
+		diffs = append(diffs, FieldDiff{Field: "title", Spec: title, Issue: issue.Title})
+	}
+	if description := stripIssueMarker(issue.Body); githubissue.Spec.Description != description {
+		diffs = append(diffs, FieldDiff{Field: "description", Spec: githubissue.Spec.Description, Issue: description})
+	}
+	if labels := githubissue.Spec.Labels; len(labels) > 0 && !equalUnordered(labels, issue.Labels) {
+		diffs = append(diffs, FieldDiff{Field: "labels", Spec: strings.Join(labels, ","), Issue: strings.Join(issue.Labels, ",")})
+	}
+	if assignees := githubissue.Spec.Assignees; len(assignees) > 0 && !equalUnordered(assignees, issue.Assignees) {
+		diffs = append(diffs, FieldDiff{Field: "assignees", Spec: strings.Join(assignees, ","), Issue: strings.Join(issue.Assignees, ",")})
+	}
+	if state := githubissue.Spec.State; state != "" && state != issue.State {
+		diffs = append(diffs, FieldDiff{Field: "state", Spec: state, Issue: issue.State})
+	}
+
+	return diffs
+}
+
+// this function returns a valid object name for an adopted issue
+func adoptedObjectName(repo string, issueNumber int) string {
+	name := regexp.MustCompile(`[^a-z0-9-]+`).ReplaceAllString(strings.ToLower(repo), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > 40 {
+		name = strings.TrimRight(name[:40], "-")
+	}
+	if name == "" {
+		name = "issue"
+	}
+
+	return fmt.Sprintf("%s-%d", name, issueNumber)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/mzeevi/githubissues-operator/internal/githubfake"
+)
+
+func TestIssueInspector(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+	server.AddRepo(testOwnerName, testRepoName)
+
+	inspector := &IssueInspector{GithubClient: server.Client()}
+
+	// an object without an issue has nothing to inspect
+	githubIssue := GenerateGithubIssueObject()
+	issue, err := inspector.LiveIssue(ctx, githubIssue)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue).To(BeNil())
+
+	// an issue claimed by another object is not the issue of the object
+	server.AddIssue(testOwnerName, testRepoName, githubfake.Issue{
+		Title: githubIssue.Spec.Title,
+		Body:  "claimed\n\n<!-- githubissues-operator: default/other -->",
+	})
+	issue, err = inspector.LiveIssue(ctx, githubIssue)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue).To(BeNil())
+
+	// an adopted issue is turned into an object which matches it
+	server.AddIssue(testOwnerName, testRepoName, githubfake.Issue{
+		Title:     "Existing issue",
+		Body:      "Found in production.",
+		State:     "closed",
+		Labels:    []string{"bug", "area/api"},
+		Assignees: []string{"octocat"},
+	})
+	adopted, err := inspector.AdoptIssue(ctx, testRepo+"/issues/2", testNamespace, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(adopted.Name).To(Equal("testrepo-2"))
+	g.Expect(adopted.Namespace).To(Equal(testNamespace))
+	g.Expect(adopted.Kind).To(Equal("GithubIssue"))
+	g.Expect(adopted.Spec.Repo).To(Equal(testRepo))
+	g.Expect(adopted.Spec.IssueNumber).To(Equal(2))
+	g.Expect(adopted.Spec.Title).To(Equal("Existing issue"))
+	g.Expect(adopted.Spec.Description).To(Equal("Found in production."))
+	g.Expect(adopted.Spec.Labels).To(ConsistOf("bug", "area/api"))
+	g.Expect(adopted.Spec.Assignees).To(ConsistOf("octocat"))
+	g.Expect(adopted.Spec.State).To(Equal("closed"))
+
+	issue, err = inspector.LiveIssue(ctx, adopted)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue.Number).To(Equal(2))
+	g.Expect(DiffIssue(adopted, issue)).To(BeEmpty())
+
+	// issues managed by an object or which are not issue URLs can't be adopted
+	_, err = inspector.AdoptIssue(ctx, testRepo+"/issues/1", testNamespace, "")
+	g.Expect(err).To(MatchError(ContainSubstring("already managed by default/other")))
+	_, err = inspector.AdoptIssue(ctx, testRepo, testNamespace, "")
+	g.Expect(err).To(MatchError(ContainSubstring("is not the URL of an issue")))
+
+	// the diff lists the fields which the reconciler would change
+	adopted.Spec.Title = "Renamed issue"
+	adopted.Spec.Labels = []string{"area/api", "bug"}
+	adopted.Spec.State = "open"
+	g.Expect(DiffIssue(adopted, issue)).To(Equal([]FieldDiff{
+		{Field: "title", Spec: "Renamed issue", Issue: "Existing issue"},
+		{Field: "state", Spec: "open", Issue: "closed"},
+	}))
+}
+
+func TestImportIssues(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	server := githubfake.NewServer()
+	defer server.Close()
+
+	// more issues than fit in a page of the listing
+	for i := 1; i <= 120; i++ {
+		issue := githubfake.Issue{Title: fmt.Sprintf("issue %d", i), Labels: []string{"triage"}}
+		if i%2 == 0 {
+			issue.Labels = append(issue.Labels, "bug")
+		}
+		if i%3 == 0 {
+			issue.State = "closed"
+		}
+		if i%5 == 0 {
+			issue.Milestone = 1
+		}
+		server.AddIssue(testOwnerName, testRepoName, issue)
+	}
+	server.AddIssue(testOwnerName, testRepoName, githubfake.Issue{
+		Title:  "managed issue",
+		Body:   "<!-- githubissues-operator: default/managed -->",
+		Labels: []string{"bug"},
+	})
+
+	inspector := &IssueInspector{GithubClient: server.Client()}
+
+	githubissues, err := inspector.ImportIssues(ctx, testOwnerName+"/"+testRepoName, IssueFilter{}, testNamespace)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(githubissues).To(HaveLen(120))
+
+	githubissues, err = inspector.ImportIssues(ctx, testRepo, IssueFilter{State: "open", Labels: []string{"BUG"}, Milestone: 1}, testNamespace)
+	g.Expect(err).ToNot(HaveOccurred())
+	numbers := []int{}
+	for _, githubissue := range githubissues {
+		g.Expect(githubissue.Name).To(Equal(fmt.Sprintf("testrepo-%d", githubissue.Spec.IssueNumber)))
+		g.Expect(githubissue.Namespace).To(Equal(testNamespace))
+		g.Expect(githubissue.Spec.Repo).To(Equal(testRepo))
+		g.Expect(githubissue.Spec.Labels).To(ConsistOf("triage", "bug"))
+		numbers = append(numbers, githubissue.Spec.IssueNumber)
+	}
+	g.Expect(numbers).To(ConsistOf(10, 20, 40, 50, 70, 80, 100, 110))
+
+	closed, err := inspector.ImportIssues(ctx, testRepo, IssueFilter{State: "closed"}, testNamespace)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(closed).To(HaveLen(40))
+	g.Expect(closed[0].Spec.State).To(Equal("closed"))
+
+	number, err := inspector.ResolveMilestone(ctx, testRepo, "3")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(number).To(Equal(3))
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+const (
+	// BackupJSONKey and backupMarkdownKey are the keys of a backup in a ConfigMap
+	BackupJSONKey     string = "issues.json"
+	backupMarkdownKey string = "issues.md"
+
+	// backupTimeLayout is the layout of the timestamps in markdown backups
+	backupTimeLayout string = time.RFC3339
+)
+
+// IssueBackup is a snapshot of the issues managed by GithubIssue objects
+type IssueBackup struct {
+	TakenAt time.Time       `json:"takenAt"`
+	Issues  []BackedUpIssue `json:"issues"`
+	// Failures are the issues which could not be backed up, the others are backed up anyway
+	Failures []BackupFailure `json:"failures,omitempty"`
+}
+
+// BackupFailure is an issue which could not be backed up with the reason
+type BackupFailure struct {
+	// Object is the GithubIssue object managing the issue, namespace/name
+	Object string `json:"object"`
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+	Error  string `json:"error"`
+}
+
+// BackedUpIssue is an issue in a backup with its comments
+type BackedUpIssue struct {
+	// Object is the GithubIssue object managing the issue, namespace/name
+	Object    string            `json:"object"`
+	Repo      string            `json:"repo"`
+	Number    int               `json:"number"`
+	URL       string            `json:"url,omitempty"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	State     string            `json:"state"`
+	Labels    []string          `json:"labels,omitempty"`
+	Assignees []string          `json:"assignees,omitempty"`
+	CreatedAt *time.Time        `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time        `json:"updatedAt,omitempty"`
+	ClosedAt  *time.Time        `json:"closedAt,omitempty"`
+	Comments  []BackedUpComment `json:"comments,omitempty"`
+}
+
+// BackedUpComment is a comment on an issue in a backup
+type BackedUpComment struct {
+	Author    string     `json:"author"`
+	Body      string     `json:"body"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// RestoredIssue is the result of restoring an issue of a backup
+type RestoredIssue struct {
+	// Source is the URL of the backed up issue
+	Source string
+	// Number is the number of the issue in the repository it was restored into
+	Number int
+	// Existing is whether an issue with the same title already existed and was left as is
+	Existing bool
+}
+
+// this function returns the issue managed by an object with its comments as it is kept in a backup
+func backUpIssue(ctx context.Context, tracker IssueTracker, githubissue *trainingv1alpha1.GithubIssue, owner, repo string) (*BackedUpIssue, error) {
+	issue, err := tracker.Get(ctx, owner, repo, githubissue.Status.IssueNumber)
+	if err != nil {
+		return nil, err
+	}
+	if issue == nil {
+		return nil, fmt.Errorf("issue %d was not found in %s/%s", githubissue.Status.IssueNumber, owner, repo)
+	}
+
+	comments, err := tracker.Comments(ctx, owner, repo, issue.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	backedUp := &BackedUpIssue{
+		Object:    issueMarkerOwner(githubissue),
+		Repo:      githubissue.Spec.Repo,
+		Number:    issue.Number,
+		URL:       issue.URL,
+		Title:     issue.Title,
+		Body:      stripIssueMarker(issue.Body),
+		State:     issue.State,
+		Labels:    issue.Labels,
+		Assignees: issue.Assignees,
+		CreatedAt: issue.CreatedAt,
+		UpdatedAt: issue.UpdatedAt,
+		ClosedAt:  issue.ClosedAt,
+	}
+	for _, comment := range comments {
+		backedUp.Comments = append(backedUp.Comments, BackedUpComment{Author: comment.Author, Body: comment.Body, CreatedAt: comment.CreatedAt})
+	}
+
+	return backedUp, nil
+}
+
+// this function returns the backup encoded in a format and the key it is kept under in a ConfigMap
+func encodeIssueBackup(backup *IssueBackup, format trainingv1alpha1.BackupFormat) ([]byte, string, error) {
+	if format == trainingv1alpha1.BackupFormatMarkdown {
+		return []byte(backup.Markdown()), backupMarkdownKey, nil
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return nil, "", err
+	}
+	return append(data, '\n'), BackupJSONKey, nil
+}
+
+// this function decodes a backup written in the json format, markdown backups can't be decoded
+func ParseIssueBackup(data []byte) (*IssueBackup, error) {
+	backup := &IssueBackup{}
+	if err := json.Unmarshal(data, backup); err != nil {
+		return nil, fmt.Errorf("invalid backup, only backups in the JSON format can be restored: %w", err)
+	}
+	return backup, nil
+}
+
+// Markdown returns the backup as a markdown document
+func (b *IssueBackup) Markdown() string {
+	var md strings.Builder
+
+	fmt.Fprintf(&md, "# Issue backup\n\nTaken at %s, %d issues.\n", b.TakenAt.UTC().Format(backupTimeLayout), len(b.Issues))
+
+	for _, issue := range b.Issues {
+		repo := issue.Repo
+		if owner, name, err := parseOwnerRepo(issue.Repo); err == nil {
+			repo = owner + "/" + name
+		}
+		fmt.Fprintf(&md, "\n## %s#%d: %s\n\n", repo, issue.Number, issue.Title)
+		fmt.Fprintf(&md, "- Object: %s\n", issue.Object)
+		if issue.URL != "" {
+			fmt.Fprintf(&md, "- URL: %s\n", issue.URL)
+		}
+		fmt.Fprintf(&md, "- State: %s\n", issue.State)
+		if len(issue.Labels) > 0 {
+			fmt.Fprintf(&md, "- Labels: %s\n", strings.Join(issue.Labels, ", "))
+		}
+		if len(issue.Assignees) > 0 {
+			fmt.Fprintf(&md, "- Assignees: %s\n", strings.Join(issue.Assignees, ", "))
+		}
+		for _, timestamp := range []struct {
+			name  string
+			value *time.Time
+		}{{"Created", issue.CreatedAt}, {"Updated", issue.UpdatedAt}, {"Closed", issue.ClosedAt}} {
+			if timestamp.value != nil {
+				fmt.Fprintf(&md, "- %s: %s\n", timestamp.name, timestamp.value.UTC().Format(backupTimeLayout))
+			}
+		}
+
+		if issue.Body != "" {
+			fmt.Fprintf(&md, "\n%s\n", issue.Body)
+		}
+
+		if len(issue.Comments) > 0 {
+			md.WriteString("\n### Comments\n")
+			for _, comment := range issue.Comments {
+				fmt.Fprintf(&md, "\n**%s**%s:\n\n%s\n", comment.Author, formatCommentTime(comment.CreatedAt), comment.Body)
+			}
+		}
+	}
+
+	if len(b.Failures) > 0 {
+		md.WriteString("\n## Failed issues\n\n")
+		for _, failure := range b.Failures {
+			fmt.Fprintf(&md, "- %s, issue %d of %s: %s\n", failure.Object, failure.Number, failure.Repo, failure.Error)
+		}
+	}
+
+	return md.String()
+}
+
+// this function recreates the issues of a backup in a repository, with their comments. issues
+// whose title already exists in the repository are skipped, so a restore can be run again
+func (i *IssueInspector) RestoreIssues(ctx context.Context, backup *IssueBackup, repoURL string) ([]RestoredIssue, error) {
+	repo, _, err := trainingv1alpha1.NormalizeRepo(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker, err := i.repoTracker(repo)
+	if err != nil {
+		return nil, err
+	}
+	owner, repoName, err := parseOwnerRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	restored := []RestoredIssue{}
+	for _, backedUp := range backup.Issues {
+		source := backedUp.URL
+		if source == "" {
+			source = fmt.Sprintf("%s/issues/%d", backedUp.Repo, backedUp.Number)
+		}
+
+		existing, err := tracker.Find(ctx, owner, repoName, backedUp.Title)
+		if err != nil {
+			return restored, err
+		}
+		if existing != nil {
+			restored = append(restored, RestoredIssue{Source: source, Number: existing.Number, Existing: true})
+			continue
+		}
+
+		// the assignees are not restored as they may not have access to the repository
+		body := fmt.Sprintf("%s\n\n_Restored from %s, created%s._", backedUp.Body, source, formatCommentTime(backedUp.CreatedAt))
+		request := &IssueRequest{Title: &backedUp.Title, Body: &body}
+		if len(backedUp.Labels) > 0 {
+			labels := backedUp.Labels
+			request.Labels = &labels
+		}
+
+		issue, err := tracker.Create(ctx, owner, repoName, request)
+		if err != nil {
+			return restored, err
+		}
+
+		for _, comment := range backedUp.Comments {
+			commentBody := fmt.Sprintf("_%s commented%s:_\n\n%s", comment.Author, formatCommentTime(comment.CreatedAt), comment.Body)
+			if err := tracker.Comment(ctx, owner, repoName, issue.Number, commentBody); err != nil {
+				return restored, err
+			}
+		}
+
+		if backedUp.State == issueStateClosed {
+			if err := tracker.Close(ctx, owner, repoName, issue.Number); err != nil {
+				return restored, err
+			}
+		}
+
+		restored = append(restored, RestoredIssue{Source: source, Number: issue.Number})
+	}
+
+	return restored, nil
+}
+
+// this function returns " at <time>" for a known time and an empty string otherwise
+func formatCommentTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return " at " + t.UTC().Format(backupTimeLayout)
+}
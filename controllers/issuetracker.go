@@ -0,0 +1,283 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+)
+
+const (
+	// githubHost is the host of repositories on github, which are handled
+	// by the github issue tracker unless another tracker is configured for it
+	githubHost string = "github.com"
+
+	issueStateOpen   string = "open"
+	issueStateClosed string = "closed"
+)
+
+// Issue is an issue as the reconciler sees it, independent of the tracker which holds it
+type Issue struct {
+	Number    int
+	Title     string
+	Body      string
+	State     string
+	Labels    []string
+	Assignees []string
+
+	Locked     bool
+	LockReason string
+
+	// HasPullRequest is whether pull requests, or merge requests, are linked to the issue
+	HasPullRequest bool
+
+	// Milestone is the number of the milestone of the issue, 0 if it has none
+	Milestone int
+
+	// URL is the web URL of the issue
+	URL string
+
+	// CreatedAt, UpdatedAt and ClosedAt are when the issue was created, last updated
+	// and closed, they are nil if the tracker doesn't report them
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+	ClosedAt  *time.Time
+
+	// NodeID is the graphql ID of an issue on github
+	NodeID string
+
+	// RepositoryURL is the api URL of the repository of an issue on github
+	RepositoryURL string
+}
+
+// IssueComment is a comment on an issue
+type IssueComment struct {
+	Author    string
+	Body      string
+	CreatedAt *time.Time
+}
+
+// IssueRequest holds the fields to set on an issue, fields which are nil are left untouched
+type IssueRequest struct {
+	Title     *string
+	Body      *string
+	State     *string
+	Labels    *[]string
+	Assignees *[]string
+	Milestone *int
+
+	// IssueType is the type of a created issue, it is only used by trackers which
+	// need it to create issues and github sets it through its graphql api instead
+	IssueType *string
+}
+
+// IssueFilter selects issues of a repository, empty fields select every issue
+type IssueFilter struct {
+	// State is open or closed, all is the same as an empty state
+	State string
+
+	// Labels selects the issues which have all of the labels
+	Labels []string
+
+	// Milestone selects the issues of the milestone with the number
+	Milestone int
+}
+
+// IssueTracker is the backend which holds the issues of a repository, the
+// reconciler only manages issues through it so it works the same for every backend
+type IssueTracker interface {
+	// Get returns the issue with a number, isIssueNotFound is true for the error if there is none
+	Get(ctx context.Context, owner, repo string, number int) (*Issue, error)
+
+	// Find returns the issue with a title, nil is returned if no issue has the title
+	Find(ctx context.Context, owner, repo, title string) (*Issue, error)
+
+	// List returns the open and closed issues of a repository
+	List(ctx context.Context, owner, repo string) ([]*Issue, error)
+
+	// Create creates an issue
+	Create(ctx context.Context, owner, repo string, request *IssueRequest) (*Issue, error)
+
+	// Update sets the fields of the request on an issue
+	Update(ctx context.Context, owner, repo string, number int, request *IssueRequest) (*Issue, error)
+
+	// Close closes an issue
+	Close(ctx context.Context, owner, repo string, number int) error
+
+	// Comment posts a comment on an issue
+	Comment(ctx context.Context, owner, repo string, number int, body string) error
+
+	// Comments returns the comments on an issue, the oldest comment first
+	Comments(ctx context.Context, owner, repo string, number int) ([]*IssueComment, error)
+
+	// Lock locks the conversation of an issue, the reason is ignored by trackers which don't support it
+	Lock(ctx context.Context, owner, repo string, number int, reason string) error
+
+	// Unlock unlocks the conversation of an issue
+	Unlock(ctx context.Context, owner, repo string, number int) error
+}
+
+// this function returns the issue tracker of the repository of an object, the trackers
+// are selected by the host of the repository and github is used for any other host
+func (r *GithubIssueReconciler) getIssueTracker(githubissue *trainingv1alpha1.GithubIssue) (IssueTracker, error) {
+	host := repoHost(githubissue.Spec.Repo)
+	if tracker, ok := r.Trackers[host]; ok {
+		return tracker, nil
+	}
+
+	if r.GithubClient == nil {
+		return nil, fmt.Errorf("github client is not available")
+	}
+
+	return &GithubIssueTracker{Client: r.GithubClient}, nil
+}
+
+// this function returns the issues of a repository which match the filter, the issues
+// are listed with all of their pages so large repositories are fully covered
+func listIssues(ctx context.Context, tracker IssueTracker, owner, repo string, filter IssueFilter) ([]*Issue, error) {
+	issues, err := tracker.List(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []*Issue
+	for _, issue := range issues {
+		if filter.matches(issue) {
+			matching = append(matching, issue)
+		}
+	}
+	return matching, nil
+}
+
+// this function checks whether an issue is selected by the filter
+func (f IssueFilter) matches(issue *Issue) bool {
+	if f.State != "" && f.State != "all" && !strings.EqualFold(f.State, issue.State) {
+		return false
+	}
+	if f.Milestone != 0 && f.Milestone != issue.Milestone {
+		return false
+	}
+
+	for _, label := range f.Labels {
+		found := false
+		for _, issueLabel := range issue.Labels {
+			if strings.EqualFold(label, issueLabel) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// this function checks whether issues are managed on github, features which
+// only exist on github such as projects and transfers are skipped otherwise
+func isGithubTracker(tracker IssueTracker) bool {
+	_, ok := tracker.(*GithubIssueTracker)
+	return ok
+}
+
+// this function returns the lowercase host of a repository URL, github.com is
+// returned for URLs without a host
+func repoHost(repositoryURL string) string {
+	parsed, err := url.Parse(repositoryURL)
+	if err != nil || parsed.Host == "" {
+		return githubHost
+	}
+	return strings.ToLower(parsed.Host)
+}
+
+// this function sends a request to the REST api of an issue tracker and decodes its json
+// response into out, http.DefaultClient is used if no http client is given
+func sendTrackerRequest(ctx context.Context, httpClient *http.Client, method, url string, header http.Header, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return &trackerStatusError{StatusCode: response.StatusCode}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+// trackerStatusError is returned by sendTrackerRequest for a response with an unexpected status code
+type trackerStatusError struct {
+	StatusCode int
+}
+
+func (e *trackerStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// this function checks whether an error of an issue tracker means that the issue doesn't exist
+func isIssueNotFound(err error) bool {
+	var statusErr *trackerStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusNotFound
+	}
+
+	var githubErr *github.ErrorResponse
+	if errors.As(err, &githubErr) {
+		return githubErr.Response != nil && githubErr.Response.StatusCode == http.StatusNotFound
+	}
+
+	return false
+}
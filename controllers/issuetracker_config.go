@@ -0,0 +1,170 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	trackerTypeGitlab  string = "gitlab"
+	trackerTypeGitea   string = "gitea"
+	trackerTypeForgejo string = "forgejo"
+	trackerTypeJira    string = "jira"
+
+	// trackerTokenSecretKey and trackerUsernameSecretKey are the keys
+	// of the token and of the user in the credentials secret of a tracker
+	trackerTokenSecretKey    string = "token"
+	trackerUsernameSecretKey string = "username"
+)
+
+// TrackerConfig maps the hosts of repositories to the issue trackers which hold their
+// issues, repositories of hosts which are not mapped are managed on github
+type TrackerConfig struct {
+	Trackers []TrackerHost `json:"trackers"`
+}
+
+// TrackerHost is the issue tracker of the repositories of a host
+type TrackerHost struct {
+	// Host is the host in the URLs of the repositories, e.g. gitea.example.com
+	Host string `json:"host"`
+
+	// Type is the kind of the tracker, one of gitlab, gitea, forgejo or jira
+	Type string `json:"type"`
+
+	// URL is the URL of the instance the api is served from, https://<host> is used if it is not set
+	URL string `json:"url,omitempty"`
+
+	// TokenEnv is the environment variable holding the access token of the tracker
+	TokenEnv string `json:"tokenEnv,omitempty"`
+
+	// CredentialsSecret is the namespace/name of a secret holding the token of the tracker, and
+	// the username authenticating with it for jira. It takes precedence over the environment variable
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	// Jira holds the settings of jira trackers
+	Jira *JiraSettings `json:"jira,omitempty"`
+}
+
+// JiraSettings are the settings of a jira tracker
+type JiraSettings struct {
+	// Cloud is whether the instance is jira cloud, whose assignees are account IDs
+	Cloud bool `json:"cloud,omitempty"`
+
+	// IssueType is the type of the issues created for objects without an issue type, Task is used if it is not set
+	IssueType string `json:"issueType,omitempty"`
+
+	// CloseTransition and ReopenTransition are the names of the workflow transitions which close and reopen issues
+	CloseTransition  string `json:"closeTransition,omitempty"`
+	ReopenTransition string `json:"reopenTransition,omitempty"`
+}
+
+// LoadTrackerConfig reads the host mapping of the issue trackers from a yaml file
+func LoadTrackerConfig(path string) (*TrackerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &TrackerConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("invalid tracker config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// NewTrackers returns the issue trackers of the config keyed by host, the tokens
+// are read from the credentials secrets with the reader or looked up with getenv
+func (c *TrackerConfig) NewTrackers(ctx context.Context, reader client.Reader, getenv func(string) string) (map[string]IssueTracker, error) {
+	trackers := map[string]IssueTracker{}
+	for _, trackerHost := range c.Trackers {
+		host := strings.ToLower(trackerHost.Host)
+		if host == "" {
+			return nil, fmt.Errorf("tracker of type %q has no host", trackerHost.Type)
+		}
+		if _, ok := trackers[host]; ok {
+			return nil, fmt.Errorf("host %s is mapped more than once", host)
+		}
+
+		baseURL := trackerHost.URL
+		if baseURL == "" {
+			baseURL = "https://" + host
+		}
+		if parsed, err := url.Parse(baseURL); err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("tracker of host %s has an invalid url %q", host, baseURL)
+		}
+
+		username, token := "", ""
+		if trackerHost.TokenEnv != "" {
+			token = getenv(trackerHost.TokenEnv)
+		}
+		if trackerHost.CredentialsSecret != "" {
+			var err error
+			username, token, err = readTrackerCredentials(ctx, reader, trackerHost.CredentialsSecret)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read credentials of tracker of host %s: %w", host, err)
+			}
+		}
+
+		switch trackerHost.Type {
+		case trackerTypeGitlab:
+			trackers[host] = NewGitlabIssueTracker(baseURL, token)
+		case trackerTypeGitea, trackerTypeForgejo:
+			trackers[host] = NewGiteaIssueTracker(baseURL, token)
+		case trackerTypeJira:
+			tracker := &JiraIssueTracker{BaseURL: baseURL, Username: username, Token: token}
+			if settings := trackerHost.Jira; settings != nil {
+				tracker.Cloud = settings.Cloud
+				tracker.IssueType = settings.IssueType
+				tracker.CloseTransition = settings.CloseTransition
+				tracker.ReopenTransition = settings.ReopenTransition
+			}
+			trackers[host] = tracker
+		default:
+			return nil, fmt.Errorf("tracker of host %s has an unknown type %q", host, trackerHost.Type)
+		}
+	}
+	return trackers, nil
+}
+
+// this function returns the username and the token in the credentials secret of a tracker
+func readTrackerCredentials(ctx context.Context, reader client.Reader, secretRef string) (string, string, error) {
+	namespace, name, ok := strings.Cut(secretRef, "/")
+	if !ok || namespace == "" || name == "" {
+		return "", "", fmt.Errorf("expected namespace/name, got %q", secretRef)
+	}
+
+	var secret corev1.Secret
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &secret); err != nil {
+		return "", "", err
+	}
+
+	token, ok := secret.Data[trackerTokenSecretKey]
+	if !ok {
+		return "", "", fmt.Errorf("secret %s has no %s key", secretRef, trackerTokenSecretKey)
+	}
+	return string(secret.Data[trackerUsernameSecretKey]), string(token), nil
+}
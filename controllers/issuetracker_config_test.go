@@ -0,0 +1,110 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestTrackerConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "jira-credentials",
+			Namespace: "githubissues-operator-system",
+		},
+		Data: map[string][]byte{
+			trackerUsernameSecretKey: []byte("bot@example.com"),
+			trackerTokenSecretKey:    []byte("jira-token"),
+		},
+	}
+
+	obj := []client.Object{secret}
+	cl, _, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	path := filepath.Join(t.TempDir(), "trackers.yaml")
+	g.Expect(os.WriteFile(path, []byte(`trackers:
+- host: Gitea.example.com
+  type: gitea
+  tokenEnv: GITEA_TOKEN
+- host: codeberg.org
+  type: forgejo
+  url: https://codeberg.org/
+- host: gitlab.example.com
+  type: gitlab
+- host: example.atlassian.net
+  type: jira
+  credentialsSecret: githubissues-operator-system/jira-credentials
+  jira:
+    cloud: true
+    issueType: Bug
+    closeTransition: Done
+`), 0o600)).To(Succeed())
+
+	config, err := LoadTrackerConfig(path)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	getenv := func(key string) string {
+		return map[string]string{"GITEA_TOKEN": "secret"}[key]
+	}
+	trackers, err := config.NewTrackers(ctx, cl, getenv)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(trackers).To(HaveLen(4))
+	g.Expect(trackers["gitea.example.com"]).To(Equal(&GiteaIssueTracker{BaseURL: "https://gitea.example.com", Token: "secret"}))
+	g.Expect(trackers["codeberg.org"]).To(Equal(&GiteaIssueTracker{BaseURL: "https://codeberg.org/"}))
+	g.Expect(trackers["gitlab.example.com"]).To(BeAssignableToTypeOf(&GitlabIssueTracker{}))
+	g.Expect(trackers["example.atlassian.net"]).To(Equal(&JiraIssueTracker{
+		BaseURL:         "https://example.atlassian.net",
+		Username:        "bot@example.com",
+		Token:           "jira-token",
+		Cloud:           true,
+		IssueType:       "Bug",
+		CloseTransition: "Done",
+	}))
+
+	// unknown types, missing secrets and hosts mapped twice are rejected
+	config.Trackers = append(config.Trackers, TrackerHost{Host: "codeberg.org", Type: "gitea"})
+	_, err = config.NewTrackers(ctx, cl, getenv)
+	g.Expect(err).To(HaveOccurred())
+
+	config.Trackers = []TrackerHost{{Host: "git.example.com", Type: "bitbucket"}}
+	_, err = config.NewTrackers(ctx, cl, getenv)
+	g.Expect(err).To(HaveOccurred())
+
+	config.Trackers = []TrackerHost{{Host: "jira.example.com", Type: "jira", CredentialsSecret: "githubissues-operator-system/missing"}}
+	_, err = config.NewTrackers(ctx, cl, getenv)
+	g.Expect(err).To(HaveOccurred())
+
+	// unknown fields are rejected
+	g.Expect(os.WriteFile(path, []byte("trackers:\n- host: git.example.com\n  token: secret\n"), 0o600)).To(Succeed())
+	_, err = LoadTrackerConfig(path)
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,347 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// giteaPerPage is the size of the pages requested from the gitea api,
+	// which is the largest page gitea serves by default
+	giteaPerPage int = 50
+)
+
+// GiteaIssueTracker manages the issues of repositories on a gitea or forgejo
+// instance through its REST api, both serve the same api under /api/v1
+type GiteaIssueTracker struct {
+	// BaseURL is the URL of the gitea instance, e.g. https://gitea.example.com
+	BaseURL string
+
+	// Token is the access token sent in the Authorization header
+	Token string
+
+	// HTTPClient sends the requests, http.DefaultClient is used if it is nil
+	HTTPClient *http.Client
+}
+
+var _ IssueTracker = &GiteaIssueTracker{}
+
+// NewGiteaIssueTracker returns a tracker for the gitea instance at a URL
+func NewGiteaIssueTracker(baseURL, token string) *GiteaIssueTracker {
+	return &GiteaIssueTracker{BaseURL: baseURL, Token: token}
+}
+
+// giteaIssue is an issue of the gitea api
+type giteaIssue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	State  string `json:"state"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Assignees []struct {
+		Login string `json:"login"`
+	} `json:"assignees"`
+	IsLocked  bool       `json:"is_locked"`
+	HTMLURL   string     `json:"html_url"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+}
+
+// giteaComment is a comment on an issue of the gitea api
+type giteaComment struct {
+	Body string `json:"body"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// giteaIssueRequest holds the fields set on an issue through the gitea api, the
+// labels are only set when an issue is created and replaced through their own endpoint
+type giteaIssueRequest struct {
+	Title     *string   `json:"title,omitempty"`
+	Body      *string   `json:"body,omitempty"`
+	State     *string   `json:"state,omitempty"`
+	Assignees *[]string `json:"assignees,omitempty"`
+	Labels    []int64   `json:"labels,omitempty"`
+}
+
+// giteaLabel is a label of a repository on gitea
+type giteaLabel struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Get returns the issue with a number
+func (t *GiteaIssueTracker) Get(ctx context.Context, owner, repo string, number int) (*Issue, error) {
+	var issue giteaIssue
+	if err := t.do(ctx, http.MethodGet, t.issuePath(owner, repo, number), nil, &issue); err != nil {
+		return nil, err
+	}
+	return fromGiteaIssue(&issue), nil
+}
+
+// Find returns the issue with a title, nil is returned if no issue has the title
+func (t *GiteaIssueTracker) Find(ctx context.Context, owner, repo, title string) (*Issue, error) {
+	issues, err := t.list(ctx, owner, repo, url.Values{"q": {title}})
+	if err != nil {
+		return nil, err
+	}
+
+	// the search matches parts of titles and bodies, so the titles are compared
+	for _, issue := range issues {
+		if issue.Title == title {
+			return issue, nil
+		}
+	}
+	return nil, nil
+}
+
+// List returns the open and closed issues of a repository
+func (t *GiteaIssueTracker) List(ctx context.Context, owner, repo string) ([]*Issue, error) {
+	return t.list(ctx, owner, repo, url.Values{})
+}
+
+// Create creates an issue
+func (t *GiteaIssueTracker) Create(ctx context.Context, owner, repo string, request *IssueRequest) (*Issue, error) {
+	giteaRequest, err := toGiteaIssueRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Labels != nil {
+		labelIDs, err := t.getLabelIDs(ctx, owner, repo, *request.Labels)
+		if err != nil {
+			return nil, err
+		}
+		giteaRequest.Labels = labelIDs
+	}
+
+	// issues are created open, a closed issue is closed right away
+	giteaRequest.State = nil
+
+	var issue giteaIssue
+	if err := t.do(ctx, http.MethodPost, t.issuesPath(owner, repo), giteaRequest, &issue); err != nil {
+		return nil, err
+	}
+
+	if request.State != nil && *request.State == issueStateClosed {
+		return t.Update(ctx, owner, repo, issue.Number, &IssueRequest{State: request.State})
+	}
+
+	return fromGiteaIssue(&issue), nil
+}
+
+// Update sets the fields of the request on an issue
+func (t *GiteaIssueTracker) Update(ctx context.Context, owner, repo string, number int, request *IssueRequest) (*Issue, error) {
+	giteaRequest, err := toGiteaIssueRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Labels != nil {
+		if err := t.replaceLabels(ctx, owner, repo, number, *request.Labels); err != nil {
+			return nil, err
+		}
+	}
+
+	var issue giteaIssue
+	if err := t.do(ctx, http.MethodPatch, t.issuePath(owner, repo, number), giteaRequest, &issue); err != nil {
+		return nil, err
+	}
+	return fromGiteaIssue(&issue), nil
+}
+
+// Close closes an issue
+func (t *GiteaIssueTracker) Close(ctx context.Context, owner, repo string, number int) error {
+	state := issueStateClosed
+	_, err := t.Update(ctx, owner, repo, number, &IssueRequest{State: &state})
+	return err
+}
+
+// Comment posts a comment on an issue
+func (t *GiteaIssueTracker) Comment(ctx context.Context, owner, repo string, number int, body string) error {
+	comment := map[string]string{"body": body}
+	return t.do(ctx, http.MethodPost, t.issuePath(owner, repo, number)+"/comments", comment, nil)
+}
+
+// Comments returns the comments on an issue, gitea returns all of them at once
+func (t *GiteaIssueTracker) Comments(ctx context.Context, owner, repo string, number int) ([]*IssueComment, error) {
+	var giteaComments []giteaComment
+	if err := t.do(ctx, http.MethodGet, t.issuePath(owner, repo, number)+"/comments", nil, &giteaComments); err != nil {
+		return nil, err
+	}
+
+	comments := []*IssueComment{}
+	for _, comment := range giteaComments {
+		comments = append(comments, &IssueComment{Author: comment.User.Login, Body: comment.Body, CreatedAt: comment.CreatedAt})
+	}
+	return comments, nil
+}
+
+// Lock is not supported, the gitea api doesn't lock the conversation of issues
+func (t *GiteaIssueTracker) Lock(ctx context.Context, owner, repo string, number int, reason string) error {
+	return fmt.Errorf("locking issues is not supported by the gitea issue tracker")
+}
+
+// Unlock is not supported, the gitea api doesn't unlock the conversation of issues
+func (t *GiteaIssueTracker) Unlock(ctx context.Context, owner, repo string, number int) error {
+	return fmt.Errorf("unlocking issues is not supported by the gitea issue tracker")
+}
+
+// this function returns all pages of the issues of a repository matching a query,
+// pull requests are listed as issues by gitea and are left out
+func (t *GiteaIssueTracker) list(ctx context.Context, owner, repo string, query url.Values) ([]*Issue, error) {
+	query.Set("state", "all")
+	query.Set("type", "issues")
+	query.Set("limit", strconv.Itoa(giteaPerPage))
+
+	var issues []*Issue
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var pageIssues []giteaIssue
+		if err := t.do(ctx, http.MethodGet, t.issuesPath(owner, repo)+"?"+query.Encode(), nil, &pageIssues); err != nil {
+			return nil, err
+		}
+
+		for i := range pageIssues {
+			issues = append(issues, fromGiteaIssue(&pageIssues[i]))
+		}
+		if len(pageIssues) < giteaPerPage {
+			return issues, nil
+		}
+	}
+}
+
+// this function replaces the labels of an issue
+func (t *GiteaIssueTracker) replaceLabels(ctx context.Context, owner, repo string, number int, labels []string) error {
+	labelIDs, err := t.getLabelIDs(ctx, owner, repo, labels)
+	if err != nil {
+		return err
+	}
+
+	labelsRequest := map[string][]int64{"labels": labelIDs}
+	return t.do(ctx, http.MethodPut, t.issuePath(owner, repo, number)+"/labels", labelsRequest, nil)
+}
+
+// this function returns the IDs of the labels of a repository with the given names,
+// gitea only sets labels by their IDs and the labels have to exist in the repository
+func (t *GiteaIssueTracker) getLabelIDs(ctx context.Context, owner, repo string, names []string) ([]int64, error) {
+	labelIDs := []int64{}
+	if len(names) == 0 {
+		return labelIDs, nil
+	}
+
+	ids := map[string]int64{}
+	for page := 1; ; page++ {
+		query := url.Values{"page": {strconv.Itoa(page)}, "limit": {strconv.Itoa(giteaPerPage)}}
+
+		var pageLabels []giteaLabel
+		if err := t.do(ctx, http.MethodGet, t.repoPath(owner, repo)+"/labels?"+query.Encode(), nil, &pageLabels); err != nil {
+			return nil, err
+		}
+
+		for _, label := range pageLabels {
+			ids[label.Name] = label.ID
+		}
+		if len(pageLabels) < giteaPerPage {
+			break
+		}
+	}
+
+	for _, name := range names {
+		id, ok := ids[name]
+		if !ok {
+			return nil, fmt.Errorf("label %s does not exist in repository %s/%s", name, owner, repo)
+		}
+		labelIDs = append(labelIDs, id)
+	}
+	return labelIDs, nil
+}
+
+// this function returns the api path of a repository
+func (t *GiteaIssueTracker) repoPath(owner, repo string) string {
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+// this function returns the api path of the issues of a repository
+func (t *GiteaIssueTracker) issuesPath(owner, repo string) string {
+	return t.repoPath(owner, repo) + "/issues"
+}
+
+// this function returns the api path of an issue
+func (t *GiteaIssueTracker) issuePath(owner, repo string, number int) string {
+	return t.issuesPath(owner, repo) + "/" + strconv.Itoa(number)
+}
+
+// this function sends a request to the gitea api and decodes its response into out
+func (t *GiteaIssueTracker) do(ctx context.Context, method, path string, in, out interface{}) error {
+	header := http.Header{}
+	if t.Token != "" {
+		header.Set("Authorization", "token "+t.Token)
+	}
+	return sendTrackerRequest(ctx, t.HTTPClient, method, strings.TrimSuffix(t.BaseURL, "/")+"/api/v1"+path, header, in, out)
+}
+
+// this function converts an IssueRequest to a request of the gitea api
+func toGiteaIssueRequest(request *IssueRequest) (*giteaIssueRequest, error) {
+	if request.Milestone != nil {
+		return nil, fmt.Errorf("milestones are not supported by the gitea issue tracker")
+	}
+
+	return &giteaIssueRequest{
+		Title:     request.Title,
+		Body:      request.Body,
+		State:     request.State,
+		Assignees: request.Assignees,
+	}, nil
+}
+
+// this function converts an issue of the gitea api to an Issue
+func fromGiteaIssue(giteaIssue *giteaIssue) *Issue {
+	issue := &Issue{
+		Number:    giteaIssue.Number,
+		Title:     giteaIssue.Title,
+		Body:      giteaIssue.Body,
+		State:     giteaIssue.State,
+		Locked:    giteaIssue.IsLocked,
+		URL:       giteaIssue.HTMLURL,
+		CreatedAt: giteaIssue.CreatedAt,
+		UpdatedAt: giteaIssue.UpdatedAt,
+		ClosedAt:  giteaIssue.ClosedAt,
+	}
+
+	for _, label := range giteaIssue.Labels {
+		issue.Labels = append(issue.Labels, label.Name)
+	}
+	for _, assignee := range giteaIssue.Assignees {
+		issue.Assignees = append(issue.Assignees, assignee.Login)
+	}
+
+	return issue
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// fakeGitea serves the parts of the gitea issues api used by the GiteaIssueTracker,
+// it holds the issues, labels and comments of a single repository
+type fakeGitea struct {
+	mu       sync.Mutex
+	issues   []*giteaIssue
+	labels   []giteaLabel
+	comments map[int][]string
+}
+
+// this function starts a fake gitea server and returns a tracker which uses it
+func newFakeGitea(t *testing.T) (*fakeGitea, *GiteaIssueTracker) {
+	fake := &fakeGitea{comments: map[int][]string{}}
+	for i, name := range []string{"bug", "dependencies", "good first issue"} {
+		fake.labels = append(fake.labels, giteaLabel{ID: int64(i + 1), Name: name})
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, NewGiteaIssueTracker(server.URL, "token")
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "token token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// paths are /repos/:owner/:repo/labels and /repos/:owner/:repo/issues[/:index[/labels|/comments]]
+	prefix := "/api/v1/repos/" + testOwnerName + "/" + testRepoName + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	query := r.URL.Query()
+
+	if parts[0] == "labels" {
+		json.NewEncoder(w).Encode(paginate(f.labels, query))
+		return
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			issues := []*giteaIssue{}
+			for _, issue := range f.issues {
+				if strings.Contains(issue.Title, query.Get("q")) {
+					issues = append(issues, issue)
+				}
+			}
+			json.NewEncoder(w).Encode(paginate(issues, query))
+		case http.MethodPost:
+			request := giteaIssueRequest{}
+			json.NewDecoder(r.Body).Decode(&request)
+			issue := &giteaIssue{Number: len(f.issues) + 1, State: issueStateOpen}
+			f.apply(issue, &request)
+			f.setLabels(issue, request.Labels)
+			f.issues = append(f.issues, issue)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(issue)
+		}
+		return
+	}
+
+	index, _ := strconv.Atoi(parts[1])
+	if index < 1 || index > len(f.issues) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	issue := f.issues[index-1]
+
+	switch {
+	case len(parts) == 3 && parts[2] == "labels":
+		request := map[string][]int64{}
+		json.NewDecoder(r.Body).Decode(&request)
+		f.setLabels(issue, request["labels"])
+		json.NewEncoder(w).Encode(issue.Labels)
+	case len(parts) == 3 && parts[2] == "comments" && r.Method == http.MethodGet:
+		comments := []map[string]interface{}{}
+		for _, body := range f.comments[index] {
+			comments = append(comments, map[string]interface{}{"body": body, "user": map[string]string{"login": "gitea-user"}})
+		}
+		json.NewEncoder(w).Encode(comments)
+	case len(parts) == 3 && parts[2] == "comments":
+		request := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&request)
+		f.comments[index] = append(f.comments[index], request["body"])
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(request)
+	case r.Method == http.MethodPatch:
+		request := giteaIssueRequest{}
+		json.NewDecoder(r.Body).Decode(&request)
+		f.apply(issue, &request)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(issue)
+	default:
+		json.NewEncoder(w).Encode(issue)
+	}
+}
+
+// this function sets the fields of a request on an issue
+func (f *fakeGitea) apply(issue *giteaIssue, request *giteaIssueRequest) {
+	if request.Title != nil {
+		issue.Title = *request.Title
+	}
+	if request.Body != nil {
+		issue.Body = *request.Body
+	}
+	if request.State != nil {
+		issue.State = *request.State
+	}
+	if request.Assignees != nil {
+		issue.Assignees = nil
+		for _, login := range *request.Assignees {
+			issue.Assignees = append(issue.Assignees, struct {
+				Login string `json:"login"`
+			}{Login: login})
+		}
+	}
+}
+
+// this function replaces the labels of an issue with the labels with the given IDs
+func (f *fakeGitea) setLabels(issue *giteaIssue, labelIDs []int64) {
+	issue.Labels = nil
+	for _, id := range labelIDs {
+		issue.Labels = append(issue.Labels, struct {
+			Name string `json:"name"`
+		}{Name: f.labels[id-1].Name})
+	}
+}
+
+// this function returns the page of items requested by the page and limit of a query
+func paginate[T any](items []T, query map[string][]string) []T {
+	page, _ := strconv.Atoi(firstValue(query["page"]))
+	limit, _ := strconv.Atoi(firstValue(query["limit"]))
+	if page < 1 || limit < 1 {
+		return items
+	}
+
+	start := (page - 1) * limit
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// this function returns the first of a list of values, or an empty string
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func TestGiteaIssueTracker(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+	fake, tracker := newFakeGitea(t)
+
+	title := "Upgrade dependencies"
+	body := "Dependencies are outdated"
+	labels := []string{"dependencies"}
+	assignees := []string{"alice"}
+	issue, err := tracker.Create(ctx, testOwnerName, testRepoName, &IssueRequest{
+		Title:     &title,
+		Body:      &body,
+		Labels:    &labels,
+		Assignees: &assignees,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue.Number).To(Equal(1))
+	g.Expect(issue.State).To(Equal(issueStateOpen))
+	g.Expect(issue.Labels).To(Equal(labels))
+	g.Expect(issue.Assignees).To(Equal(assignees))
+
+	// the issues are listed across pages
+	for i := 0; i < giteaPerPage; i++ {
+		otherTitle := "Other issue " + strconv.Itoa(i)
+		_, err := tracker.Create(ctx, testOwnerName, testRepoName, &IssueRequest{Title: &otherTitle})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	issues, err := tracker.List(ctx, testOwnerName, testRepoName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issues).To(HaveLen(giteaPerPage + 1))
+
+	found, err := tracker.Find(ctx, testOwnerName, testRepoName, title)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).ToNot(BeNil())
+	g.Expect(found.Number).To(Equal(1))
+
+	found, err = tracker.Find(ctx, testOwnerName, testRepoName, "Upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeNil())
+
+	// labels are replaced through their own endpoint
+	labels = []string{"bug", "good first issue"}
+	issue, err = tracker.Update(ctx, testOwnerName, testRepoName, 1, &IssueRequest{Labels: &labels})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue.Labels).To(Equal(labels))
+
+	g.Expect(tracker.Comment(ctx, testOwnerName, testRepoName, 1, "Closing")).To(Succeed())
+	g.Expect(tracker.Close(ctx, testOwnerName, testRepoName, 1)).To(Succeed())
+	issue, err = tracker.Get(ctx, testOwnerName, testRepoName, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue.State).To(Equal(issueStateClosed))
+	g.Expect(fake.comments[1]).To(Equal([]string{"Closing"}))
+	comments, err := tracker.Comments(ctx, testOwnerName, testRepoName, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(comments).To(Equal([]*IssueComment{{Author: "gitea-user", Body: "Closing"}}))
+
+	// labels which don't exist, milestones and locks are errors
+	unknown := []string{"wontfix"}
+	_, err = tracker.Update(ctx, testOwnerName, testRepoName, 1, &IssueRequest{Labels: &unknown})
+	g.Expect(err).To(HaveOccurred())
+
+	milestone := 1
+	_, err = tracker.Update(ctx, testOwnerName, testRepoName, 1, &IssueRequest{Milestone: &milestone})
+	g.Expect(err).To(HaveOccurred())
+
+	g.Expect(tracker.Lock(ctx, testOwnerName, testRepoName, 1, "resolved")).ToNot(Succeed())
+
+	_, err = tracker.Get(ctx, testOwnerName, testRepoName, 100)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestReconcileGiteaIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+	fake, tracker := newFakeGitea(t)
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Repo = "https://gitea.example.com/" + testOwnerName + "/" + testRepoName
+	githubIssue.Spec.Labels = []string{"bug"}
+	githubIssue.Spec.ClosePolicy = &trainingv1alpha1.ClosePolicy{Comment: "The object of this issue was deleted"}
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, Trackers: map[string]IssueTracker{"gitea.example.com": tracker}}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(fake.issues).To(HaveLen(1))
+	g.Expect(fake.issues[0].Title).To(Equal(githubIssue.Spec.Title))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(Equal(1))
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, issueOpenConditionType)).To(BeTrue())
+
+	// a change of the labels is pushed to the issue
+	githubIssueReconciled.Spec.Labels = []string{"bug", "dependencies"}
+	g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(fromGiteaIssue(fake.issues[0]).Labels).To(Equal([]string{"bug", "dependencies"}))
+
+	// the issue is commented on and closed when the object is deleted
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(cl.Delete(ctx, &githubIssueReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(fake.issues[0].State).To(Equal(issueStateClosed))
+	g.Expect(fake.comments[1]).To(Equal([]string{"The object of this issue was deleted"}))
+
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v45/github"
+)
+
+const (
+	// githubPerPage is the size of the pages requested from the github api, which is the largest page github serves
+	githubPerPage int = 100
+)
+
+// GithubIssueTracker manages the issues of repositories on github
+type GithubIssueTracker struct {
+	Client *github.Client
+}
+
+var _ IssueTracker = &GithubIssueTracker{}
+
+// Get returns the issue with a number
+func (t *GithubIssueTracker) Get(ctx context.Context, owner, repo string, number int) (*Issue, error) {
+	issue, response, err := t.Client.Issues.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return nil, err
+	}
+
+	return fromGithubIssue(issue), nil
+}
+
+// Find returns the issue with a title, nil is returned if no issue has the title
+func (t *GithubIssueTracker) Find(ctx context.Context, owner, repo, title string) (*Issue, error) {
+	issues, err := t.List(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, issue := range issues {
+		if issue.Title == title {
+			return issue, nil
+		}
+	}
+	return nil, nil
+}
+
+// List returns the open and closed issues of a repository, following
+// the pages of the listing until all of the issues were fetched
+func (t *GithubIssueTracker) List(ctx context.Context, owner, repo string) ([]*Issue, error) {
+	options := &github.IssueListByRepoOptions{
+		State:       "all",
+		ListOptions: github.ListOptions{PerPage: githubPerPage},
+	}
+
+	var issues []*Issue
+	for {
+		githubIssues, response, err := t.Client.Issues.ListByRepo(ctx, owner, repo, options)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode != http.StatusOK {
+			err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+			return nil, err
+		}
+
+		for _, githubIssue := range githubIssues {
+			issues = append(issues, fromGithubIssue(githubIssue))
+		}
+
+		if response.NextPage == 0 {
+			return issues, nil
+		}
+		options.Page = response.NextPage
+	}
+}
+
+// Create creates an issue
+func (t *GithubIssueTracker) Create(ctx context.Context, owner, repo string, request *IssueRequest) (*Issue, error) {
+	issue, response, err := t.Client.Issues.Create(ctx, owner, repo, toGithubIssueRequest(request))
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return nil, err
+	}
+
+	return fromGithubIssue(issue), nil
+}
+
+// Update sets the fields of the request on an issue
+// IssueRequest is initiated with what needs to be updated and
+// not setting a value for a parameter means keeping the current parameters the same
+func (t *GithubIssueTracker) Update(ctx context.Context, owner, repo string, number int, request *IssueRequest) (*Issue, error) {
+	issue, response, err := t.Client.Issues.Edit(ctx, owner, repo, number, toGithubIssueRequest(request))
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return nil, err
+	}
+
+	return fromGithubIssue(issue), nil
+}
+
+// Close closes an issue
+func (t *GithubIssueTracker) Close(ctx context.Context, owner, repo string, number int) error {
+	state := issueStateClosed
+	_, err := t.Update(ctx, owner, repo, number, &IssueRequest{State: &state})
+	return err
+}
+
+// Comment posts a comment on an issue
+func (t *GithubIssueTracker) Comment(ctx context.Context, owner, repo string, number int, body string) error {
+	_, response, err := t.Client.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{Body: &body})
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusCreated {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return err
+	}
+
+	return nil
+}
+
+// Comments returns the comments on an issue, following the pages of the listing
+func (t *GithubIssueTracker) Comments(ctx context.Context, owner, repo string, number int) ([]*IssueComment, error) {
+	options := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: githubPerPage},
+	}
+
+	var comments []*IssueComment
+	for {
+		githubComments, response, err := t.Client.Issues.ListComments(ctx, owner, repo, number, options)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode != http.StatusOK {
+			err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+			return nil, err
+		}
+
+		for _, githubComment := range githubComments {
+			comments = append(comments, &IssueComment{
+				Author:    githubComment.GetUser().GetLogin(),
+				Body:      githubComment.GetBody(),
+				CreatedAt: githubComment.CreatedAt,
+			})
+		}
+
+		if response.NextPage == 0 {
+			return comments, nil
+		}
+		options.Page = response.NextPage
+	}
+}
+
+// Lock locks the conversation of an issue with an optional reason
+func (t *GithubIssueTracker) Lock(ctx context.Context, owner, repo string, number int, reason string) error {
+	lockOptions := github.LockIssueOptions{
+		LockReason: reason,
+	}
+
+	response, err := t.Client.Issues.Lock(ctx, owner, repo, number, &lockOptions)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusNoContent {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return err
+	}
+
+	return nil
+}
+
+// Unlock unlocks the conversation of an issue
+func (t *GithubIssueTracker) Unlock(ctx context.Context, owner, repo string, number int) error {
+	response, err := t.Client.Issues.Unlock(ctx, owner, repo, number)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusNoContent {
+		err := fmt.Errorf("unexpected status code: %d", response.StatusCode)
+		return err
+	}
+
+	return nil
+}
+
+// this function converts an issue of the github api to an Issue
+func fromGithubIssue(githubIssue *github.Issue) *Issue {
+	if githubIssue == nil {
+		return nil
+	}
+
+	issue := &Issue{
+		Number:         githubIssue.GetNumber(),
+		Title:          githubIssue.GetTitle(),
+		Body:           githubIssue.GetBody(),
+		State:          githubIssue.GetState(),
+		Locked:         githubIssue.GetLocked(),
+		LockReason:     githubIssue.GetActiveLockReason(),
+		HasPullRequest: githubIssue.PullRequestLinks != nil,
+		Milestone:      githubIssue.GetMilestone().GetNumber(),
+		URL:            githubIssue.GetHTMLURL(),
+		NodeID:         githubIssue.GetNodeID(),
+		RepositoryURL:  githubIssue.GetRepositoryURL(),
+	}
+
+	for _, label := range githubIssue.Labels {
+		issue.Labels = append(issue.Labels, label.GetName())
+	}
+	for _, assignee := range githubIssue.Assignees {
+		issue.Assignees = append(issue.Assignees, assignee.GetLogin())
+	}
+	issue.CreatedAt = githubIssue.CreatedAt
+	issue.UpdatedAt = githubIssue.UpdatedAt
+	issue.ClosedAt = githubIssue.ClosedAt
+
+	return issue
+}
+
+// this function converts an IssueRequest to a request of the github api
+func toGithubIssueRequest(request *IssueRequest) *github.IssueRequest {
+	return &github.IssueRequest{
+		Title:     request.Title,
+		Body:      request.Body,
+		State:     request.State,
+		Labels:    request.Labels,
+		Assignees: request.Assignees,
+		Milestone: request.Milestone,
+	}
+}
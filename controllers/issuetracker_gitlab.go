@@ -0,0 +1,336 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// gitlabPerPage is the size of the pages requested from the gitlab api
+	gitlabPerPage int = 100
+
+	gitlabStateOpened string = "opened"
+)
+
+// GitlabIssueTracker manages the issues of projects on a gitlab instance
+// through its REST api, the owner of a repository is the path of its group
+type GitlabIssueTracker struct {
+	// BaseURL is the URL of the gitlab instance, e.g. https://gitlab.com
+	BaseURL string
+
+	// Token is the personal access token sent in the PRIVATE-TOKEN header
+	Token string
+
+	// HTTPClient sends the requests, http.DefaultClient is used if it is nil
+	HTTPClient *http.Client
+}
+
+var _ IssueTracker = &GitlabIssueTracker{}
+
+// NewGitlabIssueTracker returns a tracker for the gitlab instance at a URL
+func NewGitlabIssueTracker(baseURL, token string) *GitlabIssueTracker {
+	return &GitlabIssueTracker{BaseURL: baseURL, Token: token}
+}
+
+// gitlabIssue is an issue of the gitlab api
+type gitlabIssue struct {
+	IID         int      `json:"iid"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	State       string   `json:"state"`
+	Labels      []string `json:"labels"`
+	Assignees   []struct {
+		Username string `json:"username"`
+	} `json:"assignees"`
+	DiscussionLocked   bool       `json:"discussion_locked"`
+	MergeRequestsCount int        `json:"merge_requests_count"`
+	WebURL             string     `json:"web_url"`
+	CreatedAt          *time.Time `json:"created_at"`
+	UpdatedAt          *time.Time `json:"updated_at"`
+	ClosedAt           *time.Time `json:"closed_at"`
+}
+
+// gitlabNote is a note on an issue of the gitlab api, system notes record events such as label changes
+type gitlabNote struct {
+	Body   string `json:"body"`
+	System bool   `json:"system"`
+	Author struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// gitlabIssueRequest holds the fields set on an issue through the gitlab api
+type gitlabIssueRequest struct {
+	Title            *string `json:"title,omitempty"`
+	Description      *string `json:"description,omitempty"`
+	Labels           *string `json:"labels,omitempty"`
+	AssigneeIDs      *[]int  `json:"assignee_ids,omitempty"`
+	StateEvent       string  `json:"state_event,omitempty"`
+	DiscussionLocked *bool   `json:"discussion_locked,omitempty"`
+}
+
+// Get returns the issue with a number
+func (t *GitlabIssueTracker) Get(ctx context.Context, owner, repo string, number int) (*Issue, error) {
+	var issue gitlabIssue
+	if err := t.do(ctx, http.MethodGet, t.issuesPath(owner, repo)+"/"+strconv.Itoa(number), nil, &issue); err != nil {
+		return nil, err
+	}
+	return fromGitlabIssue(&issue), nil
+}
+
+// Find returns the issue with a title, nil is returned if no issue has the title
+func (t *GitlabIssueTracker) Find(ctx context.Context, owner, repo, title string) (*Issue, error) {
+	query := url.Values{"search": {title}, "in": {"title"}}
+	issues, err := t.list(ctx, owner, repo, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// the search matches parts of titles, so the titles are compared
+	for _, issue := range issues {
+		if issue.Title == title {
+			return issue, nil
+		}
+	}
+	return nil, nil
+}
+
+// List returns the open and closed issues of a repository
+func (t *GitlabIssueTracker) List(ctx context.Context, owner, repo string) ([]*Issue, error) {
+	return t.list(ctx, owner, repo, url.Values{})
+}
+
+// Create creates an issue
+func (t *GitlabIssueTracker) Create(ctx context.Context, owner, repo string, request *IssueRequest) (*Issue, error) {
+	gitlabRequest, err := t.toGitlabIssueRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var issue gitlabIssue
+	if err := t.do(ctx, http.MethodPost, t.issuesPath(owner, repo), gitlabRequest, &issue); err != nil {
+		return nil, err
+	}
+
+	// issues are created open, a closed issue is closed right away
+	if request.State != nil && *request.State == issueStateClosed {
+		return t.Update(ctx, owner, repo, issue.IID, &IssueRequest{State: request.State})
+	}
+
+	return fromGitlabIssue(&issue), nil
+}
+
+// Update sets the fields of the request on an issue
+func (t *GitlabIssueTracker) Update(ctx context.Context, owner, repo string, number int, request *IssueRequest) (*Issue, error) {
+	gitlabRequest, err := t.toGitlabIssueRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var issue gitlabIssue
+	if err := t.do(ctx, http.MethodPut, t.issuesPath(owner, repo)+"/"+strconv.Itoa(number), gitlabRequest, &issue); err != nil {
+		return nil, err
+	}
+	return fromGitlabIssue(&issue), nil
+}
+
+// Close closes an issue
+func (t *GitlabIssueTracker) Close(ctx context.Context, owner, repo string, number int) error {
+	state := issueStateClosed
+	_, err := t.Update(ctx, owner, repo, number, &IssueRequest{State: &state})
+	return err
+}
+
+// Comment posts a note on an issue
+func (t *GitlabIssueTracker) Comment(ctx context.Context, owner, repo string, number int, body string) error {
+	note := map[string]string{"body": body}
+	return t.do(ctx, http.MethodPost, t.issuesPath(owner, repo)+"/"+strconv.Itoa(number)+"/notes", note, nil)
+}
+
+// Comments returns the notes on an issue which were written by users, following the pages of the listing
+func (t *GitlabIssueTracker) Comments(ctx context.Context, owner, repo string, number int) ([]*IssueComment, error) {
+	query := url.Values{
+		"sort":     {"asc"},
+		"order_by": {"created_at"},
+		"per_page": {strconv.Itoa(gitlabPerPage)},
+	}
+
+	var comments []*IssueComment
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var notes []gitlabNote
+		if err := t.do(ctx, http.MethodGet, t.issuesPath(owner, repo)+"/"+strconv.Itoa(number)+"/notes?"+query.Encode(), nil, &notes); err != nil {
+			return nil, err
+		}
+
+		for _, note := range notes {
+			if note.System {
+				continue
+			}
+			comments = append(comments, &IssueComment{Author: note.Author.Username, Body: note.Body, CreatedAt: note.CreatedAt})
+		}
+		if len(notes) < gitlabPerPage {
+			return comments, nil
+		}
+	}
+}
+
+// Lock locks the discussion of an issue, gitlab doesn't record a reason
+func (t *GitlabIssueTracker) Lock(ctx context.Context, owner, repo string, number int, reason string) error {
+	return t.setDiscussionLocked(ctx, owner, repo, number, true)
+}
+
+// Unlock unlocks the discussion of an issue
+func (t *GitlabIssueTracker) Unlock(ctx context.Context, owner, repo string, number int) error {
+	return t.setDiscussionLocked(ctx, owner, repo, number, false)
+}
+
+// this function locks or unlocks the discussion of an issue
+func (t *GitlabIssueTracker) setDiscussionLocked(ctx context.Context, owner, repo string, number int, locked bool) error {
+	gitlabRequest := &gitlabIssueRequest{DiscussionLocked: &locked}
+	return t.do(ctx, http.MethodPut, t.issuesPath(owner, repo)+"/"+strconv.Itoa(number), gitlabRequest, nil)
+}
+
+// this function returns all pages of the issues of a project matching a query
+func (t *GitlabIssueTracker) list(ctx context.Context, owner, repo string, query url.Values) ([]*Issue, error) {
+	query.Set("scope", "all")
+	query.Set("per_page", strconv.Itoa(gitlabPerPage))
+
+	var issues []*Issue
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var pageIssues []gitlabIssue
+		if err := t.do(ctx, http.MethodGet, t.issuesPath(owner, repo)+"?"+query.Encode(), nil, &pageIssues); err != nil {
+			return nil, err
+		}
+
+		for i := range pageIssues {
+			issues = append(issues, fromGitlabIssue(&pageIssues[i]))
+		}
+		if len(pageIssues) < gitlabPerPage {
+			return issues, nil
+		}
+	}
+}
+
+// this function converts an IssueRequest to a request of the gitlab api,
+// the usernames of the assignees are resolved to the IDs of the users
+func (t *GitlabIssueTracker) toGitlabIssueRequest(ctx context.Context, request *IssueRequest) (*gitlabIssueRequest, error) {
+	if request.Milestone != nil {
+		return nil, fmt.Errorf("milestones are not supported by the gitlab issue tracker")
+	}
+
+	gitlabRequest := &gitlabIssueRequest{
+		Title:       request.Title,
+		Description: request.Body,
+	}
+
+	if request.Labels != nil {
+		labels := strings.Join(*request.Labels, ",")
+		gitlabRequest.Labels = &labels
+	}
+
+	if request.Assignees != nil {
+		assigneeIDs := []int{}
+		for _, username := range *request.Assignees {
+			id, err := t.getUserID(ctx, username)
+			if err != nil {
+				return nil, err
+			}
+			assigneeIDs = append(assigneeIDs, id)
+		}
+		gitlabRequest.AssigneeIDs = &assigneeIDs
+	}
+
+	if request.State != nil {
+		switch *request.State {
+		case issueStateOpen:
+			gitlabRequest.StateEvent = "reopen"
+		case issueStateClosed:
+			gitlabRequest.StateEvent = "close"
+		}
+	}
+
+	return gitlabRequest, nil
+}
+
+// this function returns the ID of the user with a username
+func (t *GitlabIssueTracker) getUserID(ctx context.Context, username string) (int, error) {
+	var users []struct {
+		ID int `json:"id"`
+	}
+	if err := t.do(ctx, http.MethodGet, "/users?"+url.Values{"username": {username}}.Encode(), nil, &users); err != nil {
+		return 0, err
+	}
+
+	if len(users) == 0 {
+		return 0, fmt.Errorf("gitlab user %s does not exist", username)
+	}
+	return users[0].ID, nil
+}
+
+// this function returns the api path of the issues of a project
+func (t *GitlabIssueTracker) issuesPath(owner, repo string) string {
+	return "/projects/" + url.PathEscape(owner+"/"+repo) + "/issues"
+}
+
+// this function sends a request to the gitlab api and decodes its response into out
+func (t *GitlabIssueTracker) do(ctx context.Context, method, path string, in, out interface{}) error {
+	header := http.Header{}
+	if t.Token != "" {
+		header.Set("PRIVATE-TOKEN", t.Token)
+	}
+	return sendTrackerRequest(ctx, t.HTTPClient, method, strings.TrimSuffix(t.BaseURL, "/")+"/api/v4"+path, header, in, out)
+}
+
+// this function converts an issue of the gitlab api to an Issue
+func fromGitlabIssue(gitlabIssue *gitlabIssue) *Issue {
+	state := issueStateClosed
+	if gitlabIssue.State == gitlabStateOpened {
+		state = issueStateOpen
+	}
+
+	issue := &Issue{
+		Number:         gitlabIssue.IID,
+		Title:          gitlabIssue.Title,
+		Body:           gitlabIssue.Description,
+		State:          state,
+		Labels:         gitlabIssue.Labels,
+		Locked:         gitlabIssue.DiscussionLocked,
+		HasPullRequest: gitlabIssue.MergeRequestsCount > 0,
+		URL:            gitlabIssue.WebURL,
+		CreatedAt:      gitlabIssue.CreatedAt,
+		UpdatedAt:      gitlabIssue.UpdatedAt,
+		ClosedAt:       gitlabIssue.ClosedAt,
+	}
+
+	for _, assignee := range gitlabIssue.Assignees {
+		issue.Assignees = append(issue.Assignees, assignee.Username)
+	}
+
+	return issue
+}
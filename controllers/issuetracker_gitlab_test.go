@@ -0,0 +1,255 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// fakeGitlab serves the parts of the gitlab issues api used by the GitlabIssueTracker
+type fakeGitlab struct {
+	mu     sync.Mutex
+	issues map[string][]*gitlabIssue
+	users  map[string]int
+}
+
+// this function starts a fake gitlab server and returns a tracker which uses it
+func newFakeGitlab(t *testing.T) (*fakeGitlab, *GitlabIssueTracker) {
+	fake := &fakeGitlab{issues: map[string][]*gitlabIssue{}, users: map[string]int{"alice": 1, "bob": 2}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, NewGitlabIssueTracker(server.URL, "token")
+}
+
+func (f *fakeGitlab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("PRIVATE-TOKEN") != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4")
+	if path == "/users" {
+		users := []map[string]int{}
+		if id, ok := f.users[r.URL.Query().Get("username")]; ok {
+			users = append(users, map[string]int{"id": id})
+		}
+		json.NewEncoder(w).Encode(users)
+		return
+	}
+
+	// paths are /projects/:id/issues[/:iid]
+	parts := strings.Split(strings.TrimPrefix(path, "/projects/"), "/")
+	if len(parts) < 2 || parts[1] != "issues" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	project := parts[0]
+
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			issues := []*gitlabIssue{}
+			search := r.URL.Query().Get("search")
+			for _, issue := range f.issues[project] {
+				if strings.Contains(issue.Title, search) {
+					issues = append(issues, issue)
+				}
+			}
+			json.NewEncoder(w).Encode(issues)
+		case http.MethodPost:
+			issue := &gitlabIssue{IID: len(f.issues[project]) + 1, State: gitlabStateOpened}
+			f.apply(issue, r)
+			f.issues[project] = append(f.issues[project], issue)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(issue)
+		}
+		return
+	}
+
+	iid, _ := strconv.Atoi(parts[2])
+	if iid < 1 || iid > len(f.issues[project]) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	issue := f.issues[project][iid-1]
+	if r.Method == http.MethodPut {
+		f.apply(issue, r)
+	}
+	json.NewEncoder(w).Encode(issue)
+}
+
+// this function sets the fields of a request on an issue
+func (f *fakeGitlab) apply(issue *gitlabIssue, r *http.Request) {
+	request := gitlabIssueRequest{}
+	json.NewDecoder(r.Body).Decode(&request)
+
+	if request.Title != nil {
+		issue.Title = *request.Title
+	}
+	if request.Description != nil {
+		issue.Description = *request.Description
+	}
+	if request.Labels != nil {
+		issue.Labels = strings.Split(*request.Labels, ",")
+	}
+	if request.AssigneeIDs != nil {
+		issue.Assignees = nil
+		for _, id := range *request.AssigneeIDs {
+			for username, userID := range f.users {
+				if userID == id {
+					issue.Assignees = append(issue.Assignees, struct {
+						Username string `json:"username"`
+					}{Username: username})
+				}
+			}
+		}
+	}
+	switch request.StateEvent {
+	case "close":
+		issue.State = "closed"
+	case "reopen":
+		issue.State = gitlabStateOpened
+	}
+	if request.DiscussionLocked != nil {
+		issue.DiscussionLocked = *request.DiscussionLocked
+	}
+}
+
+func TestGitlabIssueTracker(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+	fake, tracker := newFakeGitlab(t)
+
+	title := "Upgrade dependencies"
+	body := "Dependencies are outdated"
+	labels := []string{"dependencies", "bug"}
+	assignees := []string{"alice"}
+	issue, err := tracker.Create(ctx, testOwnerName, testRepoName, &IssueRequest{
+		Title:     &title,
+		Body:      &body,
+		Labels:    &labels,
+		Assignees: &assignees,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue.Number).To(Equal(1))
+	g.Expect(issue.State).To(Equal(issueStateOpen))
+	g.Expect(issue.Labels).To(Equal(labels))
+	g.Expect(issue.Assignees).To(Equal(assignees))
+
+	// the project is addressed by its escaped path
+	g.Expect(fake.issues).To(HaveKey("testOrg%2FtestRepo"))
+
+	found, err := tracker.Find(ctx, testOwnerName, testRepoName, title)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).ToNot(BeNil())
+	g.Expect(found.Number).To(Equal(1))
+
+	found, err = tracker.Find(ctx, testOwnerName, testRepoName, "Upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeNil())
+
+	g.Expect(tracker.Close(ctx, testOwnerName, testRepoName, 1)).To(Succeed())
+	g.Expect(tracker.Lock(ctx, testOwnerName, testRepoName, 1, "resolved")).To(Succeed())
+	issue, err = tracker.Get(ctx, testOwnerName, testRepoName, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue.State).To(Equal(issueStateClosed))
+	g.Expect(issue.Locked).To(BeTrue())
+
+	// unknown users and milestones are errors
+	unknown := []string{"mallory"}
+	_, err = tracker.Update(ctx, testOwnerName, testRepoName, 1, &IssueRequest{Assignees: &unknown})
+	g.Expect(err).To(HaveOccurred())
+
+	milestone := 1
+	_, err = tracker.Update(ctx, testOwnerName, testRepoName, 1, &IssueRequest{Milestone: &milestone})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = tracker.Get(ctx, testOwnerName, testRepoName, 2)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestReconcileGitlabIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+	fake, tracker := newFakeGitlab(t)
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Repo = "https://gitlab.com/" + testOwnerName + "/" + testRepoName
+	githubIssue.Spec.Labels = []string{"bug"}
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// no github client is needed for repositories on gitlab
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, Trackers: map[string]IssueTracker{"gitlab.com": tracker}}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	issues := fake.issues["testOrg%2FtestRepo"]
+	g.Expect(issues).To(HaveLen(1))
+	g.Expect(issues[0].Title).To(Equal(githubIssue.Spec.Title))
+	g.Expect(issues[0].Labels).To(Equal([]string{"bug"}))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(controllerutil.ContainsFinalizer(&githubIssueReconciled, ghIssueFinalizer)).To(BeTrue())
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(Equal(1))
+	g.Expect(githubIssueReconciled.Status.ActiveDescription).To(Equal(githubIssue.Spec.Description))
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, issueOpenConditionType)).To(BeTrue())
+	g.Expect(apimeta.IsStatusConditionFalse(githubIssueReconciled.Status.Conditions, issueHasPRConditionType)).To(BeTrue())
+
+	// the issue is closed when the object is deleted
+	g.Expect(cl.Delete(ctx, &githubIssueReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issues[0].State).To(Equal("closed"))
+
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
@@ -0,0 +1,439 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// jiraPerPage is the size of the pages of search results requested from the jira api
+	jiraPerPage int = 100
+
+	// jiraDefaultIssueType is the type of the issues created when neither the object
+	// nor the tracker set one
+	jiraDefaultIssueType string = "Task"
+
+	// jiraDoneStatusCategory is the key of the category of the statuses of closed issues
+	jiraDoneStatusCategory string = "done"
+
+	// jiraTimeLayout is the layout of the timestamps of the jira api
+	jiraTimeLayout string = "2006-01-02T15:04:05.000-0700"
+
+	jiraIssueFields string = "summary,description,status,labels,assignee,created,updated,resolutiondate"
+)
+
+// JiraIssueTracker manages the issues of projects on jira cloud or jira server through
+// its REST api. The repository of an object is the key of the project, e.g.
+// https://jira.example.com/projects/OPS, and the number of an issue is the number in its key
+type JiraIssueTracker struct {
+	// BaseURL is the URL of the jira instance, e.g. https://example.atlassian.net
+	BaseURL string
+
+	// Username is the user authenticating with basic auth, the token is sent as a
+	// bearer token if it is not set
+	Username string
+
+	// Token is the api token, or the password, of the user
+	Token string
+
+	// Cloud is whether the instance is jira cloud, which identifies the assignees
+	// of issues by their account IDs instead of their names
+	Cloud bool
+
+	// IssueType is the type of the issues created for objects without an issue type, Task is used if it is not set
+	IssueType string
+
+	// CloseTransition and ReopenTransition are the names of the workflow transitions which close and
+	// reopen issues, the first transition to a status of the done category, or out of it, is used if they are not set
+	CloseTransition  string
+	ReopenTransition string
+
+	// HTTPClient sends the requests, http.DefaultClient is used if it is nil
+	HTTPClient *http.Client
+}
+
+var _ IssueTracker = &JiraIssueTracker{}
+
+// jiraIssue is an issue of the jira api
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string   `json:"summary"`
+		Description string   `json:"description"`
+		Labels      []string `json:"labels"`
+		Status      struct {
+			Name           string `json:"name"`
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		} `json:"status"`
+		Assignee *struct {
+			Name      string `json:"name"`
+			AccountID string `json:"accountId"`
+		} `json:"assignee"`
+		Created        string `json:"created"`
+		Updated        string `json:"updated"`
+		ResolutionDate string `json:"resolutiondate"`
+	} `json:"fields"`
+}
+
+// jiraUser is the author of a comment of the jira api
+type jiraUser struct {
+	Name      string `json:"name"`
+	AccountID string `json:"accountId"`
+}
+
+// jiraComment is a comment on an issue of the jira api
+type jiraComment struct {
+	Body    string   `json:"body"`
+	Author  jiraUser `json:"author"`
+	Created string   `json:"created"`
+}
+
+// jiraTransition is a transition of the workflow of an issue
+type jiraTransition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	To   struct {
+		StatusCategory struct {
+			Key string `json:"key"`
+		} `json:"statusCategory"`
+	} `json:"to"`
+}
+
+// Get returns the issue with a number
+func (t *JiraIssueTracker) Get(ctx context.Context, owner, repo string, number int) (*Issue, error) {
+	var issue jiraIssue
+	path := "/issue/" + url.PathEscape(jiraIssueKey(repo, number)) + "?fields=" + jiraIssueFields
+	if err := t.do(ctx, http.MethodGet, path, nil, &issue); err != nil {
+		return nil, err
+	}
+	return t.fromJiraIssue(&issue), nil
+}
+
+// Find returns the issue with a title, nil is returned if no issue has the title
+func (t *JiraIssueTracker) Find(ctx context.Context, owner, repo, title string) (*Issue, error) {
+	// the title is searched as a phrase, quotes and backslashes are dropped from it as the search only narrows down the issues
+	phrase := strings.NewReplacer(`\`, " ", `"`, " ").Replace(title)
+	issues, err := t.search(ctx, fmt.Sprintf(`project = "%s" AND summary ~ "\"%s\""`, repo, phrase))
+	if err != nil {
+		return nil, err
+	}
+
+	// the search matches words of summaries, so the titles are compared
+	for _, issue := range issues {
+		if issue.Title == title {
+			return issue, nil
+		}
+	}
+	return nil, nil
+}
+
+// List returns the open and closed issues of a project
+func (t *JiraIssueTracker) List(ctx context.Context, owner, repo string) ([]*Issue, error) {
+	return t.search(ctx, fmt.Sprintf(`project = "%s" ORDER BY key ASC`, repo))
+}
+
+// Create creates an issue, issues are created in the initial status of their workflow
+func (t *JiraIssueTracker) Create(ctx context.Context, owner, repo string, request *IssueRequest) (*Issue, error) {
+	fields, err := t.toJiraFields(request)
+	if err != nil {
+		return nil, err
+	}
+
+	issueType := t.IssueType
+	if request.IssueType != nil && *request.IssueType != "" {
+		issueType = *request.IssueType
+	}
+	if issueType == "" {
+		issueType = jiraDefaultIssueType
+	}
+	fields["project"] = map[string]string{"key": repo}
+	fields["issuetype"] = map[string]string{"name": issueType}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := t.do(ctx, http.MethodPost, "/issue", map[string]interface{}{"fields": fields}, &created); err != nil {
+		return nil, err
+	}
+
+	number, err := jiraIssueNumber(created.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.State != nil && *request.State == issueStateClosed {
+		return t.Update(ctx, owner, repo, number, &IssueRequest{State: request.State})
+	}
+	return t.Get(ctx, owner, repo, number)
+}
+
+// Update sets the fields of the request on an issue, the state
+// of the issue is changed through a transition of its workflow
+func (t *JiraIssueTracker) Update(ctx context.Context, owner, repo string, number int, request *IssueRequest) (*Issue, error) {
+	fields, err := t.toJiraFields(request)
+	if err != nil {
+		return nil, err
+	}
+
+	key := jiraIssueKey(repo, number)
+	if len(fields) > 0 {
+		if err := t.do(ctx, http.MethodPut, "/issue/"+url.PathEscape(key), map[string]interface{}{"fields": fields}, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	issue, err := t.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.State != nil && *request.State != issue.State {
+		if err := t.transition(ctx, key, *request.State == issueStateClosed); err != nil {
+			return nil, err
+		}
+		return t.Get(ctx, owner, repo, number)
+	}
+	return issue, nil
+}
+
+// Close closes an issue through a transition of its workflow
+func (t *JiraIssueTracker) Close(ctx context.Context, owner, repo string, number int) error {
+	state := issueStateClosed
+	_, err := t.Update(ctx, owner, repo, number, &IssueRequest{State: &state})
+	return err
+}
+
+// Comment posts a comment on an issue
+func (t *JiraIssueTracker) Comment(ctx context.Context, owner, repo string, number int, body string) error {
+	comment := map[string]string{"body": body}
+	return t.do(ctx, http.MethodPost, "/issue/"+url.PathEscape(jiraIssueKey(repo, number))+"/comment", comment, nil)
+}
+
+// Comments returns the comments on an issue, following the pages of the listing
+func (t *JiraIssueTracker) Comments(ctx context.Context, owner, repo string, number int) ([]*IssueComment, error) {
+	path := "/issue/" + url.PathEscape(jiraIssueKey(repo, number)) + "/comment"
+
+	var comments []*IssueComment
+	for startAt := 0; ; {
+		query := url.Values{"startAt": {strconv.Itoa(startAt)}, "maxResults": {strconv.Itoa(jiraPerPage)}}
+
+		var result struct {
+			Total    int           `json:"total"`
+			Comments []jiraComment `json:"comments"`
+		}
+		if err := t.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &result); err != nil {
+			return nil, err
+		}
+
+		for _, comment := range result.Comments {
+			author := comment.Author.Name
+			if t.Cloud {
+				author = comment.Author.AccountID
+			}
+			comments = append(comments, &IssueComment{Author: author, Body: comment.Body, CreatedAt: parseJiraTime(comment.Created)})
+		}
+
+		startAt += len(result.Comments)
+		if len(result.Comments) == 0 || startAt >= result.Total {
+			return comments, nil
+		}
+	}
+}
+
+// Lock is not supported, jira issues have no conversation to lock
+func (t *JiraIssueTracker) Lock(ctx context.Context, owner, repo string, number int, reason string) error {
+	return fmt.Errorf("locking issues is not supported by the jira issue tracker")
+}
+
+// Unlock is not supported, jira issues have no conversation to unlock
+func (t *JiraIssueTracker) Unlock(ctx context.Context, owner, repo string, number int) error {
+	return fmt.Errorf("unlocking issues is not supported by the jira issue tracker")
+}
+
+// this function returns all pages of the issues matching a jql query
+func (t *JiraIssueTracker) search(ctx context.Context, jql string) ([]*Issue, error) {
+	var issues []*Issue
+	for startAt := 0; ; {
+		searchRequest := map[string]interface{}{
+			"jql":        jql,
+			"startAt":    startAt,
+			"maxResults": jiraPerPage,
+			"fields":     strings.Split(jiraIssueFields, ","),
+		}
+
+		var result struct {
+			Total  int         `json:"total"`
+			Issues []jiraIssue `json:"issues"`
+		}
+		if err := t.do(ctx, http.MethodPost, "/search", searchRequest, &result); err != nil {
+			return nil, err
+		}
+
+		for i := range result.Issues {
+			issues = append(issues, t.fromJiraIssue(&result.Issues[i]))
+		}
+
+		startAt += len(result.Issues)
+		if len(result.Issues) == 0 || startAt >= result.Total {
+			return issues, nil
+		}
+	}
+}
+
+// this function moves an issue to a status of the done category, or out of it, through
+// the configured transition or the first transition to such a status
+func (t *JiraIssueTracker) transition(ctx context.Context, key string, closing bool) error {
+	var result struct {
+		Transitions []jiraTransition `json:"transitions"`
+	}
+	if err := t.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(key)+"/transitions", nil, &result); err != nil {
+		return err
+	}
+
+	name := t.ReopenTransition
+	if closing {
+		name = t.CloseTransition
+	}
+
+	for _, transition := range result.Transitions {
+		matches := (transition.To.StatusCategory.Key == jiraDoneStatusCategory) == closing
+		if name != "" {
+			matches = strings.EqualFold(transition.Name, name)
+		}
+		if matches {
+			transitionRequest := map[string]interface{}{"transition": map[string]string{"id": transition.ID}}
+			return t.do(ctx, http.MethodPost, "/issue/"+url.PathEscape(key)+"/transitions", transitionRequest, nil)
+		}
+	}
+
+	if closing {
+		return fmt.Errorf("issue %s has no transition which closes it", key)
+	}
+	return fmt.Errorf("issue %s has no transition which reopens it", key)
+}
+
+// this function converts an IssueRequest to the fields of a jira issue, the state is
+// left out as it is changed through transitions
+func (t *JiraIssueTracker) toJiraFields(request *IssueRequest) (map[string]interface{}, error) {
+	if request.Milestone != nil {
+		return nil, fmt.Errorf("milestones are not supported by the jira issue tracker")
+	}
+
+	fields := map[string]interface{}{}
+	if request.Title != nil {
+		fields["summary"] = *request.Title
+	}
+	if request.Body != nil {
+		fields["description"] = *request.Body
+	}
+	if request.Labels != nil {
+		fields["labels"] = *request.Labels
+	}
+	if request.Assignees != nil {
+		switch assignees := *request.Assignees; {
+		case len(assignees) > 1:
+			return nil, fmt.Errorf("jira issues have a single assignee, got %d", len(assignees))
+		case len(assignees) == 0:
+			fields["assignee"] = nil
+		case t.Cloud:
+			fields["assignee"] = map[string]string{"accountId": assignees[0]}
+		default:
+			fields["assignee"] = map[string]string{"name": assignees[0]}
+		}
+	}
+	return fields, nil
+}
+
+// this function sends a request to the jira api and decodes its response into out
+func (t *JiraIssueTracker) do(ctx context.Context, method, path string, in, out interface{}) error {
+	header := http.Header{}
+	switch {
+	case t.Username != "":
+		credentials := base64.StdEncoding.EncodeToString([]byte(t.Username + ":" + t.Token))
+		header.Set("Authorization", "Basic "+credentials)
+	case t.Token != "":
+		header.Set("Authorization", "Bearer "+t.Token)
+	}
+	return sendTrackerRequest(ctx, t.HTTPClient, method, strings.TrimSuffix(t.BaseURL, "/")+"/rest/api/2"+path, header, in, out)
+}
+
+// this function converts an issue of the jira api to an Issue
+func (t *JiraIssueTracker) fromJiraIssue(jiraIssue *jiraIssue) *Issue {
+	number, _ := jiraIssueNumber(jiraIssue.Key)
+
+	state := issueStateOpen
+	if jiraIssue.Fields.Status.StatusCategory.Key == jiraDoneStatusCategory {
+		state = issueStateClosed
+	}
+
+	issue := &Issue{
+		Number: number,
+		Title:  jiraIssue.Fields.Summary,
+		Body:   jiraIssue.Fields.Description,
+		State:  state,
+		Labels: jiraIssue.Fields.Labels,
+		URL:    strings.TrimSuffix(t.BaseURL, "/") + "/browse/" + jiraIssue.Key,
+	}
+
+	if assignee := jiraIssue.Fields.Assignee; assignee != nil {
+		if t.Cloud {
+			issue.Assignees = []string{assignee.AccountID}
+		} else {
+			issue.Assignees = []string{assignee.Name}
+		}
+	}
+
+	issue.CreatedAt = parseJiraTime(jiraIssue.Fields.Created)
+	issue.UpdatedAt = parseJiraTime(jiraIssue.Fields.Updated)
+	issue.ClosedAt = parseJiraTime(jiraIssue.Fields.ResolutionDate)
+
+	return issue
+}
+
+// this function parses a timestamp of the jira api, nil is returned for empty or invalid timestamps
+func parseJiraTime(value string) *time.Time {
+	parsed, err := time.Parse(jiraTimeLayout, value)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+// this function returns the key of the issue with a number in a project
+func jiraIssueKey(project string, number int) string {
+	return strings.ToUpper(project) + "-" + strconv.Itoa(number)
+}
+
+// this function returns the number in the key of an issue, e.g. 12 for OPS-12
+func jiraIssueNumber(key string) (int, error) {
+	index := strings.LastIndex(key, "-")
+	number, err := strconv.Atoi(key[index+1:])
+	if index < 0 || err != nil {
+		return 0, fmt.Errorf("unexpected jira issue key %q", key)
+	}
+	return number, nil
+}
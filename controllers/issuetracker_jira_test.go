@@ -0,0 +1,345 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const testJiraProject = "OPS"
+
+// fakeJira serves the parts of the jira api used by the JiraIssueTracker for a single
+// project, whose workflow goes from To Do to In Progress and Done and back to To Do
+type fakeJira struct {
+	mu          sync.Mutex
+	issues      []map[string]interface{}
+	issueTypes  []string
+	comments    map[string][]string
+	transitions []string
+}
+
+// this function starts a fake jira server and returns a tracker which uses it
+func newFakeJira(t *testing.T) (*fakeJira, *JiraIssueTracker) {
+	fake := &fakeJira{comments: map[string][]string{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, &JiraIssueTracker{BaseURL: server.URL, Username: "bot", Token: "token"}
+}
+
+// fakeJiraStatuses maps the statuses of the workflow of the fake to their categories
+var fakeJiraStatuses = map[string]string{"To Do": "new", "In Progress": "indeterminate", "Done": "done"}
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if username, password, ok := r.BasicAuth(); !ok || username != "bot" || password != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/rest/api/2")
+	switch {
+	case path == "/search":
+		request := struct {
+			JQL        string `json:"jql"`
+			StartAt    int    `json:"startAt"`
+			MaxResults int    `json:"maxResults"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&request)
+
+		issues := []interface{}{}
+		for i := range f.issues {
+			issue := f.render(i)
+			summary := issue["fields"].(map[string]interface{})["summary"].(string)
+			if !strings.Contains(request.JQL, "summary ~") || strings.Contains(request.JQL, `\"`+summary+`\"`) {
+				issues = append(issues, issue)
+			}
+		}
+		page := issues[request.StartAt:]
+		if len(page) > request.MaxResults {
+			page = page[:request.MaxResults]
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total": len(issues), "issues": page})
+		return
+	case path == "/issue" && r.Method == http.MethodPost:
+		request := struct {
+			Fields map[string]interface{} `json:"fields"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.Fields["project"].(map[string]interface{})["key"] != testJiraProject {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.issueTypes = append(f.issueTypes, request.Fields["issuetype"].(map[string]interface{})["name"].(string))
+		fields := map[string]interface{}{"status": "To Do", "labels": []interface{}{}}
+		f.issues = append(f.issues, fields)
+		f.apply(fields, request.Fields)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"key": testJiraProject + "-" + strconv.Itoa(len(f.issues))})
+		return
+	}
+
+	// paths are /issue/:key[/transitions|/comment]
+	parts := strings.Split(strings.TrimPrefix(path, "/issue/"), "/")
+	number, _ := strconv.Atoi(strings.TrimPrefix(parts[0], testJiraProject+"-"))
+	if number < 1 || number > len(f.issues) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	fields := f.issues[number-1]
+
+	switch {
+	case len(parts) == 2 && parts[1] == "comment" && r.Method == http.MethodGet:
+		comments := []map[string]interface{}{}
+		for _, body := range f.comments[parts[0]] {
+			comments = append(comments, map[string]interface{}{
+				"body":    body,
+				"author":  map[string]string{"name": "jdoe", "accountId": "5b10a2844c20165700ede21g"},
+				"created": "2026-03-01T10:00:00.000+0000",
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"total": len(comments), "comments": comments})
+	case len(parts) == 2 && parts[1] == "comment":
+		request := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&request)
+		f.comments[parts[0]] = append(f.comments[parts[0]], request["body"])
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(request)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		transitions := []map[string]interface{}{}
+		for id, status := range []string{"To Do", "In Progress", "Done"} {
+			if status != fields["status"] {
+				transitions = append(transitions, map[string]interface{}{
+					"id":   strconv.Itoa(id),
+					"name": "Move to " + status,
+					"to":   map[string]interface{}{"statusCategory": map[string]string{"key": fakeJiraStatuses[status]}},
+				})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"transitions": transitions})
+	case len(parts) == 2:
+		request := struct {
+			Transition struct {
+				ID string `json:"id"`
+			} `json:"transition"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&request)
+		id, _ := strconv.Atoi(request.Transition.ID)
+		fields["status"] = []string{"To Do", "In Progress", "Done"}[id]
+		f.transitions = append(f.transitions, parts[0]+" "+fields["status"].(string))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		request := struct {
+			Fields map[string]interface{} `json:"fields"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&request)
+		f.apply(fields, request.Fields)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		json.NewEncoder(w).Encode(f.render(number - 1))
+	}
+}
+
+// this function sets the fields of a request on an issue
+func (f *fakeJira) apply(fields map[string]interface{}, requestFields map[string]interface{}) {
+	for _, name := range []string{"summary", "description", "labels", "assignee"} {
+		if value, ok := requestFields[name]; ok {
+			fields[name] = value
+		}
+	}
+	fields["updated"] = time.Now().Format(jiraTimeLayout)
+}
+
+// this function returns an issue as the jira api serves it
+func (f *fakeJira) render(index int) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for name, value := range f.issues[index] {
+		fields[name] = value
+	}
+	status := f.issues[index]["status"].(string)
+	fields["status"] = map[string]interface{}{"name": status, "statusCategory": map[string]string{"key": fakeJiraStatuses[status]}}
+	return map[string]interface{}{"key": testJiraProject + "-" + strconv.Itoa(index+1), "fields": fields}
+}
+
+func TestJiraIssueTracker(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+	fake, tracker := newFakeJira(t)
+
+	title := "Rotate certificates"
+	body := "The certificates expire next month"
+	labels := []string{"security"}
+	assignees := []string{"alice"}
+	issue, err := tracker.Create(ctx, "projects", testJiraProject, &IssueRequest{
+		Title:     &title,
+		Body:      &body,
+		Labels:    &labels,
+		Assignees: &assignees,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue.Number).To(Equal(1))
+	g.Expect(issue.Title).To(Equal(title))
+	g.Expect(issue.Body).To(Equal(body))
+	g.Expect(issue.State).To(Equal(issueStateOpen))
+	g.Expect(issue.Labels).To(Equal(labels))
+	g.Expect(issue.Assignees).To(Equal(assignees))
+	g.Expect(issue.URL).To(Equal(tracker.BaseURL + "/browse/OPS-1"))
+	g.Expect(issue.UpdatedAt).ToNot(BeNil())
+	g.Expect(fake.issueTypes).To(Equal([]string{jiraDefaultIssueType}))
+
+	// the issue type of the request is used when it is set
+	otherTitle := "Upgrade the cluster"
+	issueType := "Story"
+	_, err = tracker.Create(ctx, "projects", testJiraProject, &IssueRequest{Title: &otherTitle, IssueType: &issueType})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(fake.issueTypes).To(Equal([]string{jiraDefaultIssueType, "Story"}))
+
+	issues, err := tracker.List(ctx, "projects", testJiraProject)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issues).To(HaveLen(2))
+
+	found, err := tracker.Find(ctx, "projects", testJiraProject, title)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).ToNot(BeNil())
+	g.Expect(found.Number).To(Equal(1))
+
+	found, err = tracker.Find(ctx, "projects", testJiraProject, "Rotate")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeNil())
+
+	// the state is changed through the transitions of the workflow
+	g.Expect(tracker.Close(ctx, "projects", testJiraProject, 1)).To(Succeed())
+	open := issueStateOpen
+	issue, err = tracker.Update(ctx, "projects", testJiraProject, 1, &IssueRequest{State: &open})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue.State).To(Equal(issueStateOpen))
+	g.Expect(fake.transitions).To(Equal([]string{"OPS-1 Done", "OPS-1 To Do"}))
+
+	// a configured transition is used by its name
+	g.Expect(tracker.Close(ctx, "projects", testJiraProject, 1)).To(Succeed())
+	tracker.ReopenTransition = "move to in progress"
+	issue, err = tracker.Update(ctx, "projects", testJiraProject, 1, &IssueRequest{State: &open})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue.State).To(Equal(issueStateOpen))
+	g.Expect(fake.transitions[3]).To(Equal("OPS-1 In Progress"))
+
+	g.Expect(tracker.Comment(ctx, "projects", testJiraProject, 1, "Rotated")).To(Succeed())
+	g.Expect(fake.comments["OPS-1"]).To(Equal([]string{"Rotated"}))
+	comments, err := tracker.Comments(ctx, "projects", testJiraProject, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(comments).To(HaveLen(1))
+	g.Expect(comments[0].Body).To(Equal("Rotated"))
+	g.Expect(comments[0].CreatedAt.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC))).To(BeTrue())
+
+	// several assignees, milestones and locks are errors
+	several := []string{"alice", "bob"}
+	_, err = tracker.Update(ctx, "projects", testJiraProject, 1, &IssueRequest{Assignees: &several})
+	g.Expect(err).To(HaveOccurred())
+
+	milestone := 1
+	_, err = tracker.Update(ctx, "projects", testJiraProject, 1, &IssueRequest{Milestone: &milestone})
+	g.Expect(err).To(HaveOccurred())
+
+	g.Expect(tracker.Lock(ctx, "projects", testJiraProject, 1, "resolved")).ToNot(Succeed())
+
+	_, err = tracker.Get(ctx, "projects", testJiraProject, 100)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestReconcileJiraIssue(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	ctx := context.Background()
+	fake, tracker := newFakeJira(t)
+
+	githubIssue := GenerateGithubIssueObject()
+	githubIssue.Spec.Repo = "https://jira.example.com/projects/" + testJiraProject
+	githubIssue.Spec.IssueType = "Bug"
+	githubIssue.Spec.ClosePolicy = &trainingv1alpha1.ClosePolicy{Comment: "The object of this issue was deleted"}
+
+	obj := []client.Object{githubIssue}
+	cl, s, err := SetupClient(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	r := &GithubIssueReconciler{Client: cl, Scheme: s, Trackers: map[string]IssueTracker{"jira.example.com": tracker}}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      githubIssue.ObjectMeta.Name,
+			Namespace: githubIssue.ObjectMeta.Namespace,
+		},
+	}
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(fake.issues).To(HaveLen(1))
+	g.Expect(fake.issues[0]["summary"]).To(Equal(githubIssue.Spec.Title))
+	g.Expect(fake.issueTypes).To(Equal([]string{"Bug"}))
+
+	githubIssueReconciled := trainingv1alpha1.GithubIssue{}
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(githubIssueReconciled.Status.IssueNumber).To(Equal(1))
+	g.Expect(githubIssueReconciled.Status.ActiveDescription).To(Equal(githubIssue.Spec.Description))
+	g.Expect(apimeta.IsStatusConditionTrue(githubIssueReconciled.Status.Conditions, issueOpenConditionType)).To(BeTrue())
+
+	// closing the issue through the spec transitions it to done
+	githubIssueReconciled.Spec.State = issueStateClosed
+	g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(fake.issues[0]["status"]).To(Equal("Done"))
+
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(apimeta.IsStatusConditionFalse(githubIssueReconciled.Status.Conditions, issueOpenConditionType)).To(BeTrue())
+
+	// the issue is reopened, commented on and closed when the object is deleted
+	githubIssueReconciled.Spec.State = issueStateOpen
+	g.Expect(cl.Update(ctx, &githubIssueReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)).To(Succeed())
+	g.Expect(cl.Delete(ctx, &githubIssueReconciled)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(fake.issues[0]["status"]).To(Equal("Done"))
+	g.Expect(fake.comments["OPS-1"]).To(Equal([]string{"The object of this issue was deleted"}))
+
+	err = cl.Get(ctx, req.NamespacedName, &githubIssueReconciled)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
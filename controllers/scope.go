@@ -0,0 +1,99 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// Scope restricts the objects handled by an operator instance, so that several instances
+// with different credentials can run side by side. The zero value handles every object
+type Scope struct {
+	// Namespaces are the namespaces which are watched, all namespaces are watched if it is empty
+	Namespaces []string
+
+	// Selector selects the objects which are handled by their labels, every object is handled if it is nil
+	Selector labels.Selector
+
+	// Shards is the number of instances the objects are spread across, sharding is disabled if it is below 2
+	Shards int
+
+	// ShardIndex is the shard handled by this instance, from 0 to Shards-1
+	ShardIndex int
+
+	// ShardLabel is the label whose value is hashed to pick the shard of an object, objects
+	// without the label are sharded by their namespace and name
+	ShardLabel string
+}
+
+// Validate checks that the shard of the scope is one of its shards
+func (s Scope) Validate() error {
+	if s.Shards > 1 && (s.ShardIndex < 0 || s.ShardIndex >= s.Shards) {
+		return fmt.Errorf("shard index %d is not between 0 and %d", s.ShardIndex, s.Shards-1)
+	}
+	return nil
+}
+
+// NewCache returns the function creating the cache of the manager, it only
+// holds the objects of the watched namespaces if there are any
+func (s Scope) NewCache() cache.NewCacheFunc {
+	switch len(s.Namespaces) {
+	case 0:
+		return cache.New
+	case 1:
+		return cache.BuilderWithOptions(cache.Options{Namespace: s.Namespaces[0]})
+	default:
+		return cache.MultiNamespacedCacheBuilder(s.Namespaces)
+	}
+}
+
+// Predicate returns the predicate filtering the events of the objects outside of the scope
+func (s Scope) Predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(s.Contains)
+}
+
+// Contains returns whether an object is handled by this instance
+func (s Scope) Contains(obj client.Object) bool {
+	if s.Selector != nil && !s.Selector.Matches(labels.Set(obj.GetLabels())) {
+		return false
+	}
+
+	if s.Shards < 2 {
+		return true
+	}
+
+	return s.shardOf(obj) == s.ShardIndex
+}
+
+// this function returns the shard of an object from the hash of its shard label,
+// or of its namespace and name if it doesn't have the label
+func (s Scope) shardOf(obj client.Object) int {
+	key := client.ObjectKeyFromObject(obj).String()
+	if value, ok := obj.GetLabels()[s.ShardLabel]; ok && s.ShardLabel != "" {
+		key = value
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(s.Shards))
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestScopeLabelSelector(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	selector, err := labels.Parse("team=platform")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	githubIssue := GenerateGithubIssueObject()
+
+	// every object is handled by the zero scope
+	g.Expect(Scope{}.Contains(githubIssue)).To(BeTrue())
+
+	scope := Scope{Selector: selector}
+	g.Expect(scope.Contains(githubIssue)).To(BeFalse())
+
+	githubIssue.Labels = map[string]string{"team": "platform"}
+	g.Expect(scope.Contains(githubIssue)).To(BeTrue())
+}
+
+func TestScopeShards(t *testing.T) {
+	g := NewGomegaWithT(t)
+	RegisterFailHandler(Fail)
+
+	g.Expect(Scope{Shards: 3, ShardIndex: 3}.Validate()).ToNot(Succeed())
+
+	scopes := []Scope{}
+	for i := 0; i < 3; i++ {
+		scopes = append(scopes, Scope{Shards: 3, ShardIndex: i, ShardLabel: "shard-key"})
+	}
+
+	// every object is handled by exactly one shard
+	for i := 0; i < 30; i++ {
+		githubIssue := &trainingv1alpha1.GithubIssue{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("issue-%d", i), Namespace: testNamespace},
+		}
+
+		shards := 0
+		for _, scope := range scopes {
+			if scope.Contains(githubIssue) {
+				shards++
+			}
+		}
+		g.Expect(shards).To(Equal(1))
+	}
+
+	// objects with the same shard label are handled by the same shard
+	first := &trainingv1alpha1.GithubIssue{
+		ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: testNamespace, Labels: map[string]string{"shard-key": "team-a"}},
+	}
+	second := &trainingv1alpha1.GithubIssue{
+		ObjectMeta: metav1.ObjectMeta{Name: "second", Namespace: "other", Labels: map[string]string{"shard-key": "team-a"}},
+	}
+	g.Expect(scopes[0].shardOf(first)).To(Equal(scopes[0].shardOf(second)))
+}
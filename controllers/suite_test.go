@@ -18,12 +18,10 @@ package controllers
 
 import (
 	"context"
-	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/google/go-github/v45/github"
-	"github.com/joho/godotenv"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -36,6 +34,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	"github.com/mzeevi/githubissues-operator/internal/githubfake"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -46,6 +45,7 @@ var (
 	cfg          *rest.Config
 	k8sClient    client.Client
 	githubClient *github.Client
+	githubServer *githubfake.Server
 	testEnv      *envtest.Environment
 	ctx          context.Context
 	cancel       context.CancelFunc
@@ -72,12 +72,6 @@ var _ = BeforeSuite(func() {
 
 	var err error
 
-	// set environment variables
-	if os.Getenv("GH_PERSONAL_TOKEN") == "" {
-		err = godotenv.Load("../.env.secret")
-		Expect(err).ToNot(HaveOccurred())
-	}
-
 	// cfg is defined in this file globally.
 	cfg, err = testEnv.Start()
 	Expect(err).NotTo(HaveOccurred())
@@ -96,7 +90,10 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).ToNot(HaveOccurred())
 
-	githubClient = GetGithubClient(ctx)
+	// the reconcilers talk to a fake github api, so the suite runs offline and leaves no issues behind
+	githubServer = githubfake.NewServer()
+	githubServer.AddRepo(e2eRepoOwner, e2eRepoName)
+	githubClient = githubServer.Client()
 
 	err = (&GithubIssueReconciler{
 		Client:       k8sManager.GetClient(),
@@ -105,6 +102,34 @@ var _ = BeforeSuite(func() {
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
+	err = (&GithubLabelReconciler{
+		Client:       k8sManager.GetClient(),
+		Scheme:       k8sManager.GetScheme(),
+		GithubClient: githubClient,
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&GithubMilestoneReconciler{
+		Client:       k8sManager.GetClient(),
+		Scheme:       k8sManager.GetScheme(),
+		GithubClient: githubClient,
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&GithubIssueTemplateReconciler{
+		Client:       k8sManager.GetClient(),
+		Scheme:       k8sManager.GetScheme(),
+		GithubClient: githubClient,
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&ClusterGithubIssueReconciler{
+		Client:       k8sManager.GetClient(),
+		Scheme:       k8sManager.GetScheme(),
+		GithubClient: githubClient,
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
 	go func() {
 		defer GinkgoRecover()
 		err = k8sManager.Start(ctx)
@@ -118,4 +143,5 @@ var _ = AfterSuite(func() {
 	By("tearing down the test environment")
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
+	githubServer.Close()
 })
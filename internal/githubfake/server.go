@@ -0,0 +1,1036 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package githubfake implements an in-process fake of the github REST api for tests. It
+// keeps the issues, comments and labels of repositories in memory, paginates lists like
+// github, sends rate limit headers and can be told to fail requests.
+package githubfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+const (
+	// defaultPerPage and maxPerPage are the page sizes of github
+	defaultPerPage int = 30
+	maxPerPage     int = 100
+
+	// defaultRateLimit is the number of requests allowed per hour for a user
+	defaultRateLimit int = 5000
+
+	// defaultLabelColor is the color of labels which are created by setting them on an issue
+	defaultLabelColor string = "ededed"
+
+	// webHost is the host of the web URLs of issues
+	webHost string = "https://github.com"
+
+	rateLimitDocumentationURL string = "https://docs.github.com/rest/overview/resources-in-the-rest-api#rate-limiting"
+
+	stateOpen   string = "open"
+	stateClosed string = "closed"
+	stateAll    string = "all"
+)
+
+// Issue is an issue held by the fake server
+type Issue struct {
+	Number     int
+	Title      string
+	Body       string
+	State      string
+	Labels     []string
+	Assignees  []string
+	Locked     bool
+	LockReason string
+	// Milestone is the number of the milestone of the issue, 0 if it has none
+	Milestone int
+	Comments  []Comment
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ClosedAt  *time.Time
+}
+
+// Comment is a comment on an issue held by the fake server
+type Comment struct {
+	ID int64
+	// Author is the login of the user who wrote the comment
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// Label is a label of a repository held by the fake server
+type Label struct {
+	ID          int64
+	Name        string
+	Color       string
+	Description string
+}
+
+// Fault makes the server fail the requests it matches instead of serving them
+type Fault struct {
+	// Method is the method of the failed requests, requests of every method are failed if it is empty
+	Method string
+
+	// Path is a regular expression matched against the path of the failed requests,
+	// requests to every path are failed if it is empty
+	Path string
+
+	// StatusCode is the status code of the responses, 500 is used if it is 0
+	StatusCode int
+
+	// Message is the message in the body of the responses
+	Message string
+
+	// Times is the number of requests which are failed, requests are failed until the faults are cleared if it is 0
+	Times int
+
+	// Delay delays the responses, e.g. to make requests time out
+	Delay time.Duration
+
+	path *regexp.Regexp
+}
+
+// repository is a repository held by the fake server
+type repository struct {
+	owner  string
+	name   string
+	issues []*Issue
+	labels []*Label
+}
+
+// Server is a fake github api served over http. Repositories have to be added before
+// requests for them are served, requests for other repositories get 404 responses
+type Server struct {
+	*httptest.Server
+
+	// Token is the token the requests have to be authenticated with, requests aren't authenticated if it is empty
+	Token string
+
+	// Now returns the current time, it is used for the timestamps of issues and the rate limit
+	Now func() time.Time
+
+	// Login is the user the token belongs to, who writes the comments created through the api
+	Login string
+
+	mu             sync.Mutex
+	repos          map[string]*repository
+	nextID         int64
+	faults         []*Fault
+	requests       []string
+	rateLimit      int
+	rateRemaining  int
+	rateLimitReset time.Time
+}
+
+// route is an endpoint of the api served by the fake server
+type route struct {
+	method  string
+	path    *regexp.Regexp
+	handler func(s *Server, w http.ResponseWriter, r *http.Request, params []string)
+}
+
+var routes = []route{
+	{http.MethodGet, regexp.MustCompile(`^/rate_limit$`), (*Server).getRateLimit},
+	{http.MethodGet, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/issues$`), (*Server).listIssues},
+	{http.MethodPost, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/issues$`), (*Server).createIssue},
+	{http.MethodGet, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/issues/(\d+)$`), (*Server).getIssue},
+	{http.MethodPatch, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/issues/(\d+)$`), (*Server).editIssue},
+	{http.MethodPut, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/issues/(\d+)/lock$`), (*Server).lockIssue},
+	{http.MethodDelete, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/issues/(\d+)/lock$`), (*Server).unlockIssue},
+	{http.MethodGet, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/issues/(\d+)/comments$`), (*Server).listComments},
+	{http.MethodPost, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/issues/(\d+)/comments$`), (*Server).createComment},
+	{http.MethodGet, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/labels$`), (*Server).listLabels},
+	{http.MethodPost, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/labels$`), (*Server).createLabel},
+	{http.MethodGet, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/labels/([^/]+)$`), (*Server).getLabel},
+	{http.MethodPatch, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/labels/([^/]+)$`), (*Server).editLabel},
+	{http.MethodDelete, regexp.MustCompile(`^/repos/([^/]+)/([^/]+)/labels/([^/]+)$`), (*Server).deleteLabel},
+}
+
+// NewServer starts a fake github api, it has to be closed once it is no longer used
+func NewServer() *Server {
+	s := &Server{
+		Now:           time.Now,
+		Login:         "octocat",
+		repos:         map[string]*repository{},
+		rateLimit:     defaultRateLimit,
+		rateRemaining: defaultRateLimit,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a github client which sends its requests to the server
+func (s *Server) Client() *github.Client {
+	client := github.NewClient(&http.Client{Transport: &tokenTransport{token: s.Token}})
+	client.BaseURL, _ = url.Parse(s.URL + "/")
+	client.UploadURL, _ = url.Parse(s.URL + "/")
+	return client
+}
+
+// AddRepo adds an empty repository to the server
+func (s *Server) AddRepo(owner, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := repoKey(owner, name)
+	if _, ok := s.repos[key]; !ok {
+		s.repos[key] = &repository{owner: owner, name: name}
+	}
+}
+
+// AddIssue adds an issue to a repository, the repository is added if it doesn't exist.
+// The number, state and timestamps of the issue are set if they are empty
+func (s *Server) AddIssue(owner, name string, issue Issue) Issue {
+	s.AddRepo(owner, name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := s.repos[repoKey(owner, name)]
+	if issue.Number == 0 {
+		issue.Number = len(repo.issues) + 1
+	}
+	if issue.State == "" {
+		issue.State = stateOpen
+	}
+	if issue.CreatedAt.IsZero() {
+		issue.CreatedAt = s.Now()
+	}
+	if issue.UpdatedAt.IsZero() {
+		issue.UpdatedAt = issue.CreatedAt
+	}
+	for _, name := range issue.Labels {
+		s.ensureLabel(repo, name)
+	}
+
+	stored := copyIssue(&issue)
+	repo.issues = append(repo.issues, &stored)
+	return issue
+}
+
+// Issues returns copies of the issues of a repository ordered by number
+func (s *Server) Issues(owner, name string) []Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, ok := s.repos[repoKey(owner, name)]
+	if !ok {
+		return nil
+	}
+
+	issues := []Issue{}
+	for _, issue := range repo.issues {
+		issues = append(issues, copyIssue(issue))
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Number < issues[j].Number })
+	return issues
+}
+
+// Issue returns a copy of an issue of a repository and whether it exists
+func (s *Server) Issue(owner, name string, number int) (Issue, bool) {
+	for _, issue := range s.Issues(owner, name) {
+		if issue.Number == number {
+			return issue, true
+		}
+	}
+	return Issue{}, false
+}
+
+// Labels returns copies of the labels of a repository
+func (s *Server) Labels(owner, name string) []Label {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, ok := s.repos[repoKey(owner, name)]
+	if !ok {
+		return nil
+	}
+
+	labels := []Label{}
+	for _, label := range repo.labels {
+		labels = append(labels, *label)
+	}
+	return labels
+}
+
+// SetRateLimit sets the number of requests allowed per hour, the number of requests which are
+// left and when they are reset. Requests beyond the limit are rejected until the reset
+func (s *Server) SetRateLimit(limit, remaining int, reset time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rateLimit = limit
+	s.rateRemaining = remaining
+	s.rateLimitReset = reset
+}
+
+// InjectFault makes the server fail the requests matched by a fault,
+// it panics if the path of the fault is not a valid regular expression
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if fault.Path != "" {
+		fault.path = regexp.MustCompile(fault.Path)
+	}
+	if fault.StatusCode == 0 {
+		fault.StatusCode = http.StatusInternalServerError
+	}
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults stops failing requests
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// Requests returns the requests served so far as "METHOD path"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.requests...)
+}
+
+// this function serves a request, the request is authenticated, counted against the
+// rate limit and failed by an injected fault before it is routed to its endpoint
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	// the server is meant to be used with github.com clients, which send requests to the root.
+	// requests are routed by their escaped path like on github, so a name with a slash has to be escaped
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v3")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+path)
+	fault := s.matchFault(r.Method, path)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if fault.Delay > 0 {
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		writeError(w, fault.StatusCode, fault.Message)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Token != "" && !strings.HasSuffix(r.Header.Get("Authorization"), " "+s.Token) {
+		writeError(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	if path != "/rate_limit" && !s.takeRateLimit(w) {
+		writeError(w, http.StatusForbidden, "API rate limit exceeded for user.")
+		return
+	}
+
+	for _, route := range routes {
+		if route.method != r.Method {
+			continue
+		}
+		if match := route.path.FindStringSubmatch(path); match != nil {
+			params := make([]string, len(match)-1)
+			for i, param := range match[1:] {
+				unescaped, err := url.PathUnescape(param)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "Bad Request")
+					return
+				}
+				params[i] = unescaped
+			}
+			route.handler(s, w, r, params)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Not Found")
+}
+
+// this function returns the first fault matching a request and counts the request against it
+func (s *Server) matchFault(method, path string) *Fault {
+	for i, fault := range s.faults {
+		if fault.Method != "" && !strings.EqualFold(fault.Method, method) {
+			continue
+		}
+		if fault.path != nil && !fault.path.MatchString(path) {
+			continue
+		}
+
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return fault
+	}
+	return nil
+}
+
+// this function counts a request against the rate limit and sets the rate limit
+// headers of its response, false is returned if no requests are left
+func (s *Server) takeRateLimit(w http.ResponseWriter) bool {
+	now := s.Now()
+	if !now.Before(s.rateLimitReset) {
+		s.rateRemaining = s.rateLimit
+		s.rateLimitReset = now.Add(time.Hour)
+	}
+
+	allowed := s.rateRemaining > 0
+	if allowed {
+		s.rateRemaining--
+	}
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.rateLimit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.rateRemaining))
+	w.Header().Set("X-RateLimit-Used", strconv.Itoa(s.rateLimit-s.rateRemaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.rateLimitReset.Unix(), 10))
+	w.Header().Set("X-RateLimit-Resource", "core")
+	return allowed
+}
+
+func (s *Server) getRateLimit(w http.ResponseWriter, r *http.Request, params []string) {
+	rate := github.Rate{
+		Limit:     s.rateLimit,
+		Remaining: s.rateRemaining,
+		Reset:     github.Timestamp{Time: s.rateLimitReset},
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"resources": map[string]github.Rate{"core": rate},
+		"rate":      rate,
+	})
+}
+
+func (s *Server) listIssues(w http.ResponseWriter, r *http.Request, params []string) {
+	repo := s.getRepo(w, params)
+	if repo == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	state := query.Get("state")
+	if state == "" {
+		state = stateOpen
+	}
+
+	var labels []string
+	if query.Get("labels") != "" {
+		labels = strings.Split(query.Get("labels"), ",")
+	}
+
+	var issues []*Issue
+	for _, issue := range repo.issues {
+		if (state == stateAll || issue.State == state) && hasLabels(issue, labels) {
+			issues = append(issues, issue)
+		}
+	}
+
+	// issues are listed from the newest by default
+	ascending := query.Get("direction") == "asc"
+	sort.SliceStable(issues, func(i, j int) bool {
+		if ascending {
+			return issues[i].Number < issues[j].Number
+		}
+		return issues[i].Number > issues[j].Number
+	})
+
+	start, end, ok := paginate(w, r, len(issues))
+	if !ok {
+		return
+	}
+
+	page := []*github.Issue{}
+	for _, issue := range issues[start:end] {
+		page = append(page, s.renderIssue(repo, issue))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) createIssue(w http.ResponseWriter, r *http.Request, params []string) {
+	repo := s.getRepo(w, params)
+	if repo == nil {
+		return
+	}
+
+	request := github.IssueRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if request.GetTitle() == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+
+	now := s.Now()
+	issue := &Issue{
+		Number:    len(repo.issues) + 1,
+		State:     stateOpen,
+		CreatedAt: now,
+	}
+	s.applyIssueRequest(repo, issue, &request)
+	repo.issues = append(repo.issues, issue)
+
+	writeJSON(w, http.StatusCreated, s.renderIssue(repo, issue))
+}
+
+func (s *Server) getIssue(w http.ResponseWriter, r *http.Request, params []string) {
+	repo, issue := s.getRepoIssue(w, params)
+	if issue == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.renderIssue(repo, issue))
+}
+
+func (s *Server) editIssue(w http.ResponseWriter, r *http.Request, params []string) {
+	repo, issue := s.getRepoIssue(w, params)
+	if issue == nil {
+		return
+	}
+
+	request := github.IssueRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if state := request.GetState(); state != "" && state != stateOpen && state != stateClosed {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+
+	s.applyIssueRequest(repo, issue, &request)
+	writeJSON(w, http.StatusOK, s.renderIssue(repo, issue))
+}
+
+func (s *Server) lockIssue(w http.ResponseWriter, r *http.Request, params []string) {
+	_, issue := s.getRepoIssue(w, params)
+	if issue == nil {
+		return
+	}
+
+	options := github.LockIssueOptions{}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&options); err != nil {
+			writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+	}
+
+	issue.Locked = true
+	issue.LockReason = options.LockReason
+	issue.UpdatedAt = s.Now()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) unlockIssue(w http.ResponseWriter, r *http.Request, params []string) {
+	_, issue := s.getRepoIssue(w, params)
+	if issue == nil {
+		return
+	}
+
+	issue.Locked = false
+	issue.LockReason = ""
+	issue.UpdatedAt = s.Now()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listComments(w http.ResponseWriter, r *http.Request, params []string) {
+	repo, issue := s.getRepoIssue(w, params)
+	if issue == nil {
+		return
+	}
+
+	start, end, ok := paginate(w, r, len(issue.Comments))
+	if !ok {
+		return
+	}
+
+	page := []*github.IssueComment{}
+	for i := range issue.Comments[start:end] {
+		page = append(page, s.renderComment(repo, issue, &issue.Comments[start+i]))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) createComment(w http.ResponseWriter, r *http.Request, params []string) {
+	repo, issue := s.getRepoIssue(w, params)
+	if issue == nil {
+		return
+	}
+
+	request := github.IssueComment{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if request.GetBody() == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+	if issue.Locked {
+		writeError(w, http.StatusForbidden, "Unable to create comment because issue is locked.")
+		return
+	}
+
+	s.nextID++
+	comment := Comment{ID: s.nextID, Author: s.Login, Body: request.GetBody(), CreatedAt: s.Now()}
+	issue.Comments = append(issue.Comments, comment)
+	issue.UpdatedAt = comment.CreatedAt
+
+	writeJSON(w, http.StatusCreated, s.renderComment(repo, issue, &comment))
+}
+
+func (s *Server) listLabels(w http.ResponseWriter, r *http.Request, params []string) {
+	repo := s.getRepo(w, params)
+	if repo == nil {
+		return
+	}
+
+	start, end, ok := paginate(w, r, len(repo.labels))
+	if !ok {
+		return
+	}
+
+	page := []*github.Label{}
+	for _, label := range repo.labels[start:end] {
+		page = append(page, s.renderLabel(repo, label))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) createLabel(w http.ResponseWriter, r *http.Request, params []string) {
+	repo := s.getRepo(w, params)
+	if repo == nil {
+		return
+	}
+
+	request := github.Label{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if request.GetName() == "" || findLabel(repo, request.GetName()) != nil {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+
+	label := s.ensureLabel(repo, request.GetName())
+	if request.Color != nil {
+		label.Color = request.GetColor()
+	}
+	label.Description = request.GetDescription()
+
+	writeJSON(w, http.StatusCreated, s.renderLabel(repo, label))
+}
+
+func (s *Server) getLabel(w http.ResponseWriter, r *http.Request, params []string) {
+	repo, label := s.getRepoLabel(w, params)
+	if label == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.renderLabel(repo, label))
+}
+
+func (s *Server) editLabel(w http.ResponseWriter, r *http.Request, params []string) {
+	repo, label := s.getRepoLabel(w, params)
+	if label == nil {
+		return
+	}
+
+	request := github.Label{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+
+	if name := request.GetName(); name != "" && !strings.EqualFold(name, label.Name) {
+		if findLabel(repo, name) != nil {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+			return
+		}
+		s.renameIssueLabels(repo, label.Name, name)
+		label.Name = name
+	}
+	if request.Color != nil {
+		label.Color = request.GetColor()
+	}
+	if request.Description != nil {
+		label.Description = request.GetDescription()
+	}
+
+	writeJSON(w, http.StatusOK, s.renderLabel(repo, label))
+}
+
+func (s *Server) deleteLabel(w http.ResponseWriter, r *http.Request, params []string) {
+	repo, label := s.getRepoLabel(w, params)
+	if label == nil {
+		return
+	}
+
+	for i := range repo.labels {
+		if repo.labels[i] == label {
+			repo.labels = append(repo.labels[:i], repo.labels[i+1:]...)
+			break
+		}
+	}
+	s.renameIssueLabels(repo, label.Name, "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// this function returns the repository of a request, a 404 response is written if it doesn't exist
+func (s *Server) getRepo(w http.ResponseWriter, params []string) *repository {
+	repo, ok := s.repos[repoKey(params[0], params[1])]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil
+	}
+	return repo
+}
+
+// this function returns the issue of a request, a 404 response is written if it doesn't exist
+func (s *Server) getRepoIssue(w http.ResponseWriter, params []string) (*repository, *Issue) {
+	repo := s.getRepo(w, params)
+	if repo == nil {
+		return nil, nil
+	}
+
+	number, _ := strconv.Atoi(params[2])
+	for _, issue := range repo.issues {
+		if issue.Number == number {
+			return repo, issue
+		}
+	}
+	writeError(w, http.StatusNotFound, "Not Found")
+	return nil, nil
+}
+
+// this function returns the label of a request, a 404 response is written if it doesn't exist
+func (s *Server) getRepoLabel(w http.ResponseWriter, params []string) (*repository, *Label) {
+	repo := s.getRepo(w, params)
+	if repo == nil {
+		return nil, nil
+	}
+
+	// the name is unescaped, so names of labels may contain slashes
+	label := findLabel(repo, params[2])
+	if label == nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil, nil
+	}
+	return repo, label
+}
+
+// this function sets the fields of a request on an issue, labels which don't exist are created
+func (s *Server) applyIssueRequest(repo *repository, issue *Issue, request *github.IssueRequest) {
+	now := s.Now()
+
+	if request.Title != nil {
+		issue.Title = request.GetTitle()
+	}
+	if request.Body != nil {
+		issue.Body = request.GetBody()
+	}
+	if request.Labels != nil {
+		issue.Labels = nil
+		for _, name := range *request.Labels {
+			issue.Labels = append(issue.Labels, s.ensureLabel(repo, name).Name)
+		}
+	}
+	if request.Assignee != nil {
+		issue.Assignees = []string{request.GetAssignee()}
+	}
+	if request.Assignees != nil {
+		issue.Assignees = append([]string{}, *request.Assignees...)
+	}
+	if request.Milestone != nil {
+		issue.Milestone = request.GetMilestone()
+	}
+	if state := request.GetState(); state != "" && state != issue.State {
+		issue.State = state
+		issue.ClosedAt = nil
+		if state == stateClosed {
+			issue.ClosedAt = &now
+		}
+	}
+
+	issue.UpdatedAt = now
+}
+
+// this function returns the label of a repository with a name, the label is created if it doesn't exist
+func (s *Server) ensureLabel(repo *repository, name string) *Label {
+	if label := findLabel(repo, name); label != nil {
+		return label
+	}
+
+	s.nextID++
+	label := &Label{ID: s.nextID, Name: name, Color: defaultLabelColor}
+	repo.labels = append(repo.labels, label)
+	return label
+}
+
+// this function renames a label on the issues of a repository, the label is removed if the new name is empty
+func (s *Server) renameIssueLabels(repo *repository, name, newName string) {
+	for _, issue := range repo.issues {
+		var labels []string
+		for _, label := range issue.Labels {
+			switch {
+			case !strings.EqualFold(label, name):
+				labels = append(labels, label)
+			case newName != "":
+				labels = append(labels, newName)
+			}
+		}
+		issue.Labels = labels
+	}
+}
+
+// this function returns an issue as the github api serves it
+func (s *Server) renderIssue(repo *repository, issue *Issue) *github.Issue {
+	repoURL := s.URL + "/repos/" + repo.owner + "/" + repo.name
+	number := strconv.Itoa(issue.Number)
+
+	rendered := &github.Issue{
+		ID:            github.Int64(int64(issue.Number)),
+		NodeID:        github.String(fmt.Sprintf("I_%s_%s_%d", repo.owner, repo.name, issue.Number)),
+		Number:        github.Int(issue.Number),
+		Title:         github.String(issue.Title),
+		Body:          github.String(issue.Body),
+		State:         github.String(issue.State),
+		Locked:        github.Bool(issue.Locked),
+		Comments:      github.Int(len(issue.Comments)),
+		CreatedAt:     timePtr(issue.CreatedAt),
+		UpdatedAt:     timePtr(issue.UpdatedAt),
+		ClosedAt:      issue.ClosedAt,
+		URL:           github.String(repoURL + "/issues/" + number),
+		HTMLURL:       github.String(webHost + "/" + repo.owner + "/" + repo.name + "/issues/" + number),
+		CommentsURL:   github.String(repoURL + "/issues/" + number + "/comments"),
+		RepositoryURL: github.String(repoURL),
+		Labels:        []*github.Label{},
+		Assignees:     []*github.User{},
+	}
+	if issue.LockReason != "" {
+		rendered.ActiveLockReason = github.String(issue.LockReason)
+	}
+	if issue.Milestone != 0 {
+		rendered.Milestone = &github.Milestone{Number: github.Int(issue.Milestone)}
+	}
+
+	for _, name := range issue.Labels {
+		label := findLabel(repo, name)
+		if label == nil {
+			label = &Label{Name: name, Color: defaultLabelColor}
+		}
+		rendered.Labels = append(rendered.Labels, s.renderLabel(repo, label))
+	}
+
+	for _, login := range issue.Assignees {
+		rendered.Assignees = append(rendered.Assignees, &github.User{Login: github.String(login)})
+	}
+	if len(rendered.Assignees) > 0 {
+		rendered.Assignee = rendered.Assignees[0]
+	}
+
+	return rendered
+}
+
+// this function returns a comment as the github api serves it
+func (s *Server) renderComment(repo *repository, issue *Issue, comment *Comment) *github.IssueComment {
+	repoURL := s.URL + "/repos/" + repo.owner + "/" + repo.name
+	id := strconv.FormatInt(comment.ID, 10)
+
+	return &github.IssueComment{
+		ID:        github.Int64(comment.ID),
+		NodeID:    github.String("IC_" + id),
+		User:      &github.User{Login: github.String(comment.Author)},
+		Body:      github.String(comment.Body),
+		CreatedAt: timePtr(comment.CreatedAt),
+		UpdatedAt: timePtr(comment.CreatedAt),
+		URL:       github.String(repoURL + "/issues/comments/" + id),
+		HTMLURL:   github.String(fmt.Sprintf("%s/%s/%s/issues/%d#issuecomment-%s", webHost, repo.owner, repo.name, issue.Number, id)),
+		IssueURL:  github.String(repoURL + "/issues/" + strconv.Itoa(issue.Number)),
+	}
+}
+
+// this function returns a label as the github api serves it
+func (s *Server) renderLabel(repo *repository, label *Label) *github.Label {
+	return &github.Label{
+		ID:          github.Int64(label.ID),
+		NodeID:      github.String("LA_" + strconv.FormatInt(label.ID, 10)),
+		URL:         github.String(s.URL + "/repos/" + repo.owner + "/" + repo.name + "/labels/" + url.PathEscape(label.Name)),
+		Name:        github.String(label.Name),
+		Color:       github.String(label.Color),
+		Description: github.String(label.Description),
+		Default:     github.Bool(false),
+	}
+}
+
+// this function returns the bounds of the page of a list requested by the page and per_page
+// parameters and sets the Link header pointing to the other pages like github does
+func paginate(w http.ResponseWriter, r *http.Request, total int) (int, int, bool) {
+	query := r.URL.Query()
+
+	page := 1
+	if value := query.Get("page"); value != "" {
+		var err error
+		if page, err = strconv.Atoi(value); err != nil || page < 1 {
+			writeError(w, http.StatusBadRequest, "Invalid page")
+			return 0, 0, false
+		}
+	}
+
+	perPage := defaultPerPage
+	if value := query.Get("per_page"); value != "" {
+		var err error
+		if perPage, err = strconv.Atoi(value); err != nil || perPage < 1 {
+			writeError(w, http.StatusBadRequest, "Invalid per_page")
+			return 0, 0, false
+		}
+		if perPage > maxPerPage {
+			perPage = maxPerPage
+		}
+	}
+
+	lastPage := (total + perPage - 1) / perPage
+	if lastPage == 0 {
+		lastPage = 1
+	}
+
+	var links []string
+	link := func(page int, rel string) {
+		pageQuery := r.URL.Query()
+		pageQuery.Set("page", strconv.Itoa(page))
+		pageQuery.Set("per_page", strconv.Itoa(perPage))
+		links = append(links, fmt.Sprintf(`<http://%s%s?%s>; rel="%s"`, r.Host, r.URL.Path, pageQuery.Encode(), rel))
+	}
+	if page > 1 {
+		link(page-1, "prev")
+		link(1, "first")
+	}
+	if page < lastPage {
+		link(page+1, "next")
+		link(lastPage, "last")
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+	return start, end, true
+}
+
+// this function writes a json response
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+// this function writes an error response like the ones of the github api
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+
+	body := map[string]string{"message": message}
+	if statusCode == http.StatusForbidden && strings.HasPrefix(message, "API rate limit exceeded") {
+		body["documentation_url"] = rateLimitDocumentationURL
+	}
+	writeJSON(w, statusCode, body)
+}
+
+// this function checks whether an issue has all of the given labels
+func hasLabels(issue *Issue, labels []string) bool {
+	for _, name := range labels {
+		found := false
+		for _, label := range issue.Labels {
+			found = found || strings.EqualFold(label, name)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// this function returns the label of a repository with a name, names are case insensitive
+func findLabel(repo *repository, name string) *Label {
+	for _, label := range repo.labels {
+		if strings.EqualFold(label.Name, name) {
+			return label
+		}
+	}
+	return nil
+}
+
+// this function returns a copy of an issue which doesn't share its slices
+func copyIssue(issue *Issue) Issue {
+	copied := *issue
+	copied.Labels = append([]string(nil), issue.Labels...)
+	copied.Assignees = append([]string(nil), issue.Assignees...)
+	copied.Comments = append([]Comment(nil), issue.Comments...)
+	return copied
+}
+
+// this function returns the key of a repository, repository names are case insensitive
+func repoKey(owner, name string) string {
+	return strings.ToLower(owner + "/" + name)
+}
+
+// this function returns a pointer to a time
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// tokenTransport authenticates the requests of a client with a token
+type tokenTransport struct {
+	token string
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.token == "" {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package githubfake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	testOwner = "testOrg"
+	testRepo  = "testRepo"
+)
+
+func TestIssues(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	server := NewServer()
+	defer server.Close()
+	server.AddRepo(testOwner, testRepo)
+	client := server.Client()
+
+	issue, response, err := client.Issues.Create(ctx, testOwner, testRepo, &github.IssueRequest{
+		Title:     github.String("Upgrade dependencies"),
+		Body:      github.String("Dependencies are outdated"),
+		Labels:    &[]string{"dependencies"},
+		Assignees: &[]string{"alice"},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(response.StatusCode).To(Equal(http.StatusCreated))
+	g.Expect(issue.GetNumber()).To(Equal(1))
+	g.Expect(issue.GetState()).To(Equal("open"))
+	g.Expect(issue.GetHTMLURL()).To(Equal("https://github.com/testOrg/testRepo/issues/1"))
+	g.Expect(issue.Labels).To(HaveLen(1))
+	g.Expect(issue.Assignees).To(HaveLen(1))
+
+	// labels set on issues are created in the repository
+	g.Expect(server.Labels(testOwner, testRepo)).To(HaveLen(1))
+
+	_, _, err = client.Issues.Create(ctx, testOwner, testRepo, &github.IssueRequest{Title: github.String("Second issue")})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	issue, _, err = client.Issues.Edit(ctx, testOwner, testRepo, 1, &github.IssueRequest{State: github.String("closed")})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issue.GetState()).To(Equal("closed"))
+	g.Expect(issue.ClosedAt).ToNot(BeNil())
+
+	open, _, err := client.Issues.ListByRepo(ctx, testOwner, testRepo, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(open).To(HaveLen(1))
+	g.Expect(open[0].GetNumber()).To(Equal(2))
+
+	all, _, err := client.Issues.ListByRepo(ctx, testOwner, testRepo, &github.IssueListByRepoOptions{State: "all", Labels: []string{"dependencies"}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(all).To(HaveLen(1))
+	g.Expect(all[0].GetNumber()).To(Equal(1))
+
+	// comments are listed in the order they were created
+	for _, body := range []string{"first", "second"} {
+		_, response, err := client.Issues.CreateComment(ctx, testOwner, testRepo, 1, &github.IssueComment{Body: github.String(body)})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(response.StatusCode).To(Equal(http.StatusCreated))
+	}
+	comments, _, err := client.Issues.ListComments(ctx, testOwner, testRepo, 1, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(comments).To(HaveLen(2))
+	g.Expect(comments[0].GetBody()).To(Equal("first"))
+
+	// locked issues can't be commented on
+	response, err = client.Issues.Lock(ctx, testOwner, testRepo, 1, &github.LockIssueOptions{LockReason: "resolved"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(response.StatusCode).To(Equal(http.StatusNoContent))
+	_, _, err = client.Issues.CreateComment(ctx, testOwner, testRepo, 1, &github.IssueComment{Body: github.String("third")})
+	g.Expect(err).To(HaveOccurred())
+
+	stored, ok := server.Issue(testOwner, testRepo, 1)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(stored.Locked).To(BeTrue())
+	g.Expect(stored.LockReason).To(Equal("resolved"))
+	g.Expect(stored.Comments).To(HaveLen(2))
+
+	// unknown repositories and issues are not found
+	_, response, err = client.Issues.Get(ctx, testOwner, "missing", 1)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+	_, response, err = client.Issues.Get(ctx, testOwner, testRepo, 3)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+}
+
+func TestLabels(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	server := NewServer()
+	defer server.Close()
+	server.AddIssue(testOwner, testRepo, Issue{Title: "Flaky test", Labels: []string{"bug"}})
+	client := server.Client()
+
+	_, _, err := client.Issues.CreateLabel(ctx, testOwner, testRepo, &github.Label{Name: github.String("bug")})
+	g.Expect(err).To(HaveOccurred())
+
+	label, _, err := client.Issues.EditLabel(ctx, testOwner, testRepo, "bug", &github.Label{Name: github.String("kind/bug"), Color: github.String("d73a4a")})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(label.GetName()).To(Equal("kind/bug"))
+
+	// renamed labels are renamed on the issues, names with a slash are only found when they are escaped
+	_, _, err = client.Issues.GetLabel(ctx, testOwner, testRepo, "kind/bug")
+	g.Expect(err).To(HaveOccurred())
+	label, _, err = client.Issues.GetLabel(ctx, testOwner, testRepo, url.PathEscape("kind/bug"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(label.GetColor()).To(Equal("d73a4a"))
+	issue, _ := server.Issue(testOwner, testRepo, 1)
+	g.Expect(issue.Labels).To(Equal([]string{"kind/bug"}))
+
+	_, err = client.Issues.DeleteLabel(ctx, testOwner, testRepo, url.PathEscape("kind/bug"))
+	g.Expect(err).ToNot(HaveOccurred())
+	issue, _ = server.Issue(testOwner, testRepo, 1)
+	g.Expect(issue.Labels).To(BeEmpty())
+	g.Expect(server.Labels(testOwner, testRepo)).To(BeEmpty())
+}
+
+func TestPagination(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	server := NewServer()
+	defer server.Close()
+	for i := 0; i < 75; i++ {
+		server.AddIssue(testOwner, testRepo, Issue{Title: "Issue"})
+	}
+	client := server.Client()
+
+	options := &github.IssueListByRepoOptions{State: "all", Direction: "asc"}
+	issues, response, err := client.Issues.ListByRepo(ctx, testOwner, testRepo, options)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issues).To(HaveLen(30))
+	g.Expect(response.NextPage).To(Equal(2))
+	g.Expect(response.LastPage).To(Equal(3))
+
+	var numbers []int
+	for {
+		issues, response, err := client.Issues.ListByRepo(ctx, testOwner, testRepo, options)
+		g.Expect(err).ToNot(HaveOccurred())
+		for _, issue := range issues {
+			numbers = append(numbers, issue.GetNumber())
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		options.Page = response.NextPage
+	}
+	g.Expect(numbers).To(HaveLen(75))
+	g.Expect(numbers[0]).To(Equal(1))
+	g.Expect(numbers[74]).To(Equal(75))
+
+	// pages are at most 100 issues long
+	options = &github.IssueListByRepoOptions{State: "all", ListOptions: github.ListOptions{PerPage: 500}}
+	issues, response, err = client.Issues.ListByRepo(ctx, testOwner, testRepo, options)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(issues).To(HaveLen(75))
+	g.Expect(response.NextPage).To(Equal(0))
+}
+
+func TestRateLimit(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	server := NewServer()
+	defer server.Close()
+	server.AddRepo(testOwner, testRepo)
+
+	now := time.Now()
+	server.Now = func() time.Time { return now }
+	server.SetRateLimit(10, 1, now.Add(time.Hour))
+
+	_, response, err := server.Client().Issues.ListByRepo(ctx, testOwner, testRepo, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(response.Rate.Limit).To(Equal(10))
+	g.Expect(response.Rate.Remaining).To(Equal(0))
+	g.Expect(response.Rate.Reset.Unix()).To(Equal(now.Add(time.Hour).Unix()))
+
+	_, _, err = server.Client().Issues.ListByRepo(ctx, testOwner, testRepo, nil)
+	var rateLimitErr *github.RateLimitError
+	g.Expect(errors.As(err, &rateLimitErr)).To(BeTrue())
+
+	// the rate limit is served without counting against it
+	limits, _, err := server.Client().RateLimits(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(limits.Core.Remaining).To(Equal(0))
+
+	// the requests are allowed again after the reset
+	now = now.Add(time.Hour)
+	_, response, err = server.Client().Issues.ListByRepo(ctx, testOwner, testRepo, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(response.Rate.Remaining).To(Equal(9))
+}
+
+func TestFaults(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	server := NewServer()
+	defer server.Close()
+	server.AddRepo(testOwner, testRepo)
+	client := server.Client()
+
+	server.InjectFault(Fault{Method: http.MethodPost, Path: `/issues$`, StatusCode: http.StatusBadGateway, Times: 1})
+
+	request := &github.IssueRequest{Title: github.String("Upgrade dependencies")}
+	_, response, err := client.Issues.Create(ctx, testOwner, testRepo, request)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(response.StatusCode).To(Equal(http.StatusBadGateway))
+
+	// the fault only fails as many requests as requested
+	_, _, err = client.Issues.Create(ctx, testOwner, testRepo, request)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// delayed faults make requests time out
+	server.InjectFault(Fault{Delay: time.Minute})
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, _, err = client.Issues.Get(timeoutCtx, testOwner, testRepo, 1)
+	g.Expect(err).To(HaveOccurred())
+
+	server.ClearFaults()
+	_, _, err = client.Issues.Get(ctx, testOwner, testRepo, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(server.Requests()).To(Equal([]string{
+		"POST /repos/testOrg/testRepo/issues",
+		"POST /repos/testOrg/testRepo/issues",
+		"GET /repos/testOrg/testRepo/issues/1",
+		"GET /repos/testOrg/testRepo/issues/1",
+	}))
+
+	// requests with another token are rejected
+	server.Token = "token"
+	_, response, err = client.Issues.Get(ctx, testOwner, testRepo, 1)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+	_, _, err = server.Client().Issues.Get(ctx, testOwner, testRepo, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+}
@@ -18,7 +18,11 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -27,8 +31,11 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	trainingv1alpha1 "github.com/mzeevi/githubissues-operator/api/v1alpha1"
+	trainingv1beta1 "github.com/mzeevi/githubissues-operator/api/v1beta1"
 	"github.com/mzeevi/githubissues-operator/controllers"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -46,6 +53,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(trainingv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(trainingv1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -53,11 +61,59 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var clusterCredentialsSecret string
+	var leaderElectionID string
+	var watchNamespaces string
+	var labelSelector string
+	var scope controllers.Scope
+	var throttle controllers.Throttle
+	var gitlabURL string
+	var trackerConfig string
+	var githubCassette string
+	var githubCassetteMode string
+	var dryRun bool
+	var backupDir string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&clusterCredentialsSecret, "cluster-credentials-secret", "",
+		"The namespace/name of the secret holding the GH_PERSONAL_TOKEN used for ClusterGithubIssue objects. "+
+			"The token of the manager is used if it is not set.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "bf80380f.redhat.com",
+		"The name of the lease used for leader election. "+
+			"Instances which handle different objects need different ids.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"A comma-separated list of the namespaces which are watched. All namespaces are watched if it is not set.")
+	flag.StringVar(&labelSelector, "label-selector", "",
+		"A label selector of the objects which are handled. All objects are handled if it is not set.")
+	flag.IntVar(&scope.Shards, "shards", 0,
+		"The number of instances the objects are spread across by the hash of their shard label. "+
+			"Sharding is disabled if it is below 2.")
+	flag.IntVar(&scope.ShardIndex, "shard-index", 0, "The shard handled by this instance, from 0 to shards-1.")
+	flag.StringVar(&scope.ShardLabel, "shard-label", "",
+		"The label whose value is hashed to pick the shard of an object. "+
+			"Objects without the label are sharded by their namespace and name.")
+	flag.IntVar(&throttle.MaxOpenIssuesPerRepo, "max-open-issues-per-repo", 0,
+		"The maximum number of open issues managed by the operator in a repository. 0 means no limit.")
+	flag.IntVar(&throttle.MaxCreationsPerHourPerNamespace, "max-creations-per-hour-per-namespace", 0,
+		"The maximum number of issues created per hour for the objects of a namespace. 0 means no limit.")
+	flag.IntVar(&throttle.MaxCreationsPerHourPerRepo, "max-creations-per-hour-per-repo", 0,
+		"The maximum number of issues created per hour in a repository. 0 means no limit.")
+	flag.StringVar(&gitlabURL, "gitlab-url", "https://gitlab.com",
+		"The URL of the gitlab instance whose repositories are managed with the GL_PERSONAL_TOKEN.")
+	flag.StringVar(&trackerConfig, "tracker-config", "",
+		"The path of a yaml file mapping the hosts of repositories to gitlab, gitea or forgejo issue trackers.")
+	flag.StringVar(&githubCassette, "github-cassette", "",
+		"The path of a cassette file to which the github api requests and responses are recorded, with tokens and emails redacted.")
+	flag.StringVar(&githubCassetteMode, "github-cassette-mode", controllers.CassetteModeRecord,
+		"Whether the github cassette is recorded or replayed instead of calling the github api. One of record or replay.")
+	flag.StringVar(&backupDir, "backup-dir", "",
+		"The directory under which GithubIssueBackup objects write files, backups to files are disabled if it is not set.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Only plan the changes to issues and report them in the Planned condition and events of objects, without changing anything on github. "+
+			"Deleted objects are let go without closing their issues.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -66,6 +122,24 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	for _, namespace := range strings.Split(watchNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			scope.Namespaces = append(scope.Namespaces, namespace)
+		}
+	}
+	if labelSelector != "" {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			setupLog.Error(err, "invalid label selector")
+			os.Exit(1)
+		}
+		scope.Selector = selector
+	}
+	if err := scope.Validate(); err != nil {
+		setupLog.Error(err, "invalid shard")
+		os.Exit(1)
+	}
+
 	syncPeriod := 60 * time.Second
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
@@ -73,8 +147,9 @@ func main() {
 		Port:                   9443,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "bf80380f.redhat.com",
+		LeaderElectionID:       leaderElectionID,
 		SyncPeriod:             &syncPeriod,
+		NewCache:               scope.NewCache(),
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -94,14 +169,132 @@ func main() {
 
 	ctx := ctrl.SetupSignalHandler()
 
+	clientCtx := ctx
+	var githubTransport http.RoundTripper
+	if githubCassette != "" {
+		transport, err := controllers.NewCassetteTransport(githubCassetteMode, githubCassette, os.Getenv("GH_PERSONAL_TOKEN"))
+		if err != nil {
+			setupLog.Error(err, "unable to set up github cassette")
+			os.Exit(1)
+		}
+		setupLog.Info("using github cassette", "path", githubCassette, "mode", githubCassetteMode)
+		githubTransport = transport
+		clientCtx = controllers.WithGithubTransport(ctx, transport)
+	}
+
+	ghClient := controllers.GetGithubClient(clientCtx)
+	ghV4Client := controllers.GetGithubV4Client(clientCtx)
+
+	gitlabHost, err := url.Parse(gitlabURL)
+	if err != nil || gitlabHost.Host == "" {
+		setupLog.Error(fmt.Errorf("expected an absolute URL, got %q", gitlabURL), "invalid gitlab URL")
+		os.Exit(1)
+	}
+	trackers := map[string]controllers.IssueTracker{
+		strings.ToLower(gitlabHost.Host): controllers.NewGitlabIssueTracker(gitlabURL, os.Getenv("GL_PERSONAL_TOKEN")),
+	}
+	if trackerConfig != "" {
+		config, err := controllers.LoadTrackerConfig(trackerConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to load tracker config")
+			os.Exit(1)
+		}
+		configTrackers, err := config.NewTrackers(ctx, mgr.GetAPIReader(), os.Getenv)
+		if err != nil {
+			setupLog.Error(err, "invalid tracker config")
+			os.Exit(1)
+		}
+		for host, tracker := range configTrackers {
+			trackers[host] = tracker
+		}
+	}
+
 	if err = (&controllers.GithubIssueReconciler{
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		GithubClient:   ghClient,
+		GithubV4Client: ghV4Client,
+		Scope:          scope,
+		Throttle:       &throttle,
+		Trackers:       trackers,
+		DryRun:         dryRun,
+		Recorder:       mgr.GetEventRecorderFor("githubissue-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GithubIssue")
+		os.Exit(1)
+	}
+	if err = (&controllers.GithubLabelReconciler{
 		Client:       mgr.GetClient(),
 		Scheme:       mgr.GetScheme(),
-		GithubClient: controllers.GetGithubClient(ctx),
+		GithubClient: ghClient,
+		Scope:        scope,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "GithubIssue")
+		setupLog.Error(err, "unable to create controller", "controller", "GithubLabel")
 		os.Exit(1)
 	}
+	if err = (&controllers.GithubMilestoneReconciler{
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		GithubClient: ghClient,
+		Scope:        scope,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GithubMilestone")
+		os.Exit(1)
+	}
+	if err = (&controllers.GithubIssueTemplateReconciler{
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		GithubClient: ghClient,
+		Scope:        scope,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GithubIssueTemplate")
+		os.Exit(1)
+	}
+	credentialsSecret := types.NamespacedName{}
+	if clusterCredentialsSecret != "" {
+		namespace, name, ok := strings.Cut(clusterCredentialsSecret, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Error(fmt.Errorf("expected namespace/name, got %q", clusterCredentialsSecret), "invalid cluster credentials secret")
+			os.Exit(1)
+		}
+		credentialsSecret = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	clusterGithubIssueReconciler := &controllers.ClusterGithubIssueReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		GithubClient:      ghClient,
+		GithubV4Client:    ghV4Client,
+		APIReader:         mgr.GetAPIReader(),
+		CredentialsSecret: credentialsSecret,
+		GithubTransport:   githubTransport,
+		Scope:             scope,
+		Throttle:          &throttle,
+		Trackers:          trackers,
+		DryRun:            dryRun,
+		Recorder:          mgr.GetEventRecorderFor("clustergithubissue-controller"),
+	}
+	if err = clusterGithubIssueReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterGithubIssue")
+		os.Exit(1)
+	}
+	if err = (&controllers.GithubIssueBackupReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		GithubClient:  ghClient,
+		Trackers:      trackers,
+		BackupDir:     backupDir,
+		ClusterIssues: clusterGithubIssueReconciler,
+		Scope:         scope,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GithubIssueBackup")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&trainingv1alpha1.GithubIssue{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GithubIssue")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {